@@ -9,17 +9,20 @@ CREATE TABLE IF NOT EXISTS users (
     password_hash bytea NOT NULL,
     activated bool NOT NULL,
     deleted bool NOT NULL DEFAULT false,
-    version integer NOT NULL DEFAULT 1
+    version integer NOT NULL DEFAULT 1,
+    role text NOT NULL DEFAULT 'user'
 );
 
 CREATE TABLE IF NOT EXISTS tags (
     id serial PRIMARY KEY,
-    name VARCHAR(255)  NOT NULL
+    name VARCHAR(255)  NOT NULL,
+    CONSTRAINT tags_name_key UNIQUE (name)
 );
 
 CREATE TABLE IF NOT EXISTS categories (
     id serial PRIMARY KEY,
-    name VARCHAR(255)  NOT NULL
+    name VARCHAR(255)  NOT NULL,
+    CONSTRAINT categories_name_key UNIQUE (name)
 );
 
 CREATE TABLE IF NOT EXISTS pets (
@@ -27,15 +30,42 @@ CREATE TABLE IF NOT EXISTS pets (
     category_id serial REFERENCES categories(id),
     name VARCHAR(255) NOT NULL,
     status VARCHAR(50) NOT NULL,
-    photo_urls TEXT[]
+    photo_urls TEXT[],
+    photos JSONB NOT NULL DEFAULT '[]',
+    status_changed_by text NOT NULL DEFAULT '',
+    status_changed_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1,
+    breed VARCHAR(255) NOT NULL DEFAULT '',
+    birth_date date,
+    sex VARCHAR(10) NOT NULL DEFAULT '',
+    weight_grams bigint NOT NULL DEFAULT 0 CHECK (weight_grams >= 0),
+    description text NOT NULL DEFAULT '',
+    price bigint NOT NULL DEFAULT 0 CHECK (price >= 0),
+    created_by text NOT NULL DEFAULT ''
 );
 
+CREATE INDEX IF NOT EXISTS pets_breed_idx ON pets (lower(breed));
+CREATE INDEX IF NOT EXISTS pets_birth_date_idx ON pets (birth_date);
+CREATE INDEX IF NOT EXISTS pets_price_idx ON pets (price);
+CREATE INDEX IF NOT EXISTS pets_created_by_idx ON pets (created_by);
+
 CREATE TABLE IF NOT EXISTS pet_tags (
   pet_id serial REFERENCES pets(id) ON DELETE CASCADE,
   tag_id serial REFERENCES tags(id) ON DELETE CASCADE,
   PRIMARY KEY (pet_id, tag_id)
 );
 
+CREATE TABLE IF NOT EXISTS pet_events (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL,
+    action text NOT NULL,
+    actor text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    changes jsonb NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS pet_events_pet_id_idx ON pet_events (pet_id, created_at);
+
 CREATE TABLE IF NOT EXISTS orders (
     id serial PRIMARY KEY,
     pet_id serial REFERENCES pets(id) ON DELETE CASCADE,
@@ -44,4 +74,59 @@ CREATE TABLE IF NOT EXISTS orders (
     ship_date timestamp(0) with time zone NOT NULL DEFAULT NOW(),
     status VARCHAR(50) NOT NULL,
     complete bool NOT NULL
-)
+);
+
+CREATE TABLE IF NOT EXISTS adoptions (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    applicant text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    message text NOT NULL DEFAULT '',
+    reason text NOT NULL DEFAULT '',
+    decided_by text NOT NULL DEFAULT '',
+    order_id bigint REFERENCES orders(id) ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    decided_at timestamp(0) with time zone,
+    expires_at timestamp(0) with time zone NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS adoptions_pending_pet_idx ON adoptions (pet_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS adoptions_applicant_idx ON adoptions (applicant, created_at);
+CREATE INDEX IF NOT EXISTS adoptions_expires_at_idx ON adoptions (expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS holds (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL UNIQUE REFERENCES pets(id) ON DELETE CASCADE,
+    holder text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp(0) with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS holds_expires_at_idx ON holds (expires_at);
+
+CREATE TABLE IF NOT EXISTS medical_records (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    type text NOT NULL,
+    title text NOT NULL,
+    notes text NOT NULL DEFAULT '',
+    vet text NOT NULL DEFAULT '',
+    performed_on date NOT NULL,
+    due_on date,
+    created_by text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS medical_records_pet_idx ON medical_records (pet_id, performed_on);
+CREATE INDEX IF NOT EXISTS medical_records_due_idx ON medical_records (due_on) WHERE type = 'vaccination';
+
+CREATE TABLE IF NOT EXISTS favorites (
+    username text NOT NULL,
+    pet_id bigint NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, pet_id)
+);
+
+CREATE INDEX IF NOT EXISTS favorites_pet_idx ON favorites (pet_id);
+CREATE INDEX IF NOT EXISTS favorites_user_created_idx ON favorites (username, created_at);
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"test/config"
+	"test/internal/infrastructure/wal"
 	"test/run"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -18,7 +21,27 @@ func init() {
 }
 
 func main() {
-	cfg := config.NewConfig(config.WithPort(8080), config.WithDBname("postgres"), config.WithDSN(os.Getenv("DB_DSN")))
+	// DB_BACKEND is postgres or memory; only postgres needs DB_DSN
+	backend := os.Getenv("DB_BACKEND")
+	if backend == "" {
+		backend = "postgres"
+	}
+	opts := []config.Option{config.WithPort(8080), config.WithDBname(backend)}
+	if backend == "postgres" {
+		dsn := os.Getenv("DB_DSN")
+		if dsn == "" {
+			log.Fatal("DB_DSN must be set for the postgres backend")
+		}
+		opts = append(opts, config.WithDSN(dsn))
+	}
+	if backend == config.BackendMemory {
+		memory, err := memoryOptions()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, memory...)
+	}
+	cfg := config.NewConfig(opts...)
 
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -33,3 +56,49 @@ func main() {
 	os.Exit(1)
 
 }
+
+// memoryOptions reads the persistence of the memory backend: MEMORY_DIR turns
+// it on, MEMORY_FSYNC is always, interval or never, with
+// MEMORY_FSYNC_INTERVAL for interval, and MEMORY_SNAPSHOT_INTERVAL sets how
+// often the log is compacted. Unset values keep the config defaults.
+func memoryOptions() ([]config.Option, error) {
+	var opts []config.Option
+	if dir := os.Getenv("MEMORY_DIR"); dir != "" {
+		opts = append(opts, config.WithMemoryDir(dir))
+	}
+
+	if fsync := os.Getenv("MEMORY_FSYNC"); fsync != "" {
+		policy, err := wal.ParseSyncPolicy(fsync)
+		if err != nil {
+			return nil, err
+		}
+		interval, err := envDuration("MEMORY_FSYNC_INTERVAL")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, config.WithFsync(string(policy), interval))
+	}
+
+	interval, err := envDuration("MEMORY_SNAPSHOT_INTERVAL")
+	if err != nil {
+		return nil, err
+	}
+	if interval != 0 {
+		opts = append(opts, config.WithSnapshotInterval(interval))
+	}
+	return opts, nil
+}
+
+// envDuration parses the environment variable key as a duration, 0 when it is
+// unset.
+func envDuration(key string) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
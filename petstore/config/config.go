@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 	Port int
 	Env  string
@@ -10,8 +12,37 @@ type Config struct {
 		MaxIdleConns int
 		MaxIdleTime  string
 	}
+	Media struct {
+		Dir            string
+		MaxUploadSize  int64
+		ThumbnailSizes []int
+	}
+	// persistence of the memory backend, off while Dir is empty
+	Memory struct {
+		Dir              string
+		Fsync            string
+		FsyncInterval    time.Duration
+		SnapshotInterval time.Duration
+	}
+	// pending adoption applications expire after TTL, checked every
+	// ExpiryInterval
+	Adoption struct {
+		TTL            time.Duration
+		ExpiryInterval time.Duration
+	}
+	// holds on pets during checkout last TTL and are swept every
+	// ExpiryInterval
+	Reservation struct {
+		TTL            time.Duration
+		ExpiryInterval time.Duration
+	}
 }
 
+// BackendMemory selects the in-memory storages instead of a database when
+// used as Db.DBname. With Memory.Dir set, pets, categories, tags and
+// favorites are kept across restarts; everything else starts empty.
+const BackendMemory = "memory"
+
 type Option func(с *Config)
 
 func NewConfig(opts ...Option) *Config {
@@ -47,6 +78,46 @@ func NewConfig(opts ...Option) *Config {
 	if config.Db.MaxIdleTime == "" {
 		config.Db.MaxIdleTime = "15m"
 	}
+
+	if config.Media.Dir == "" {
+		config.Media.Dir = "media"
+	}
+
+	if config.Media.MaxUploadSize == 0 {
+		config.Media.MaxUploadSize = 5 << 20
+	}
+
+	if config.Media.ThumbnailSizes == nil {
+		config.Media.ThumbnailSizes = []int{160, 640}
+	}
+
+	if config.Memory.Fsync == "" {
+		config.Memory.Fsync = "always"
+	}
+
+	if config.Memory.FsyncInterval == 0 {
+		config.Memory.FsyncInterval = time.Second
+	}
+
+	if config.Memory.SnapshotInterval == 0 {
+		config.Memory.SnapshotInterval = 5 * time.Minute
+	}
+
+	if config.Adoption.TTL == 0 {
+		config.Adoption.TTL = 72 * time.Hour
+	}
+
+	if config.Adoption.ExpiryInterval == 0 {
+		config.Adoption.ExpiryInterval = time.Minute
+	}
+
+	if config.Reservation.TTL == 0 {
+		config.Reservation.TTL = 15 * time.Minute
+	}
+
+	if config.Reservation.ExpiryInterval == 0 {
+		config.Reservation.ExpiryInterval = time.Minute
+	}
 	return config
 }
 
@@ -77,3 +148,50 @@ func WithMaxIdleConns(maxIdleConns int) Option {
 func WithMaxIdleTime(maxIdleTime string) Option {
 	return func(c *Config) { c.Db.MaxIdleTime = maxIdleTime }
 }
+
+func WithMediaDir(dir string) Option {
+	return func(c *Config) { c.Media.Dir = dir }
+}
+
+func WithMaxUploadSize(size int64) Option {
+	return func(c *Config) { c.Media.MaxUploadSize = size }
+}
+
+func WithThumbnailSizes(sizes ...int) Option {
+	return func(c *Config) { c.Media.ThumbnailSizes = sizes }
+}
+
+func WithMemoryDir(dir string) Option {
+	return func(c *Config) { c.Memory.Dir = dir }
+}
+
+// WithFsync sets when the memory backend fsyncs its log: always, every
+// interval, or never.
+func WithFsync(policy string, interval time.Duration) Option {
+	return func(c *Config) {
+		c.Memory.Fsync = policy
+		c.Memory.FsyncInterval = interval
+	}
+}
+
+func WithSnapshotInterval(interval time.Duration) Option {
+	return func(c *Config) { c.Memory.SnapshotInterval = interval }
+}
+
+// WithAdoptionTTL sets how long adoption applications stay pending and how
+// often expired ones are looked for.
+func WithAdoptionTTL(ttl, interval time.Duration) Option {
+	return func(c *Config) {
+		c.Adoption.TTL = ttl
+		c.Adoption.ExpiryInterval = interval
+	}
+}
+
+// WithReservationTTL sets how long holds on pets last and how often expired
+// ones are swept.
+func WithReservationTTL(ttl, interval time.Duration) Option {
+	return func(c *Config) {
+		c.Reservation.TTL = ttl
+		c.Reservation.ExpiryInterval = interval
+	}
+}
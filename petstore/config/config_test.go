@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -65,3 +66,82 @@ func TestWithMaxIdleTime(t *testing.T) {
 		t.Errorf("expected %s, got %s", maxIdleTime, config.Db.MaxIdleTime)
 	}
 }
+
+func TestWithMediaDir(t *testing.T) {
+	dir := "/var/lib/petstore/media"
+	config := NewConfig(WithMediaDir(dir))
+
+	if config.Media.Dir != dir {
+		t.Errorf("expected %s, got %s", dir, config.Media.Dir)
+	}
+}
+
+func TestWithMaxUploadSize(t *testing.T) {
+	var size int64 = 1 << 20
+	config := NewConfig(WithMaxUploadSize(size))
+
+	if config.Media.MaxUploadSize != size {
+		t.Errorf("expected %d, got %d", size, config.Media.MaxUploadSize)
+	}
+}
+
+func TestWithThumbnailSizes(t *testing.T) {
+	config := NewConfig(WithThumbnailSizes(100, 300))
+
+	if len(config.Media.ThumbnailSizes) != 2 || config.Media.ThumbnailSizes[1] != 300 {
+		t.Errorf("expected [100 300], got %v", config.Media.ThumbnailSizes)
+	}
+}
+
+func TestWithMemoryDir(t *testing.T) {
+	dir := "/var/lib/petstore/data"
+	config := NewConfig(WithMemoryDir(dir))
+
+	if config.Memory.Dir != dir {
+		t.Errorf("expected %s, got %s", dir, config.Memory.Dir)
+	}
+}
+
+func TestWithFsync(t *testing.T) {
+	config := NewConfig()
+	if config.Memory.Fsync != "always" {
+		t.Errorf("expected always by default, got %s", config.Memory.Fsync)
+	}
+
+	config = NewConfig(WithFsync("interval", 200*time.Millisecond))
+	if config.Memory.Fsync != "interval" || config.Memory.FsyncInterval != 200*time.Millisecond {
+		t.Errorf("expected interval every 200ms, got %s every %v", config.Memory.Fsync, config.Memory.FsyncInterval)
+	}
+}
+
+func TestWithSnapshotInterval(t *testing.T) {
+	config := NewConfig(WithSnapshotInterval(time.Minute))
+
+	if config.Memory.SnapshotInterval != time.Minute {
+		t.Errorf("expected 1m, got %v", config.Memory.SnapshotInterval)
+	}
+}
+
+func TestWithAdoptionTTL(t *testing.T) {
+	config := NewConfig()
+	if config.Adoption.TTL != 72*time.Hour || config.Adoption.ExpiryInterval != time.Minute {
+		t.Errorf("expected 72h checked every 1m by default, got %v every %v", config.Adoption.TTL, config.Adoption.ExpiryInterval)
+	}
+
+	config = NewConfig(WithAdoptionTTL(time.Hour, 10*time.Second))
+	if config.Adoption.TTL != time.Hour || config.Adoption.ExpiryInterval != 10*time.Second {
+		t.Errorf("expected 1h checked every 10s, got %v every %v", config.Adoption.TTL, config.Adoption.ExpiryInterval)
+	}
+}
+
+func TestWithReservationTTL(t *testing.T) {
+	config := NewConfig()
+	if config.Reservation.TTL != 15*time.Minute || config.Reservation.ExpiryInterval != time.Minute {
+		t.Errorf("expected 15m swept every 1m by default, got %v every %v", config.Reservation.TTL, config.Reservation.ExpiryInterval)
+	}
+
+	config = NewConfig(WithReservationTTL(5*time.Minute, 10*time.Second))
+	if config.Reservation.TTL != 5*time.Minute || config.Reservation.ExpiryInterval != 10*time.Second {
+		t.Errorf("expected 5m swept every 10s, got %v every %v", config.Reservation.TTL, config.Reservation.ExpiryInterval)
+	}
+}
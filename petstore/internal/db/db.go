@@ -52,7 +52,7 @@ func NewSqlDB(cfg *config.Config, logger *zap.Logger) (*sqlx.DB, error) {
 		}
 		db := sqlx.NewDb(rawdb, "sqlite3")
 		return db, nil
-	case "test":
+	case "test", config.BackendMemory:
 		return nil, nil
 	}
 
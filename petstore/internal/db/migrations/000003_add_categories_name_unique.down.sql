@@ -0,0 +1 @@
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
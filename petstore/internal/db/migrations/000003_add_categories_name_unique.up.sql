@@ -0,0 +1,11 @@
+UPDATE pets
+SET category_id = keep.id
+FROM categories dup
+JOIN (SELECT name, min(id) AS id FROM categories GROUP BY name) keep ON keep.name = dup.name
+WHERE pets.category_id = dup.id AND dup.id <> keep.id;
+
+DELETE FROM categories dup
+USING categories keep
+WHERE dup.name = keep.name AND dup.id > keep.id;
+
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
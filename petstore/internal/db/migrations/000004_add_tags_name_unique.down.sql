@@ -0,0 +1 @@
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
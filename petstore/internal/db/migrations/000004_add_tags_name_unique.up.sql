@@ -0,0 +1,13 @@
+INSERT INTO pet_tags (pet_id, tag_id)
+SELECT pet_tags.pet_id, keep.id
+FROM pet_tags
+JOIN tags dup ON dup.id = pet_tags.tag_id
+JOIN (SELECT name, min(id) AS id FROM tags GROUP BY name) keep ON keep.name = dup.name
+WHERE dup.id <> keep.id
+ON CONFLICT DO NOTHING;
+
+DELETE FROM tags dup
+USING tags keep
+WHERE dup.name = keep.name AND dup.id > keep.id;
+
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
@@ -0,0 +1 @@
+ALTER TABLE pets DROP COLUMN IF EXISTS photos;
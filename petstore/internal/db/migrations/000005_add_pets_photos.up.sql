@@ -0,0 +1,8 @@
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS photos JSONB NOT NULL DEFAULT '[]';
+
+UPDATE pets
+SET photos = (
+    SELECT coalesce(jsonb_agg(jsonb_build_object('url', url, 'variants', '[]'::jsonb)), '[]')
+    FROM unnest(photo_urls) AS url
+)
+WHERE photo_urls IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE pets DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE pets DROP COLUMN IF EXISTS status_changed_by;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
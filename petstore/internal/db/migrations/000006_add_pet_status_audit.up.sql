@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'user';
+
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS status_changed_by text NOT NULL DEFAULT '';
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS status_changed_at timestamp(0) with time zone;
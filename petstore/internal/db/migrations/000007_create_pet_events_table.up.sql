@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS pet_events (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL,
+    action text NOT NULL,
+    actor text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    changes jsonb NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS pet_events_pet_id_idx ON pet_events (pet_id, created_at);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS pets_price_idx;
+DROP INDEX IF EXISTS pets_birth_date_idx;
+DROP INDEX IF EXISTS pets_breed_idx;
+
+ALTER TABLE pets DROP COLUMN IF EXISTS price;
+ALTER TABLE pets DROP COLUMN IF EXISTS description;
+ALTER TABLE pets DROP COLUMN IF EXISTS weight_grams;
+ALTER TABLE pets DROP COLUMN IF EXISTS sex;
+ALTER TABLE pets DROP COLUMN IF EXISTS birth_date;
+ALTER TABLE pets DROP COLUMN IF EXISTS breed;
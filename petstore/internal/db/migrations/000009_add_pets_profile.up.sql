@@ -0,0 +1,10 @@
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS breed VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS birth_date date;
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS sex VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS weight_grams bigint NOT NULL DEFAULT 0 CHECK (weight_grams >= 0);
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '';
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS price bigint NOT NULL DEFAULT 0 CHECK (price >= 0);
+
+CREATE INDEX IF NOT EXISTS pets_breed_idx ON pets (lower(breed));
+CREATE INDEX IF NOT EXISTS pets_birth_date_idx ON pets (birth_date);
+CREATE INDEX IF NOT EXISTS pets_price_idx ON pets (price);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pets_created_by_idx;
+
+ALTER TABLE pets DROP COLUMN IF EXISTS created_by;
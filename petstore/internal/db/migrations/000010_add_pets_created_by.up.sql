@@ -0,0 +1,3 @@
+ALTER TABLE pets ADD COLUMN IF NOT EXISTS created_by text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS pets_created_by_idx ON pets (created_by);
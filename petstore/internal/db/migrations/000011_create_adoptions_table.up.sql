@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS adoptions (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    applicant text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    message text NOT NULL DEFAULT '',
+    reason text NOT NULL DEFAULT '',
+    decided_by text NOT NULL DEFAULT '',
+    order_id bigint REFERENCES orders(id) ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    decided_at timestamp(0) with time zone,
+    expires_at timestamp(0) with time zone NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS adoptions_pending_pet_idx ON adoptions (pet_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS adoptions_applicant_idx ON adoptions (applicant, created_at);
+CREATE INDEX IF NOT EXISTS adoptions_expires_at_idx ON adoptions (expires_at) WHERE status = 'pending';
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS holds (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL UNIQUE REFERENCES pets(id) ON DELETE CASCADE,
+    holder text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp(0) with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS holds_expires_at_idx ON holds (expires_at);
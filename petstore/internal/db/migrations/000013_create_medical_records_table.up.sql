@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS medical_records (
+    id bigserial PRIMARY KEY,
+    pet_id bigint NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    type text NOT NULL,
+    title text NOT NULL,
+    notes text NOT NULL DEFAULT '',
+    vet text NOT NULL DEFAULT '',
+    performed_on date NOT NULL,
+    due_on date,
+    created_by text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS medical_records_pet_idx ON medical_records (pet_id, performed_on);
+CREATE INDEX IF NOT EXISTS medical_records_due_idx ON medical_records (due_on) WHERE type = 'vaccination';
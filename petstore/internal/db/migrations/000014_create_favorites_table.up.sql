@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS favorites (
+    username text NOT NULL,
+    pet_id bigint NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, pet_id)
+);
+
+CREATE INDEX IF NOT EXISTS favorites_pet_idx ON favorites (pet_id);
+CREATE INDEX IF NOT EXISTS favorites_user_created_idx ON favorites (username, created_at);
@@ -0,0 +1,52 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+
+	t.Run("Put and Open", func(t *testing.T) {
+		if err := store.Put("pets/1/photo.png", strings.NewReader("image")); err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := store.Open("pets/1/photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "image" {
+			t.Errorf("expected image got %q", data)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := store.Delete("pets/1/photo.png"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Open("pets/1/photo.png"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected %v got %v", ErrNotFound, err)
+		}
+		if err := store.Delete("pets/1/photo.png"); err != nil {
+			t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "../escape.png", "/etc/passwd"} {
+			if err := store.Put(key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("%q: expected %v got %v", key, ErrInvalidKey, err)
+			}
+		}
+	})
+}
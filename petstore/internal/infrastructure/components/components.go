@@ -1,6 +1,8 @@
 package components
 
 import (
+	"test/config"
+	"test/internal/infrastructure/blobstore"
 	"test/internal/infrastructure/responder"
 
 	"github.com/jmoiron/sqlx"
@@ -13,13 +15,17 @@ type Components struct {
 	Decoder   godecoder.Decoder
 	Logger    *zap.Logger
 	DB        *sqlx.DB
+	Blobs     blobstore.BlobStore
+	Config    *config.Config
 }
 
-func NewComponents(responder responder.Responder, decoder godecoder.Decoder, logger *zap.Logger, db *sqlx.DB) *Components {
+func NewComponents(responder responder.Responder, decoder godecoder.Decoder, logger *zap.Logger, db *sqlx.DB, blobs blobstore.BlobStore, cfg *config.Config) *Components {
 	return &Components{
 		Responder: responder,
 		Decoder:   decoder,
 		Logger:    logger,
 		DB:        db,
+		Blobs:     blobs,
+		Config:    cfg,
 	}
 }
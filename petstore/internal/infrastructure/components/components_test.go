@@ -26,7 +26,7 @@ func TestNewComponents(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	components := NewComponents(responseManager, decoder, zap.NewNop(), dbx)
+	components := NewComponents(responseManager, decoder, zap.NewNop(), dbx, nil, cfg)
 
 	if components == nil {
 		t.Fatal("components is nil")
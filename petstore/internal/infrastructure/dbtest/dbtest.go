@@ -0,0 +1,28 @@
+// Package dbtest sets up databases for repository tests.
+package dbtest
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// OpenSQLite returns an in-memory sqlite database with schema applied,
+// closed when the test ends. It keeps to one connection, since each
+// connection to :memory: would get a database of its own.
+func OpenSQLite(tb testing.TB, schema string) *sqlx.DB {
+	tb.Helper()
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	tb.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(schema); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,12 @@
+package dbtest
+
+import "testing"
+
+func TestOpenSQLite(t *testing.T) {
+	db := OpenSQLite(t, `CREATE TABLE pets (id INTEGER PRIMARY KEY); INSERT INTO pets (id) VALUES (1), (2);`)
+
+	count := 0
+	if err := db.Get(&count, `SELECT count(*) FROM pets`); err != nil || count != 2 {
+		t.Errorf("expected the schema to be applied, got %d pets, %v", count, err)
+	}
+}
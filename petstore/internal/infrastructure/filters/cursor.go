@@ -0,0 +1,116 @@
+package filters
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"test/internal/infrastructure/validator"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last row of a keyset page: its value in the sort
+// column and its id, which breaks ties. Clients get it as an opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ReadKeyset switches f to keyset pagination when the query string has
+// ?after= or ?limit=, which then replace ?page= and ?page_size=. Call it once
+// f.Sort is read: a cursor only continues the order it was issued for.
+func ReadKeyset(qs url.Values, f *Filters, v *validator.Validator) {
+	if !qs.Has("after") && !qs.Has("limit") {
+		return
+	}
+	v.Check(!qs.Has("page"), "page", "cannot be combined with after or limit")
+
+	f.Keyset = true
+	f.Page = 1
+
+	if s := qs.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			v.AddError("limit", "must be an integer value")
+		} else {
+			f.PageSize = limit
+		}
+	}
+
+	if s := qs.Get("after"); s != "" {
+		after, err := DecodeCursor(s)
+		if err != nil || after.Sort != f.Sort {
+			v.AddError("after", "must be a cursor from the same listing")
+			return
+		}
+		f.After = after
+	}
+}
+
+// KeysetClause returns the condition selecting the rows that follow f.After
+// in the order of f, with the columns qualified by table. Ties in the sort
+// column are ordered by id ascending, as every listing does. The clause uses
+// ? placeholders.
+func (f Filters) KeysetClause(table string) (string, []any) {
+	if f.After == nil {
+		return "1 = 1", nil
+	}
+
+	op := ">"
+	if f.SortDirection() == "DESC" {
+		op = "<"
+	}
+
+	column := f.SortColumn()
+	if column == "id" {
+		return table + ".id " + op + " ?", []any{f.After.ID}
+	}
+
+	qualified := table + "." + column
+	clause := "(" + qualified + " " + op + " ? OR (" + qualified + " = ? AND " + table + ".id > ?))"
+	return clause, []any{f.After.Value, f.After.Value, f.After.ID}
+}
+
+// KeysetLimit is the number of rows to fetch for a keyset page: one more
+// than fits, which tells whether another page follows.
+func (f Filters) KeysetLimit() int {
+	return f.PageSize + 1
+}
+
+// KeysetPage trims rows fetched with KeysetLimit to one page and returns it
+// with its metadata. key gives the sort column value and id of a row.
+func KeysetPage[T any](f Filters, rows []T, key func(T) (string, int64)) ([]T, Metadata) {
+	metadata := Metadata{PageSize: f.PageSize}
+	if len(rows) <= f.PageSize {
+		return rows, metadata
+	}
+
+	rows = rows[:f.PageSize]
+	value, id := key(rows[len(rows)-1])
+	if f.SortColumn() == "id" {
+		value = ""
+	}
+	metadata.NextCursor = Cursor{Sort: f.Sort, Value: value, ID: id}.Encode()
+	return rows, metadata
+}
@@ -0,0 +1,111 @@
+package filters
+
+import (
+	"net/url"
+	"reflect"
+	"test/internal/infrastructure/validator"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Sort: "-name", Value: "Rex", ID: 42}
+
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != c {
+		t.Errorf("expected %+v, got %+v", c, got)
+	}
+
+	for _, s := range []string{"", "not base64!", "bm90IGpzb24"} {
+		if _, err := DecodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("%q: expected ErrInvalidCursor, got %v", s, err)
+		}
+	}
+}
+
+func TestReadKeyset(t *testing.T) {
+	cursor := Cursor{Sort: "name", Value: "Rex", ID: 3}.Encode()
+
+	tests := []struct {
+		name   string
+		query  string
+		keyset bool
+		after  *Cursor
+		size   int
+		valid  bool
+	}{
+		{"page numbers", "page=2", false, nil, 20, true},
+		{"first page", "limit=5", true, nil, 5, true},
+		{"next page", "after=" + cursor, true, &Cursor{Sort: "name", Value: "Rex", ID: 3}, 20, true},
+		{"other sort", "after=" + Cursor{Sort: "-name", ID: 3}.Encode(), true, nil, 20, false},
+		{"garbage", "after=xyz", true, nil, 20, false},
+		{"bad limit", "limit=ten", true, nil, 20, false},
+		{"with page", "page=2&limit=5", true, nil, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := Filters{Page: 1, PageSize: 20, Sort: "name", SortSafelist: []string{"name"}}
+			v := validator.New()
+
+			ReadKeyset(qs, &f, v)
+
+			if f.Keyset != tt.keyset || f.PageSize != tt.size || !reflect.DeepEqual(f.After, tt.after) {
+				t.Errorf("unexpected filters %+v", f)
+			}
+			if v.Valid() != tt.valid {
+				t.Errorf("expected valid %v, got errors %v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestKeysetClause(t *testing.T) {
+	after := &Cursor{Value: "Rex", ID: 3}
+
+	tests := []struct {
+		sort   string
+		clause string
+		args   []any
+	}{
+		{"id", "pets.id > ?", []any{int64(3)}},
+		{"-id", "pets.id < ?", []any{int64(3)}},
+		{"name", "(pets.name > ? OR (pets.name = ? AND pets.id > ?))", []any{"Rex", "Rex", int64(3)}},
+		{"-name", "(pets.name < ? OR (pets.name = ? AND pets.id > ?))", []any{"Rex", "Rex", int64(3)}},
+	}
+	for _, tt := range tests {
+		f := Filters{Sort: tt.sort, SortSafelist: []string{tt.sort}, After: after}
+		clause, args := f.KeysetClause("pets")
+		if clause != tt.clause || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: unexpected clause %q %v", tt.sort, clause, args)
+		}
+	}
+
+	if clause, args := (Filters{Sort: "id", SortSafelist: []string{"id"}}).KeysetClause("pets"); clause != "1 = 1" || args != nil {
+		t.Errorf("expected no condition on the first page, got %q %v", clause, args)
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	f := Filters{Page: 1, PageSize: 2, Sort: "id", SortSafelist: []string{"id"}, Keyset: true}
+	key := func(id int64) (string, int64) { return "", id }
+
+	rows, meta := KeysetPage(f, []int64{1, 2, 3}, key)
+	if len(rows) != 2 || meta.PageSize != 2 || meta.NextCursor == "" {
+		t.Fatalf("unexpected page %v %+v", rows, meta)
+	}
+	next, err := DecodeCursor(meta.NextCursor)
+	if err != nil || next.ID != 2 || next.Sort != "id" {
+		t.Errorf("expected a cursor at 2, got %+v: %v", next, err)
+	}
+
+	rows, meta = KeysetPage(f, []int64{3}, key)
+	if len(rows) != 1 || meta.NextCursor != "" {
+		t.Errorf("expected the last page, got %v %+v", rows, meta)
+	}
+}
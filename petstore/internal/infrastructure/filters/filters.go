@@ -12,6 +12,11 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+
+	// Keyset pages through the rows by cursor, see ReadKeyset. After is the
+	// cursor of the last row served so far, nil on the first page.
+	Keyset bool
+	After  *Cursor
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -50,11 +55,12 @@ func (f Filters) Offset() int {
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty" xml:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty" xml:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty" xml:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty" xml:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty" xml:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
 }
 
 func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
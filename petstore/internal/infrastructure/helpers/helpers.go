@@ -1,11 +1,14 @@
 package helpers
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
 	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"time"
 
 	"github.com/go-chi/jwtauth/v5"
 )
@@ -19,11 +22,38 @@ func init() {
 	fmt.Printf("DEBUG: a sample jwt is %s\n\n", tokenString)
 }
 
-func GenerateToken(name string) string {
-	_, tokenString, _ := TokenAuth.Encode(map[string]interface{}{"username": name})
+// tokenTTL bounds how long a token, and the role baked into it, is honoured
+// after login.
+const tokenTTL = 24 * time.Hour
+
+func GenerateToken(name, role string) string {
+	claims := map[string]interface{}{"username": name, "role": role}
+	jwtauth.SetIssuedNow(claims)
+	jwtauth.SetExpiryIn(claims, tokenTTL)
+	_, tokenString, _ := TokenAuth.Encode(claims)
 	return tokenString
 }
 
+// ActorFromContext returns the user behind the request's verified token. An
+// expired token has none.
+// Tokens issued before roles existed carry no role claim and are treated as
+// regular users.
+func ActorFromContext(ctx context.Context) models.Actor {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return models.Actor{}
+	}
+
+	actor := models.Actor{Role: models.RoleUser}
+	if username, ok := claims["username"].(string); ok {
+		actor.Username = username
+	}
+	if role, ok := claims["role"].(string); ok && role != "" {
+		actor.Role = role
+	}
+	return actor
+}
+
 func ReadString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
 	if s == "" {
@@ -1,10 +1,16 @@
 package helpers
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"test/internal/infrastructure/validator"
+	"test/internal/models"
 	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
 )
 
 func TestReadString(t *testing.T) {
@@ -73,3 +79,42 @@ func TestReadInt(t *testing.T) {
 		})
 	}
 }
+
+func TestActorFromContext(t *testing.T) {
+	expired := map[string]interface{}{"username": "alice", "role": models.RoleAdmin}
+	jwtauth.SetExpiryIn(expired, -time.Minute)
+	_, expiredToken, _ := TokenAuth.Encode(expired)
+
+	tests := []struct {
+		name  string
+		token string
+		want  models.Actor
+	}{
+		{"staff", GenerateToken("alice", models.RoleAdmin), models.Actor{Username: "alice", Role: models.RoleAdmin}},
+		{"no role", GenerateToken("bob", ""), models.Actor{Username: "bob", Role: models.RoleUser}},
+		{"expired", expiredToken, models.Actor{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.Actor
+			handler := jwtauth.Verifier(TokenAuth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ActorFromContext(r.Context())
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	token, err := TokenAuth.Decode(GenerateToken("alice", models.RoleUser))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(token.Expiration()); ttl <= 0 || ttl > tokenTTL {
+		t.Errorf("expected the token to expire within %v, got %v", tokenTTL, ttl)
+	}
+}
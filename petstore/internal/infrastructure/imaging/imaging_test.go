@@ -0,0 +1,50 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{800, 600, 160, 160, 120},
+		{600, 800, 160, 120, 160},
+		{100, 50, 160, 100, 50},
+		{4000, 1, 160, 160, 1},
+	}
+	for _, tt := range tests {
+		w, h := Fit(tt.w, tt.h, tt.size)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("Fit(%d, %d, %d) = %d×%d, want %d×%d", tt.w, tt.h, tt.size, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(10, 10, 410, 210))
+	for y := 10; y < 210; y++ {
+		for x := 10; x < 410; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= 210 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	thumb := Thumbnail(src, 100)
+
+	if got := thumb.Bounds(); got.Dx() != 100 || got.Dy() != 50 {
+		t.Fatalf("expected 100×50 thumbnail, got %v", got)
+	}
+	if c := thumb.RGBAAt(10, 25); c.R != 255 || c.B != 0 {
+		t.Errorf("expected red on the left, got %v", c)
+	}
+	if c := thumb.RGBAAt(90, 25); c.B != 255 || c.R != 0 {
+		t.Errorf("expected blue on the right, got %v", c)
+	}
+}
@@ -0,0 +1,211 @@
+package responder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Format is the encoding of the bodies the responder writes and reads.
+type Format int
+
+const (
+	JSON Format = iota
+	XML
+)
+
+const (
+	mimeJSON = "application/json"
+	mimeXML  = "application/xml"
+)
+
+// maxXMLSize bounds XML bodies, which are read into memory whole to be
+// transcoded. It is the most any handler takes, that of the pet import.
+const maxXMLSize = 32 << 20
+
+var ErrUnsupportedMediaType = errors.New("Content-Type must be application/json or application/xml")
+
+// Negotiate picks the format of the responder from the Accept header of every
+// request it serves, answering 406 when the client takes neither JSON nor
+// XML, and 415 to bodies that are neither. XML bodies are handed on as JSON,
+// so handlers only ever decode JSON: bodies lists the types they are read
+// as, by the root element that encoding/xml gives each, and a root made of
+// one of these repeated is a list. XML bodies over maxXMLSize get 413.
+// passthrough lists the media types that handlers read or write themselves,
+// such as text/csv: they are let through, and the responder keeps to JSON
+// for them.
+func Negotiate(resp Responder, bodies []interface{}, passthrough ...string) func(http.Handler) http.Handler {
+	types := newBodyTypes(bodies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+
+			format, ok := negotiate(r.Header.Get("Accept"), passthrough)
+			if !ok {
+				resp.ErrorNotAcceptable(w, fmt.Errorf("Accept must allow %s or %s", mimeJSON, mimeXML))
+				return
+			}
+			nw := &negotiatedWriter{ResponseWriter: w, format: format}
+
+			if r.ContentLength != 0 {
+				mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				format, ok := requestFormat(mediaType)
+				if !ok && !contains(passthrough, mediaType) {
+					resp.ErrorUnsupportedMediaType(nw, ErrUnsupportedMediaType)
+					return
+				}
+				if ok && format == XML {
+					body, err := xmlToJSON(http.MaxBytesReader(w, r.Body, maxXMLSize), types)
+					if err != nil {
+						var maxBytesErr *http.MaxBytesError
+						if errors.As(err, &maxBytesErr) {
+							resp.ErrorTooLarge(nw, err)
+							return
+						}
+						resp.ErrorBadRequest(nw, err)
+						return
+					}
+					r = r.Clone(r.Context())
+					r.Body = io.NopCloser(bytes.NewReader(body))
+					r.ContentLength = int64(len(body))
+					r.Header.Set("Content-Type", mimeJSON)
+				}
+			}
+
+			next.ServeHTTP(nw, r)
+		})
+	}
+}
+
+// negotiatedWriter carries the format picked by Negotiate down to the
+// responder, which only sees the ResponseWriter.
+type negotiatedWriter struct {
+	http.ResponseWriter
+	format Format
+}
+
+func (w *negotiatedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *negotiatedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// formatOf is the format negotiated for w, JSON when nothing was negotiated.
+func formatOf(w http.ResponseWriter) Format {
+	for {
+		switch x := w.(type) {
+		case *negotiatedWriter:
+			return x.format
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = x.Unwrap()
+		default:
+			return JSON
+		}
+	}
+}
+
+// requestFormat tells how to read a body of mediaType. A body without a
+// Content-Type is taken for JSON, as it always has been.
+func requestFormat(mediaType string) (Format, bool) {
+	switch {
+	case mediaType == "", mediaType == mimeJSON, strings.HasSuffix(mediaType, "+json"):
+		return JSON, true
+	case mediaType == mimeXML, mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return XML, true
+	}
+	return JSON, false
+}
+
+// negotiate picks JSON or XML, whichever accept rates higher, JSON on a tie.
+// It reports false when accept rules out both, unless it allows one of
+// passthrough.
+func negotiate(accept string, passthrough []string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, true
+	}
+	ranges := parseAccept(accept)
+
+	json := quality(ranges, mimeJSON)
+	xml := quality(ranges, mimeXML)
+	if q := quality(ranges, "text/xml"); q > xml {
+		xml = q
+	}
+
+	switch {
+	case xml > 0 && xml > json:
+		return XML, true
+	case json > 0:
+		return JSON, true
+	}
+	for _, mediaType := range passthrough {
+		if quality(ranges, mediaType) > 0 {
+			return JSON, true
+		}
+	}
+	return JSON, false
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// quality is the q of the most specific range of ranges that matches
+// mediaType, 0 when none does.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	kind, _, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch r.mediaType {
+		case mediaType:
+			s = 2
+		case kind + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package responder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	passthrough := []string{"text/csv"}
+
+	tests := []struct {
+		accept string
+		format Format
+		ok     bool
+	}{
+		{"", JSON, true},
+		{"*/*", JSON, true},
+		{"application/json", JSON, true},
+		{"application/xml", XML, true},
+		{"text/xml", XML, true},
+		{"application/*", JSON, true},
+		{"application/json;q=0.5, application/xml", XML, true},
+		{"application/xml;q=0.9, */*", JSON, true},
+		{"application/json, application/xml", JSON, true},
+		{"application/json;q=0, */*;q=0.1", XML, true},
+		{"text/csv", JSON, true},
+		{"text/html", JSON, false},
+		{"application/json;q=0", JSON, false},
+	}
+	for _, tt := range tests {
+		format, ok := negotiate(tt.accept, passthrough)
+		if format != tt.format || ok != tt.ok {
+			t.Errorf("%q: expected %v %v, got %v %v", tt.accept, tt.format, tt.ok, format, ok)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	resp := NewResponder(godecoder.NewDecoder(jsoniter.Config{}), logger)
+
+	handler := Negotiate(resp, nil, "text/csv")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp.OutputJSON(w, map[string]interface{}{"name": "doggie"})
+	}))
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+		code        int
+		produces    string
+	}{
+		{"json", "", "", "", http.StatusOK, "application/json"},
+		{"xml", "application/xml", "", "", http.StatusOK, "application/xml"},
+		{"not acceptable", "text/html", "", "", http.StatusNotAcceptable, "application/json"},
+		{"json body", "", "application/json", "{}", http.StatusOK, "application/json"},
+		{"xml body", "", "text/xml; charset=utf-8", "<pet/>", http.StatusOK, "application/json"},
+		{"untyped body", "", "", "{}", http.StatusOK, "application/json"},
+		{"passthrough body", "", "text/csv", "name", http.StatusOK, "application/json"},
+		{"unsupported body", "application/xml", "text/plain", "name", http.StatusUnsupportedMediaType, "application/xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status %d, got %d: %s", tt.code, w.Code, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.produces) {
+				t.Errorf("expected %s, got %q", tt.produces, ct)
+			}
+			if w.Header().Get("Vary") != "Accept" {
+				t.Errorf("expected Vary: Accept, got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
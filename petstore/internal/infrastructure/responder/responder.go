@@ -17,12 +17,22 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// Responder writes response bodies in the format picked by Negotiate, JSON
+// unless the client asked for XML.
 type Responder interface {
 	OutputJSON(w http.ResponseWriter, responseData interface{})
 
 	ErrorUnauthorized(w http.ResponseWriter, err error)
 	ErrorBadRequest(w http.ResponseWriter, err error)
 	ErrorForbidden(w http.ResponseWriter, err error)
+	ErrorNotFound(w http.ResponseWriter, err error)
+	ErrorConflict(w http.ResponseWriter, err error)
+	ErrorPreconditionFailed(w http.ResponseWriter, err error)
+	ErrorUnprocessableEntity(w http.ResponseWriter, err error)
+	ErrorValidation(w http.ResponseWriter, errors map[string]string)
+	ErrorTooLarge(w http.ResponseWriter, err error)
+	ErrorUnsupportedMediaType(w http.ResponseWriter, err error)
+	ErrorNotAcceptable(w http.ResponseWriter, err error)
 	ErrorInternal(w http.ResponseWriter, err error)
 }
 
@@ -35,51 +45,134 @@ func NewResponder(decoder godecoder.Decoder, logger *zap.Logger) Responder {
 	return &Respond{log: logger, Decoder: decoder}
 }
 
+// OutputJSON writes responseData with 200, as XML when that is what was
+// negotiated for w.
 func (r *Respond) OutputJSON(w http.ResponseWriter, responseData interface{}) {
+	r.write(w, 0, responseData)
+}
+
+// write encodes v in the format negotiated for w, after status unless it is
+// 0, which leaves the status to the first write.
+func (r *Respond) write(w http.ResponseWriter, status int, v interface{}) {
+	if formatOf(w) == XML {
+		w.Header().Set("Content-Type", "application/xml;charset=utf-8")
+		if status != 0 {
+			w.WriteHeader(status)
+		}
+		if err := writeXML(w, v); err != nil {
+			r.log.Error("responder xml encode error", zap.Error(err))
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	//w.Header().Set("Accept", "application/json")
-	if err := r.Encode(w, responseData); err != nil {
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+	if err := r.Encode(w, v); err != nil {
 		r.log.Error("responder json encode error", zap.Error(err))
 	}
 }
 
 func (r *Respond) ErrorBadRequest(w http.ResponseWriter, err error) {
 	r.log.Info("http response bad request status code", zap.Error(err))
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusBadRequest)
-	if err := r.Encode(w, Response{
+	r.write(w, http.StatusBadRequest, Response{
 		Success: false,
 		Message: err.Error(),
 		Data:    nil,
-	}); err != nil {
-		r.log.Info("response writer error on write", zap.Error(err))
-	}
+	})
 }
 
 func (r *Respond) ErrorForbidden(w http.ResponseWriter, err error) {
 	r.log.Warn("http resposne forbidden", zap.Error(err))
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusForbidden)
-	if err := r.Encode(w, Response{
+	r.write(w, http.StatusForbidden, Response{
 		Success: false,
 		Message: err.Error(),
 		Data:    nil,
-	}); err != nil {
-		r.log.Error("response writer error on write", zap.Error(err))
-	}
+	})
+}
+
+func (r *Respond) ErrorNotFound(w http.ResponseWriter, err error) {
+	r.log.Info("http response not found", zap.Error(err))
+	r.write(w, http.StatusNotFound, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
+}
+
+func (r *Respond) ErrorConflict(w http.ResponseWriter, err error) {
+	r.log.Info("http response conflict", zap.Error(err))
+	r.write(w, http.StatusConflict, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
+}
+
+func (r *Respond) ErrorPreconditionFailed(w http.ResponseWriter, err error) {
+	r.log.Info("http response precondition failed", zap.Error(err))
+	r.write(w, http.StatusPreconditionFailed, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
+}
+
+func (r *Respond) ErrorUnprocessableEntity(w http.ResponseWriter, err error) {
+	r.log.Info("http response unprocessable entity", zap.Error(err))
+	r.write(w, http.StatusUnprocessableEntity, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
+}
+
+// ErrorValidation answers 422 with the message for every invalid field in
+// data.
+func (r *Respond) ErrorValidation(w http.ResponseWriter, errors map[string]string) {
+	r.log.Info("http response failed validation", zap.Any("errors", errors))
+	r.write(w, http.StatusUnprocessableEntity, Response{
+		Success: false,
+		Message: "validation failed",
+		Data:    errors,
+	})
+}
+
+func (r *Respond) ErrorTooLarge(w http.ResponseWriter, err error) {
+	r.log.Info("http response request entity too large", zap.Error(err))
+	r.write(w, http.StatusRequestEntityTooLarge, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
+}
+
+func (r *Respond) ErrorUnsupportedMediaType(w http.ResponseWriter, err error) {
+	r.log.Info("http response unsupported media type", zap.Error(err))
+	r.write(w, http.StatusUnsupportedMediaType, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
+}
+
+func (r *Respond) ErrorNotAcceptable(w http.ResponseWriter, err error) {
+	r.log.Info("http response not acceptable", zap.Error(err))
+	r.write(w, http.StatusNotAcceptable, Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	})
 }
 
 func (r *Respond) ErrorUnauthorized(w http.ResponseWriter, err error) {
 	r.log.Warn("http resposne Unauthorized", zap.Error(err))
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusUnauthorized)
-	if err := r.Encode(w, Response{
+	r.write(w, http.StatusUnauthorized, Response{
 		Success: false,
 		Message: err.Error(),
 		Data:    nil,
-	}); err != nil {
-		r.log.Error("response writer error on write", zap.Error(err))
-	}
+	})
 }
 
 func (r *Respond) ErrorInternal(w http.ResponseWriter, err error) {
@@ -87,13 +180,9 @@ func (r *Respond) ErrorInternal(w http.ResponseWriter, err error) {
 		return
 	}
 	r.log.Error("http response internal error", zap.Error(err))
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusInternalServerError)
-	if err := r.Encode(w, Response{
+	r.write(w, http.StatusInternalServerError, Response{
 		Success: false,
 		Message: err.Error(),
 		Data:    nil,
-	}); err != nil {
-		r.log.Error("response writer error on write", zap.Error(err))
-	}
+	})
 }
@@ -0,0 +1,422 @@
+package responder
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XML bodies are written with encoding/xml and the xml tags of the models,
+// so a struct is a document named after its type. Lists, maps and text,
+// which are not, are written inside a <response> element.
+const xmlRoot = "response"
+
+// writeXML writes v as an XML document.
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	root := xml.StartElement{Name: xml.Name{Local: xmlRoot}}
+
+	var err error
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch {
+	case !rv.IsValid():
+		err = enc.EncodeElement("", root)
+	case rv.Kind() == reflect.Struct:
+		err = enc.Encode(v)
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8, rv.Kind() == reflect.Array:
+		err = writeXMLItems(enc, root, rv)
+	default:
+		err = enc.EncodeElement(xmlValue{v}, root)
+	}
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// writeXMLItems writes the items of list inside root, each named after its
+// type like a document of its own, or <item> when it has no such name.
+func writeXMLItems(enc *xml.Encoder, root xml.StartElement, list reflect.Value) error {
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+	for i := 0; i < list.Len(); i++ {
+		item := list.Index(i).Interface()
+		if reflect.Indirect(reflect.ValueOf(item)).Kind() == reflect.Struct {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := enc.EncodeElement(xmlValue{item}, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(root.End())
+}
+
+// xmlValue writes maps, which encoding/xml can't, as one element per key in
+// key order, and leaves everything else to encoding/xml. A list repeats its
+// element once per item; nil writes nothing.
+type xmlValue struct {
+	v interface{}
+}
+
+func (x xmlValue) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	rv := reflect.ValueOf(x.v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			name := xml.StartElement{Name: xml.Name{Local: fmt.Sprint(key)}}
+			if err := e.EncodeElement(xmlValue{rv.MapIndex(key).Interface()}, name); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.EncodeElement(xmlValue{rv.Index(i).Interface()}, start); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return e.EncodeElement(x.v, start)
+}
+
+// MarshalXML writes Data through xmlValue, since it is often a map.
+func (r Response) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Success, xml.StartElement{Name: xml.Name{Local: "success"}}); err != nil {
+		return err
+	}
+	if r.Message != "" {
+		if err := e.EncodeElement(r.Message, xml.StartElement{Name: xml.Name{Local: "message"}}); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeElement(xmlValue{r.Data}, xml.StartElement{Name: xml.Name{Local: "data"}}); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// bodyTypes maps the root element of an XML request body to the type it is
+// read as: the element encoding/xml names a document of that type with.
+type bodyTypes map[string]reflect.Type
+
+func newBodyTypes(bodies []interface{}) bodyTypes {
+	types := make(bodyTypes, len(bodies))
+	for _, body := range bodies {
+		t := reflect.TypeOf(body)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		name := t.Name()
+		if f, ok := t.FieldByName("XMLName"); ok {
+			if tag, _, _ := strings.Cut(f.Tag.Get("xml"), ","); tag != "" {
+				name = tag
+			}
+		}
+		types[name] = t
+	}
+	return types
+}
+
+func (b bodyTypes) lookup(name string) (reflect.Type, bool) {
+	if t, ok := b[name]; ok {
+		return t, true
+	}
+	for known, t := range b {
+		if strings.EqualFold(known, name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// xmlNode is an element of a request body: its text, or its child elements
+// in document order.
+type xmlNode struct {
+	name     string
+	text     string
+	children []*xmlNode
+}
+
+// xmlToJSON turns an XML request body into the JSON document that handlers
+// decode. The type registered for the root element tells which elements are
+// lists, numbers or strings and what they are called in JSON; a root holding
+// only registered elements is a list of them. Elements of no known type are
+// kept as text, under their own names.
+func xmlToJSON(r io.Reader, types bodyTypes) ([]byte, error) {
+	root, err := parseXML(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := types.lookup(root.name); ok {
+		return json.Marshal(jsonValue(root, t))
+	}
+	if len(root.children) > 0 {
+		if t, ok := types.lookup(root.children[0].name); ok {
+			list := make([]interface{}, 0, len(root.children))
+			for _, child := range root.children {
+				if child.name != root.children[0].name {
+					list = nil
+					break
+				}
+				list = append(list, jsonValue(child, t))
+			}
+			if list != nil {
+				return json.Marshal(list)
+			}
+		}
+	}
+	return json.Marshal(anyValue(root))
+}
+
+// maxXMLDepth bounds how deep request bodies nest, well past any model.
+const maxXMLDepth = 32
+
+var errXMLTooDeep = fmt.Errorf("XML bodies must not nest more than %d elements deep", maxXMLDepth)
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == maxXMLDepth {
+				return nil, errXMLTooDeep
+			}
+			node := &xmlNode{name: t.Name.Local}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return node, nil
+			}
+		}
+	}
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// jsonValue is the JSON value for node when it is decoded into t. Text that
+// does not fit t is kept as a string, so that decoding it fails with the
+// same error as the JSON would.
+func jsonValue(node *xmlNode, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) || reflect.PtrTo(t).Implements(textUnmarshaler) {
+		if len(node.children) > 0 {
+			return anyValue(node)
+		}
+		return node.text
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := bodyFields(t)
+		object := make(map[string]interface{}, len(node.children))
+		for _, child := range node.children {
+			f, ok := fieldOf(fields, child.name)
+			if !ok {
+				object[child.name] = anyValue(child)
+				continue
+			}
+			if isList(f.typ) {
+				list, _ := object[f.json].([]interface{})
+				object[f.json] = append(list, jsonValue(child, listElem(f.typ)))
+			} else {
+				object[f.json] = jsonValue(child, f.typ)
+			}
+		}
+		return object
+	case reflect.Map:
+		object := make(map[string]interface{}, len(node.children))
+		for _, child := range node.children {
+			object[child.name] = jsonValue(child, t.Elem())
+		}
+		return object
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return node.text
+		}
+		list := make([]interface{}, 0, len(node.children))
+		for _, child := range node.children {
+			list = append(list, jsonValue(child, t.Elem()))
+		}
+		return list
+	case reflect.Bool:
+		if s := strings.TrimSpace(node.text); s == "true" || s == "false" {
+			return json.RawMessage(s)
+		}
+		return node.text
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s := strings.TrimSpace(node.text); isNumber(s) {
+			return json.RawMessage(s)
+		}
+		return node.text
+	case reflect.Interface:
+		return anyValue(node)
+	default:
+		return node.text
+	}
+}
+
+// anyValue is node without a type to go by: text, or an object whose
+// repeated elements are lists.
+func anyValue(node *xmlNode) interface{} {
+	if len(node.children) == 0 {
+		return node.text
+	}
+	object := make(map[string]interface{}, len(node.children))
+	for _, child := range node.children {
+		value := anyValue(child)
+		switch prev := object[child.name].(type) {
+		case nil:
+			object[child.name] = value
+		case []interface{}:
+			object[child.name] = append(prev, value)
+		default:
+			object[child.name] = []interface{}{prev, value}
+		}
+	}
+	return object
+}
+
+// bodyField is a field of a body type under its XML and JSON names.
+type bodyField struct {
+	xml, json string
+	typ       reflect.Type
+}
+
+// bodyFields lists the fields of t that both encodings read, those of
+// embedded structs included.
+func bodyFields(t reflect.Type) []bodyField {
+	var fields []bodyField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		xmlTag, jsonTag := f.Tag.Get("xml"), f.Tag.Get("json")
+		if xmlTag == "-" || jsonTag == "-" || f.Name == "XMLName" {
+			continue
+		}
+		xmlName, _, _ := strings.Cut(xmlTag, ",")
+		jsonName, _, _ := strings.Cut(jsonTag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && jsonName == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, bodyFields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if xmlName == "" {
+			xmlName = f.Name
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		fields = append(fields, bodyField{xml: xmlName, json: jsonName, typ: f.Type})
+	}
+	return fields
+}
+
+// fieldOf finds the field for an element named name, falling back to a case
+// insensitive match.
+func fieldOf(fields []bodyField, name string) (bodyField, bool) {
+	for _, f := range fields {
+		if f.xml == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.xml, name) {
+			return f, true
+		}
+	}
+	return bodyField{}, false
+}
+
+// isList reports whether a field of type t is a list, which XML spells as
+// its element repeated.
+func isList(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) || reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return false
+	}
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+func listElem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Elem()
+}
+
+func isNumber(s string) bool {
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
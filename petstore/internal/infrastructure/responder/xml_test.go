@@ -0,0 +1,216 @@
+package responder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"test/internal/models"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+func newTestResponder() Responder {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	return NewResponder(godecoder.NewDecoder(jsoniter.Config{SortMapKeys: true}), logger)
+}
+
+func TestOutputXML(t *testing.T) {
+	resp := newTestResponder()
+	name := "doggie"
+	birth, _ := models.ParseDate("2020-01-02")
+	pet := models.Pet{
+		ID:        7,
+		Name:      &name,
+		Category:  &models.Category{ID: 1, Name: "Dogs"},
+		PhotoUrls: []string{"a.jpg", "b.jpg"},
+		Tags:      []*models.Tag{{ID: 2, Name: "good & loyal"}},
+		Status:    "available",
+		BirthDate: &birth,
+	}
+
+	tests := []struct {
+		name string
+		data interface{}
+		body string
+	}{
+		{
+			"struct",
+			pet,
+			"<Pet><category><id>1</id><name>Dogs</name></category><id>7</id><name>doggie</name>" +
+				"<photoUrls>a.jpg</photoUrls><photoUrls>b.jpg</photoUrls><status>available</status>" +
+				"<tags><id>2</id><name>good &amp; loyal</name></tags><birthDate>2020-01-02</birthDate></Pet>",
+		},
+		{
+			"list",
+			[]*models.Tag{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+			"<response><Tag><id>1</id><name>a</name></Tag><Tag><id>2</id><name>b</name></Tag></response>",
+		},
+		{
+			"map",
+			map[string]interface{}{"data": []int{1, 2}, "metadata": map[string]int{"page_size": 2}},
+			"<response><data>1</data><data>2</data><metadata><page_size>2</page_size></metadata></response>",
+		},
+		{"text", "Pet added to favorites", "<response>Pet added to favorites</response>"},
+		{"empty", []string{}, "<response></response>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			resp.OutputJSON(&negotiatedWriter{ResponseWriter: w, format: XML}, tt.data)
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/xml;charset=utf-8" {
+				t.Errorf("unexpected Content-Type %q", ct)
+			}
+			body := strings.TrimPrefix(w.Body.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+			if body != tt.body {
+				t.Errorf("expected\n%s\ngot\n%s", tt.body, body)
+			}
+		})
+	}
+}
+
+func TestErrorXML(t *testing.T) {
+	resp := newTestResponder()
+	w := httptest.NewRecorder()
+
+	resp.ErrorValidation(&negotiatedWriter{ResponseWriter: w, format: XML}, map[string]string{"name": "must be provided"})
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	want := "<Response><success>false</success><message>validation failed</message><data><name>must be provided</name></data></Response>"
+	if !strings.HasSuffix(w.Body.String(), want) {
+		t.Errorf("expected %s, got %s", want, w.Body)
+	}
+}
+
+// decodeXML sends body through Negotiate to a handler that decodes JSON into
+// v, the way the controllers do.
+func decodeXML(t *testing.T, body string, v interface{}) (int, error) {
+	t.Helper()
+	var decodeErr error
+	handler := Negotiate(newTestResponder(), []interface{}{models.Pet{}, models.User{}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected the handler to see application/json, got %q", ct)
+		}
+		decodeErr = json.NewDecoder(r.Body).Decode(v)
+	}))
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/xml")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code, decodeErr
+}
+
+func TestNegotiateDecodesXML(t *testing.T) {
+	t.Run("pet", func(t *testing.T) {
+		body := `<Pet>
+			<id>7</id>
+			<name>007</name>
+			<category><id>1</id><name>Dogs</name></category>
+			<photoUrls>a.jpg</photoUrls>
+			<photoUrls>b.jpg</photoUrls>
+			<tags><id>2</id><name>loyal</name></tags>
+			<status>available</status>
+			<statusChangedAt>2024-05-01T10:00:00Z</statusChangedAt>
+			<birthDate>2020-01-02</birthDate>
+		</Pet>`
+
+		var pet models.Pet
+		if _, err := decodeXML(t, body, &pet); err != nil {
+			t.Fatal(err)
+		}
+
+		name := "007"
+		birth, _ := models.ParseDate("2020-01-02")
+		changed := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		want := models.Pet{
+			ID:              7,
+			Name:            &name,
+			Category:        &models.Category{ID: 1, Name: "Dogs"},
+			PhotoUrls:       []string{"a.jpg", "b.jpg"},
+			Tags:            []*models.Tag{{ID: 2, Name: "loyal"}},
+			Status:          "available",
+			StatusChangedAt: &changed,
+			BirthDate:       &birth,
+		}
+		if !reflect.DeepEqual(pet, want) {
+			t.Errorf("expected %+v, got %+v", want, pet)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		body := `<users><User><username>a</username></User><User><username>b</username></User></users>`
+
+		var users []struct {
+			Username string `json:"username"`
+		}
+		if _, err := decodeXML(t, body, &users); err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 2 || users[0].Username != "a" || users[1].Username != "b" {
+			t.Errorf("unexpected users %+v", users)
+		}
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		body := `<User><username>a</username><password>secret1</password></User>`
+
+		var input struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if _, err := decodeXML(t, body, &input); err != nil {
+			t.Fatal(err)
+		}
+		if input.Username != "a" || input.Password != "secret1" {
+			t.Errorf("unexpected input %+v", input)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		var pet models.Pet
+		if _, err := decodeXML(t, `<Pet><id>seven</id></Pet>`, &pet); err == nil {
+			t.Error("expected an error for a non-numeric id")
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var pet models.Pet
+		if code, _ := decodeXML(t, `<Pet><id>7</Pet>`, &pet); code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+		}
+	})
+
+	t.Run("too deep", func(t *testing.T) {
+		body := strings.Repeat("<a>", maxXMLDepth+1) + strings.Repeat("</a>", maxXMLDepth+1)
+
+		var v interface{}
+		if code, _ := decodeXML(t, body, &v); code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+		}
+		body = strings.Repeat("<a>", maxXMLDepth) + strings.Repeat("</a>", maxXMLDepth)
+		if _, err := decodeXML(t, body, &v); err != nil {
+			t.Errorf("expected %d levels to be read, got %v", maxXMLDepth, err)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		body := "<Pet><name>" + strings.Repeat("a", maxXMLSize) + "</name></Pet>"
+
+		var pet models.Pet
+		if code, _ := decodeXML(t, body, &pet); code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, code)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrValidation = errors.New("validation error")
+
+// ValidationError lists what is wrong with a request, keyed by field. It
+// matches ErrValidation with errors.Is, and the error Causes gives for any
+// field among its Errors, so that callers can single out a field.
+type ValidationError struct {
+	Errors map[string]string
+	Causes map[string]error
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for field, message := range e.Errors {
+		fields = append(fields, field+" "+message)
+	}
+	sort.Strings(fields)
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(fields, ", "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	if target == ErrValidation {
+		return true
+	}
+	for field, cause := range e.Causes {
+		if _, ok := e.Errors[field]; ok && cause == target {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -14,3 +15,21 @@ func TestValidator(t *testing.T) {
 	}
 
 }
+
+func TestValidationError(t *testing.T) {
+	errTooLong, errNoEmail := errors.New("too long"), errors.New("no email")
+	err := error(&ValidationError{
+		Errors: map[string]string{"name": "must be provided", "bio": "must not be more than 10 characters long"},
+		Causes: map[string]error{"bio": errTooLong, "email": errNoEmail},
+	})
+
+	if err.Error() != "validation error: bio must not be more than 10 characters long, name must be provided" {
+		t.Errorf("unexpected message %q", err)
+	}
+	if !errors.Is(err, ErrValidation) || !errors.Is(err, errTooLong) {
+		t.Errorf("expected %v to match ErrValidation and the bio cause", err)
+	}
+	if errors.Is(err, errNoEmail) {
+		t.Errorf("expected %v not to match the cause of a valid field", err)
+	}
+}
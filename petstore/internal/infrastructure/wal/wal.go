@@ -0,0 +1,311 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClosed        = errors.New("log closed")
+	ErrUnknownPolicy = errors.New("unknown fsync policy")
+	ErrBroken        = errors.New("log broken by a failed append")
+)
+
+// SyncPolicy says when appended records are fsynced to disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs before Append returns. Nothing acknowledged is lost.
+	SyncAlways SyncPolicy = "always"
+
+	// SyncInterval fsyncs in the background, losing at most one interval of
+	// records when the machine goes down.
+	SyncInterval SyncPolicy = "interval"
+
+	// SyncNever leaves flushing to the operating system. Records survive the
+	// process crashing but not the machine.
+	SyncNever SyncPolicy = "never"
+)
+
+// ParseSyncPolicy checks s against the known policies.
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncInterval, SyncNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownPolicy, s)
+}
+
+// headerSize is the length and the CRC-32 of the payload in front of every
+// record.
+const headerSize = 8
+
+// maxRecordSize guards replay against reading a garbage length.
+const maxRecordSize = 64 << 20
+
+// Log is an append-only file of records. Every record is framed with its
+// length and checksum, so a record torn by a crash is recognised on replay
+// and cut off instead of being read as garbage.
+type Log struct {
+	mu     sync.Mutex
+	f      *os.File
+	policy SyncPolicy
+	dirty  bool
+	closed bool
+
+	// end is the offset after the last good record. broken is set when a
+	// failed append couldn't be cut off, which would leave a torn record for
+	// later ones to hide behind.
+	end    int64
+	broken error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open opens the log at path, creating it and its directory if needed.
+// Records are appended after the existing ones; call Replay first to read
+// them back. interval is only used with SyncInterval.
+func Open(path string, policy SyncPolicy, interval time.Duration) (*Log, error) {
+	if _, err := ParseSyncPolicy(string(policy)); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &Log{f: f, policy: policy, end: end}
+	if policy == SyncInterval {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		l.stop, l.done = make(chan struct{}), make(chan struct{})
+		go l.syncEvery(interval)
+	}
+	return l, nil
+}
+
+// Append writes payload as one record. A record that fails to be written,
+// or synced under SyncAlways, is cut off again, so that it doesn't end the
+// log on replay before the records appended after it.
+func (l *Log) Append(payload []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	if l.broken != nil {
+		return fmt.Errorf("%w: %v", ErrBroken, l.broken)
+	}
+
+	record := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[headerSize:], payload)
+
+	_, err := l.f.Write(record)
+	if err == nil && l.policy == SyncAlways {
+		err = l.f.Sync()
+	}
+	if err != nil {
+		l.rollback()
+		return err
+	}
+
+	l.end += int64(len(record))
+	if l.policy != SyncAlways {
+		l.dirty = true
+	}
+	return nil
+}
+
+// rollback truncates the log back to the last good record after a failed
+// append, or marks it broken if that fails too.
+func (l *Log) rollback() {
+	if err := l.f.Truncate(l.end); err != nil {
+		l.broken = err
+		return
+	}
+	if _, err := l.f.Seek(l.end, io.SeekStart); err != nil {
+		l.broken = err
+	}
+}
+
+// Replay calls fn with every record in the log, oldest first. A torn or
+// corrupt record ends the log: it and anything after it are truncated, so
+// new records follow the last good one.
+func (l *Log) Replay(fn func(payload []byte) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(l.f)
+	var good int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+			break
+		}
+		if err := fn(payload); err != nil {
+			return err
+		}
+		good += headerSize + int64(size)
+	}
+
+	if err := l.f.Truncate(good); err != nil {
+		return err
+	}
+	if _, err := l.f.Seek(good, io.SeekStart); err != nil {
+		return err
+	}
+	l.end = good
+	return nil
+}
+
+// Reset empties the log, once its records are safe in a snapshot.
+func (l *Log) Reset() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	l.end, l.broken = 0, nil
+	l.dirty = false
+	return l.f.Sync()
+}
+
+// Sync flushes appended records to disk.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.sync()
+}
+
+func (l *Log) sync() error {
+	if l.closed || !l.dirty {
+		return nil
+	}
+	l.dirty = false
+	return l.f.Sync()
+}
+
+func (l *Log) syncEvery(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.Sync()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Close syncs and closes the log whatever the policy.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	err := l.f.Sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.mu.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+	return err
+}
+
+// WriteFile replaces the file at path with data so that readers find either
+// the old or the new content, never a mix: data goes to a temporary file
+// that is fsynced and then renamed over path.
+func WriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
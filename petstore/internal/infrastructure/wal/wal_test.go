@@ -0,0 +1,198 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func replayAll(t *testing.T, l *Log) []string {
+	t.Helper()
+	var got []string
+	err := l.Replay(func(payload []byte) error {
+		got = append(got, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestLog(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncInterval, SyncNever} {
+		t.Run(string(policy), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log", "test.wal")
+
+			l, err := Open(path, policy, 10*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, rec := range []string{"one", "two", ""} {
+				if err := l.Append([]byte(rec)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Append([]byte("late")); !errors.Is(err, ErrClosed) {
+				t.Errorf("expected ErrClosed got %v", err)
+			}
+
+			l, err = Open(path, policy, 10*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			got := replayAll(t, l)
+			if len(got) != 3 || got[0] != "one" || got[1] != "two" || got[2] != "" {
+				t.Errorf("unexpected records %q", got)
+			}
+		})
+	}
+}
+
+func TestLogTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wal")
+
+	l, err := Open(path, SyncNever, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append([]byte("one"))
+	l.Append([]byte("two"))
+	l.Close()
+
+	// Cut the last record in half, as a crash in the middle of a write would.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(path, SyncNever, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replayAll(t, l); len(got) != 1 || got[0] != "one" {
+		t.Fatalf("expected only the first record got %q", got)
+	}
+	if err := l.Append([]byte("three")); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	l, err = Open(path, SyncNever, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if got := replayAll(t, l); len(got) != 2 || got[1] != "three" {
+		t.Errorf("expected the new record after the torn one got %q", got)
+	}
+}
+
+func TestLogFailedAppend(t *testing.T) {
+	t.Run("cut off", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.wal")
+
+		l, err := Open(path, SyncAlways, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Append([]byte("one"))
+
+		// Leave half a record behind, as a short write would, and roll back
+		// as Append does when the write fails.
+		if _, err := l.f.Write([]byte{3, 0, 0, 0, 1}); err != nil {
+			t.Fatal(err)
+		}
+		l.rollback()
+		if err := l.Append([]byte("two")); err != nil {
+			t.Fatal(err)
+		}
+		l.Close()
+
+		l, err = Open(path, SyncAlways, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		if got := replayAll(t, l); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+			t.Errorf("expected the record after the failed one to be kept, got %q", got)
+		}
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		l, err := Open(filepath.Join(t.TempDir(), "test.wal"), SyncAlways, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Append([]byte("one"))
+
+		// With the file gone neither the write nor the truncation works.
+		l.f.Close()
+		if err := l.Append([]byte("two")); err == nil {
+			t.Fatal("expected the append to fail")
+		}
+		if err := l.Append([]byte("three")); !errors.Is(err, ErrBroken) {
+			t.Errorf("expected ErrBroken got %v", err)
+		}
+	})
+}
+
+func TestLogReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wal")
+
+	l, err := Open(path, SyncAlways, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.Append([]byte("one"))
+	if err := l.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	l.Append([]byte("two"))
+
+	if got := replayAll(t, l); len(got) != 1 || got[0] != "two" {
+		t.Errorf("expected only the record after the reset got %q", got)
+	}
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	if p, err := ParseSyncPolicy("interval"); err != nil || p != SyncInterval {
+		t.Errorf("expected interval got %q %v", p, err)
+	}
+	if _, err := ParseSyncPolicy("sometimes"); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("expected ErrUnknownPolicy got %v", err)
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "test.wal"), "sometimes", 0); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("expected ErrUnknownPolicy got %v", err)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap", "test.snapshot")
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil || string(got) != "second" {
+		t.Errorf("expected second got %q %v", got, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected temporary files to be gone, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+const (
+	RoleUser  = "user"
+	RoleStaff = "staff"
+	RoleAdmin = "admin"
+)
+
+// Actor is the authenticated user on whose behalf a change is made.
+type Actor struct {
+	Username string
+	Role     string
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+// IsStaff reports whether the actor works for the store. Admins are staff
+// too.
+func (a Actor) IsStaff() bool {
+	return a.Role == RoleStaff || a.IsAdmin()
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+const (
+	AdoptionPending  = "pending"
+	AdoptionApproved = "approved"
+	AdoptionRejected = "rejected"
+	AdoptionExpired  = "expired"
+)
+
+// AdoptionStatuses lists every status of an adoption application.
+var AdoptionStatuses = []string{AdoptionPending, AdoptionApproved, AdoptionRejected, AdoptionExpired}
+
+// Adoption is a customer's application to adopt a pet. While it is pending
+// the pet is pending too; approving it sells the pet through a store order.
+type Adoption struct {
+	ID int64 `json:"id" xml:"id"`
+
+	// pet applied for
+	PetID int64 `json:"petId" xml:"petId"`
+
+	// username of the customer who applied
+	Applicant string `json:"applicant" xml:"applicant"`
+
+	// Enum: ["pending","approved","rejected","expired"]
+	Status string `json:"status" xml:"status"`
+
+	// note from the applicant
+	Message string `json:"message,omitempty" xml:"message,omitempty"`
+
+	// why staff rejected the application
+	Reason string `json:"reason,omitempty" xml:"reason,omitempty"`
+
+	// staff member who approved or rejected the application
+	DecidedBy string `json:"decidedBy,omitempty" xml:"decidedBy,omitempty"`
+
+	// store order created on approval
+	OrderID *int64 `json:"orderId,omitempty" xml:"orderId,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt" xml:"createdAt"`
+
+	// when the application was approved, rejected or expired
+	DecidedAt *time.Time `json:"decidedAt,omitempty" xml:"decidedAt,omitempty"`
+
+	// pending applications expire at this time
+	ExpiresAt time.Time `json:"expiresAt" xml:"expiresAt"`
+
+	// bumped on every write
+	Version int `json:"-" xml:"-"`
+}
+
+// AdoptionFilter narrows adoption listings. Zero values leave the
+// corresponding column unrestricted.
+type AdoptionFilter struct {
+
+	// pet applied for
+	PetID int64
+
+	// username of the applicant
+	Applicant string
+
+	// any of these statuses
+	Statuses []string
+}
@@ -3,8 +3,8 @@ package models
 type Category struct {
 
 	// id
-	ID int64 `json:"id,omitempty"`
+	ID int64 `json:"id,omitempty" xml:"id,omitempty"`
 
 	// name
-	Name string `json:"name,omitempty"`
+	Name string `json:"name,omitempty" xml:"name,omitempty"`
 }
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DateLayout is how dates are written in json and csv.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date without a time of day, such as a date of birth. It
+// is stored as a date column and written as "2006-01-02".
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date of t in its own location.
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate reads a date written as "2006-01-02".
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New("date must be a string like 2006-01-02")
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return fmt.Errorf("date %q must look like 2006-01-02", s)
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+	parsed, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.Time, nil
+}
+
+func (d *Date) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d = NewDate(v)
+		return nil
+	case string:
+		return d.scanString(v)
+	case []byte:
+		return d.scanString(string(v))
+	}
+	return fmt.Errorf("can't scan %T into a date", src)
+}
+
+func (d *Date) scanString(s string) error {
+	if len(s) < len(DateLayout) {
+		return fmt.Errorf("can't scan %q into a date", s)
+	}
+	parsed, err := ParseDate(s[:len(DateLayout)])
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Age is how old a pet is in completed years and months.
+type Age struct {
+	Years  int `json:"years" xml:"years"`
+	Months int `json:"months" xml:"months"`
+}
+
+// AgeAt returns the age of someone born on d at the date of now, nil when d
+// lies after now.
+func (d Date) AgeAt(now time.Time) *Age {
+	today := NewDate(now)
+	if today.Before(d.Time) {
+		return nil
+	}
+
+	months := (today.Year()-d.Year())*12 + int(today.Month()-d.Month())
+	if today.Day() < d.Day() {
+		months--
+	}
+	return &Age{Years: months / 12, Months: months % 12}
+}
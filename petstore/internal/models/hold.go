@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Hold reserves a pet for one customer during checkout. Until it expires
+// nobody else can order the pet.
+type Hold struct {
+	ID int64 `json:"id" xml:"id"`
+
+	// pet held
+	PetID int64 `json:"petId" xml:"petId"`
+
+	// username of the customer holding the pet
+	Holder string `json:"holder" xml:"holder"`
+
+	CreatedAt time.Time `json:"createdAt" xml:"createdAt"`
+
+	// the pet can be ordered by anyone again after this time
+	ExpiresAt time.Time `json:"expiresAt" xml:"expiresAt"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+const (
+	RecordVaccination = "vaccination"
+	RecordTreatment   = "treatment"
+	RecordCheckup     = "checkup"
+)
+
+// MedicalRecordTypes lists every type of medical record.
+var MedicalRecordTypes = []string{RecordVaccination, RecordTreatment, RecordCheckup}
+
+// MedicalRecord is one entry of a pet's medical file, kept by staff.
+type MedicalRecord struct {
+	ID int64 `json:"id" xml:"id"`
+
+	// pet the record belongs to
+	PetID int64 `json:"petId" xml:"petId"`
+
+	// Enum: ["vaccination","treatment","checkup"]
+	Type string `json:"type" xml:"type"`
+
+	// what was given or done, e.g. Rabies
+	Title string `json:"title" xml:"title"`
+
+	Notes string `json:"notes,omitempty" xml:"notes,omitempty"`
+
+	// vet or clinic who did it
+	Vet string `json:"vet,omitempty" xml:"vet,omitempty"`
+
+	// day it was given or done
+	PerformedOn Date `json:"performedOn" xml:"performedOn"`
+
+	// day the next one is due, such as a booster shot
+	DueOn *Date `json:"dueOn,omitempty" xml:"dueOn,omitempty"`
+
+	// staff member who entered the record
+	CreatedBy string `json:"createdBy" xml:"createdBy"`
+
+	CreatedAt time.Time `json:"createdAt" xml:"createdAt"`
+}
+
+// MedicalSummary sums up a pet's medical file for PetGetByID.
+type MedicalSummary struct {
+	Records int `json:"records" xml:"records"`
+
+	// day of the latest check-up
+	LastCheckup *Date `json:"lastCheckup,omitempty" xml:"lastCheckup,omitempty"`
+
+	// latest vaccination of each kind, by title
+	Vaccinations []MedicalRecord `json:"vaccinations" xml:"vaccinations"`
+
+	// earliest day something falls due
+	NextDue *Date `json:"nextDue,omitempty" xml:"nextDue,omitempty"`
+
+	// titles of what is past due
+	Overdue []string `json:"overdue,omitempty" xml:"overdue,omitempty"`
+}
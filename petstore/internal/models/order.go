@@ -7,22 +7,22 @@ import (
 type Order struct {
 
 	// complete
-	Complete bool `json:"complete,omitempty"`
+	Complete bool `json:"complete,omitempty" xml:"complete,omitempty"`
 
 	// id
-	ID int64 `json:"id,omitempty"`
+	ID int64 `json:"id,omitempty" xml:"id,omitempty"`
 
 	// pet Id
-	PetID int64 `json:"petId,omitempty"`
+	PetID int64 `json:"petId,omitempty" xml:"petId,omitempty"`
 
 	// quantity
-	Quantity int32 `json:"quantity,omitempty"`
+	Quantity int32 `json:"quantity,omitempty" xml:"quantity,omitempty"`
 
 	// ship date
 	// Format: date-time
-	ShipDate time.Time `json:"shipDate,omitempty"`
+	ShipDate time.Time `json:"shipDate,omitempty" xml:"shipDate,omitempty"`
 
 	// Order Status
 	// Enum: ["placed","approved","delivered"]
-	Status string `json:"status,omitempty"`
+	Status string `json:"status,omitempty" xml:"status,omitempty"`
 }
@@ -4,37 +4,76 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
-var Pets []*Pet
-
-var PrimaryKeyIDx map[int64]*Pet
-
 type Pet struct {
 
 	// category
-	Category *Category `json:"category,omitempty"`
+	Category *Category `json:"category,omitempty" xml:"category,omitempty"`
 
 	// id
-	ID int64 `json:"id,omitempty"`
+	ID int64 `json:"id,omitempty" xml:"id,omitempty"`
 
 	// name
 	// Example: doggie
 	// Required: true
-	Name *string `json:"name"`
+	Name *string `json:"name" xml:"name"`
 
 	// photo urls
 	// Required: true
 	PhotoUrls []string `json:"photoUrls" xml:"photoUrls"`
 
+	// uploaded photos with their thumbnails
+	Photos Photos `json:"photos,omitempty" xml:"photos"`
+
 	// pet status in the store
 	// Enum: ["available","pending","sold"]
-	Status string `json:"status,omitempty"`
+	Status string `json:"status,omitempty" xml:"status,omitempty"`
+
+	// user who made the last status change
+	StatusChangedBy string `json:"statusChangedBy,omitempty" xml:"statusChangedBy,omitempty"`
+
+	// time of the last status change
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty" xml:"statusChangedAt,omitempty"`
 
 	// tags
 	Tags []*Tag `json:"tags" xml:"tags"`
+
+	// breed, free text
+	Breed string `json:"breed,omitempty" xml:"breed,omitempty"`
+
+	// date of birth
+	BirthDate *Date `json:"birthDate,omitempty" xml:"birthDate,omitempty"`
+
+	// age computed from the date of birth when the pet is served, never stored
+	Age *Age `json:"age,omitempty" xml:"age,omitempty"`
+
+	// Enum: ["male","female"]
+	Sex string `json:"sex,omitempty" xml:"sex,omitempty"`
+
+	// weight in grams
+	WeightGrams int64 `json:"weightGrams,omitempty" xml:"weightGrams,omitempty"`
+
+	// description
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+
+	// price in minor units of the store currency, e.g. cents
+	Price int64 `json:"price,omitempty" xml:"price,omitempty"`
+
+	// username of the user who created the pet and owns it
+	CreatedBy string `json:"createdBy,omitempty" xml:"createdBy,omitempty"`
+
+	// number of users who have the pet among their favorites
+	FavoriteCount int `json:"favoriteCount,omitempty" xml:"favoriteCount,omitempty"`
+
+	// summary of the medical records, only served when asked for
+	MedicalSummary *MedicalSummary `json:"medical_summary,omitempty" xml:"medical_summary,omitempty"`
+
+	// bumped on every write, served as the ETag
+	Version int `json:"-" xml:"-"`
 }
 
 func (c Category) Value() (driver.Value, error) {
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	PetEventCreated       = "created"
+	PetEventUpdated       = "updated"
+	PetEventStatusChanged = "status_changed"
+	PetEventDeleted       = "deleted"
+)
+
+// PetEvent is one entry of a pet's change history.
+type PetEvent struct {
+	ID int64 `json:"id" xml:"id"`
+
+	// pet the change was made to, kept after the pet is deleted
+	PetID int64 `json:"petId" xml:"petId"`
+
+	// one of created, updated, status_changed, deleted
+	Action string `json:"action" xml:"action"`
+
+	// user who made the change
+	Actor string `json:"actor" xml:"actor"`
+
+	CreatedAt time.Time `json:"createdAt" xml:"createdAt"`
+
+	// changed fields with their previous and new values
+	Changes PetChanges `json:"changes" xml:"changes"`
+}
+
+// FieldChange holds a field's value before and after a change. Before is
+// null for created pets and After is null for deleted ones.
+type FieldChange struct {
+	Before any `json:"before" xml:"before"`
+	After  any `json:"after" xml:"after"`
+}
+
+// PetChanges is stored as a jsonb column keyed by field name. Value returns a
+// string for the same reason Photos does.
+type PetChanges map[string]FieldChange
+
+func (c PetChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *PetChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// MarshalXML writes one element per field, by field name, which encoding/xml
+// can't do for a map.
+func (c PetChanges) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	fields := make([]string, 0, len(c))
+	for field := range c {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if err := e.EncodeElement(c[field], xml.StartElement{Name: xml.Name{Local: field}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML writes Before and After as text: scalars as they are, anything
+// else as JSON. A null side is left out.
+func (c FieldChange) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, side := range []struct {
+		name  string
+		value any
+	}{{"before", c.Before}, {"after", c.After}} {
+		var text string
+		switch v := side.value.(type) {
+		case nil:
+			continue
+		case string, bool, int, int64, float64:
+			text = fmt.Sprint(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			text = string(data)
+		}
+		if err := e.EncodeElement(text, xml.StartElement{Name: xml.Name{Local: side.name}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,36 @@
+package models
+
+// PetFilter narrows pet listings. Zero values and nil bounds leave the
+// corresponding column unrestricted.
+type PetFilter struct {
+
+	// name prefix, matched case-insensitively
+	Name string
+
+	// category id
+	CategoryID int64
+
+	// category name
+	Category string
+
+	// tag name the pet must carry
+	Tag string
+
+	// any of these statuses
+	Statuses []string
+
+	// username of the owner
+	CreatedBy string
+
+	// breed, matched case-insensitively
+	Breed string
+
+	// price range in minor units, inclusive
+	MinPrice *int64
+	MaxPrice *int64
+
+	// age range in completed years, inclusive; pets without a date of birth
+	// don't match an age range
+	MinAge *int
+	MaxAge *int
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// ImportReport describes the outcome of a bulk pet import.
+type ImportReport struct {
+
+	// nothing was stored
+	DryRun bool `json:"dry_run" xml:"dry_run"`
+
+	// rows read from the body
+	Total int `json:"total" xml:"total"`
+
+	// rows stored, or rows that would be stored on a dry run
+	Created int `json:"created" xml:"created"`
+
+	// rows rejected
+	Failed int `json:"failed" xml:"failed"`
+
+	// one entry per row, in input order
+	Rows []ImportRow `json:"rows" xml:"rows"`
+
+	// why the import stopped before the end of the body; rows past the last
+	// one reported were not read
+	Error string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+type ImportRow struct {
+
+	// 1-based row number, not counting the csv header
+	Row int `json:"row" xml:"row"`
+
+	// id of the created pet
+	ID int64 `json:"id,omitempty" xml:"id,omitempty"`
+
+	// problems with the row keyed by field
+	Errors map[string]string `json:"errors,omitempty" xml:"errors,omitempty"`
+}
+
+// MarshalXML writes Errors as one element per field, which encoding/xml can't
+// do for a map.
+func (r ImportRow) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Row, xml.StartElement{Name: xml.Name{Local: "row"}}); err != nil {
+		return err
+	}
+	if r.ID != 0 {
+		if err := e.EncodeElement(r.ID, xml.StartElement{Name: xml.Name{Local: "id"}}); err != nil {
+			return err
+		}
+	}
+	if len(r.Errors) > 0 {
+		fields := make([]string, 0, len(r.Errors))
+		for field := range r.Errors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		errors := xml.StartElement{Name: xml.Name{Local: "errors"}}
+		if err := e.EncodeToken(errors); err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if err := e.EncodeElement(r.Errors[field], xml.StartElement{Name: xml.Name{Local: field}}); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(errors.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
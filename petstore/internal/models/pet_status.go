@@ -0,0 +1,18 @@
+package models
+
+const (
+	PetStatusAvailable = "available"
+	PetStatusPending   = "pending"
+	PetStatusSold      = "sold"
+)
+
+// PetStatuses lists every status a pet can be in.
+var PetStatuses = []string{PetStatusAvailable, PetStatusPending, PetStatusSold}
+
+const (
+	PetSexMale   = "male"
+	PetSexFemale = "female"
+)
+
+// PetSexes lists the values of Pet.Sex besides the empty, unknown one.
+var PetSexes = []string{PetSexMale, PetSexFemale}
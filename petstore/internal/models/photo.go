@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type Photo struct {
+
+	// url of the original upload
+	URL string `json:"url" xml:"url"`
+
+	// resized copies, smallest first
+	Variants []PhotoVariant `json:"variants" xml:"variants"`
+}
+
+type PhotoVariant struct {
+
+	// longest side the variant was fitted into
+	Size int `json:"size" xml:"size"`
+
+	// width
+	Width int `json:"width" xml:"width"`
+
+	// height
+	Height int `json:"height" xml:"height"`
+
+	// url
+	URL string `json:"url" xml:"url"`
+}
+
+// Photos is stored as a single jsonb column next to the pet. Value returns a
+// string because lib/pq would send []byte as bytea.
+type Photos []Photo
+
+func (p Photos) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(p)
+	return string(b), err
+}
+
+func (p *Photos) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil:
+		*p = nil
+		return nil
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
@@ -8,14 +8,15 @@ import (
 )
 
 type User struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  Password  `json:"-"`
-	Activated bool      `json:"activated"`
-	Deleted   bool      `json:"deleted"`
-	Version   int       `json:"-"`
+	ID        int64     `json:"id" xml:"id"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	Name      string    `json:"name" xml:"name"`
+	Email     string    `json:"email" xml:"email"`
+	Password  Password  `json:"-" xml:"-"`
+	Activated bool      `json:"activated" xml:"activated"`
+	Deleted   bool      `json:"deleted" xml:"deleted"`
+	Role      string    `json:"role" xml:"role"`
+	Version   int       `json:"-" xml:"-"`
 }
 
 type Password struct {
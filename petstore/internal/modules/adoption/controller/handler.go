@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/adoption/service"
+
+	"github.com/go-chi/chi"
+)
+
+// r.Post("/adoption", ctrl.AdoptionHandler.Submit)
+// r.Get("/adoption", ctrl.AdoptionHandler.List)
+// r.Get("/adoption/{adoptionID}", ctrl.AdoptionHandler.GetByID)
+// r.Post("/adoption/{adoptionID}/approve", ctrl.AdoptionHandler.Approve)
+// r.Post("/adoption/{adoptionID}/reject", ctrl.AdoptionHandler.Reject)
+
+type IAdoptionController interface {
+	Submit(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+	GetByID(w http.ResponseWriter, r *http.Request)
+	Approve(w http.ResponseWriter, r *http.Request)
+	Reject(w http.ResponseWriter, r *http.Request)
+}
+
+type AdoptionController struct {
+	responder responder.Responder
+	service   service.IAdoptionService
+}
+
+func NewAdoptionController(responder responder.Responder, service service.IAdoptionService) *AdoptionController {
+	return &AdoptionController{
+		responder: responder,
+		service:   service,
+	}
+}
+
+// Submit serves POST /adoption with a body of {"petId": 1, "message": "..."}.
+func (a *AdoptionController) Submit(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PetID   int64  `json:"petId"`
+		Message string `json:"message"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	adoption := &models.Adoption{PetID: input.PetID, Message: input.Message}
+	err = a.service.Submit(helpers.ActorFromContext(r.Context()), adoption)
+	if err != nil {
+		a.error(w, err)
+		return
+	}
+
+	a.responder.OutputJSON(w, adoption)
+}
+
+var adoptionSortSafelist = []string{"id", "created_at", "expires_at", "status", "-id", "-created_at", "-expires_at", "-status"}
+
+// List serves GET /adoption. Besides page, page_size and sort it filters on
+// pet_id, applicant and a comma-separated list of statuses. Customers only
+// get their own applications whatever they ask for.
+func (a *AdoptionController) List(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	f := models.AdoptionFilter{
+		PetID:     int64(helpers.ReadInt(qs, "pet_id", 0, v)),
+		Applicant: helpers.ReadString(qs, "applicant", ""),
+		Statuses:  helpers.ReadCSV(qs, "status", nil),
+	}
+	for _, status := range f.Statuses {
+		v.Check(validator.PermittedValue(status, models.AdoptionStatuses...), "status", "invalid status value")
+	}
+
+	page := filters.Filters{
+		Page:         helpers.ReadInt(qs, "page", 1, v),
+		PageSize:     helpers.ReadInt(qs, "page_size", 20, v),
+		Sort:         helpers.ReadString(qs, "sort", "-created_at"),
+		SortSafelist: adoptionSortSafelist,
+	}
+	if filters.ValidateFilters(v, page); !v.Valid() {
+		a.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	adoptions, metadata, err := a.service.List(helpers.ActorFromContext(r.Context()), f, page)
+	if err != nil {
+		a.responder.ErrorInternal(w, err)
+		return
+	}
+	a.responder.OutputJSON(w, map[string]interface{}{"metadata": metadata, "data": adoptions})
+}
+
+func (a *AdoptionController) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "adoptionID"))
+	if err != nil {
+		a.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	adoption, err := a.service.GetByID(helpers.ActorFromContext(r.Context()), int64(id))
+	if err != nil {
+		a.error(w, err)
+		return
+	}
+	a.responder.OutputJSON(w, adoption)
+}
+
+// Approve serves POST /adoption/{adoptionID}/approve for staff.
+func (a *AdoptionController) Approve(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "adoptionID"))
+	if err != nil {
+		a.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	adoption, err := a.service.Approve(helpers.ActorFromContext(r.Context()), int64(id))
+	if err != nil {
+		a.error(w, err)
+		return
+	}
+	a.responder.OutputJSON(w, adoption)
+}
+
+// Reject serves POST /adoption/{adoptionID}/reject for staff, with a body of
+// {"reason": "..."}.
+func (a *AdoptionController) Reject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "adoptionID"))
+	if err != nil {
+		a.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	adoption, err := a.service.Reject(helpers.ActorFromContext(r.Context()), int64(id), input.Reason)
+	if err != nil {
+		a.error(w, err)
+		return
+	}
+	a.responder.OutputJSON(w, adoption)
+}
+
+func (a *AdoptionController) error(w http.ResponseWriter, err error) {
+	var validationErr *validator.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		a.responder.ErrorValidation(w, validationErr.Errors)
+	case errors.Is(err, service.ErrRecordNotFound), errors.Is(err, service.ErrPetNotFound):
+		a.responder.ErrorNotFound(w, err)
+	case errors.Is(err, service.ErrForbidden):
+		a.responder.ErrorForbidden(w, err)
+	case errors.Is(err, service.ErrPetUnavailable), errors.Is(err, service.ErrNotPending), errors.Is(err, service.ErrEditConflict):
+		a.responder.ErrorConflict(w, err)
+	default:
+		a.responder.ErrorInternal(w, err)
+	}
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/adoption/service"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+type MockService struct {
+	Submit_mock      func(actor models.Actor, adoption *models.Adoption) error
+	GetByID_mock     func(actor models.Actor, id int64) (*models.Adoption, error)
+	List_mock        func(actor models.Actor, f models.AdoptionFilter, filters filters.Filters) ([]models.Adoption, filters.Metadata, error)
+	Approve_mock     func(actor models.Actor, id int64) (*models.Adoption, error)
+	Reject_mock      func(actor models.Actor, id int64, reason string) (*models.Adoption, error)
+	ExpireStale_mock func(now time.Time) (int, error)
+}
+
+func (m *MockService) Submit(actor models.Actor, adoption *models.Adoption) error {
+	return m.Submit_mock(actor, adoption)
+}
+func (m *MockService) GetByID(actor models.Actor, id int64) (*models.Adoption, error) {
+	return m.GetByID_mock(actor, id)
+}
+func (m *MockService) List(actor models.Actor, f models.AdoptionFilter, filters filters.Filters) ([]models.Adoption, filters.Metadata, error) {
+	return m.List_mock(actor, f, filters)
+}
+func (m *MockService) Approve(actor models.Actor, id int64) (*models.Adoption, error) {
+	return m.Approve_mock(actor, id)
+}
+func (m *MockService) Reject(actor models.Actor, id int64, reason string) (*models.Adoption, error) {
+	return m.Reject_mock(actor, id, reason)
+}
+func (m *MockService) ExpireStale(now time.Time) (int, error) {
+	return m.ExpireStale_mock(now)
+}
+
+func newController(mock *MockService) *AdoptionController {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		DisallowUnknownFields:  true,
+	})
+	return NewAdoptionController(responder.NewResponder(decoder, logger), mock)
+}
+
+func asUser(req *http.Request, username, role string) *http.Request {
+	token, err := helpers.TokenAuth.Decode(helpers.GenerateToken(username, role))
+	return req.WithContext(jwtauth.NewContext(req.Context(), token, err))
+}
+
+func withID(req *http.Request, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("adoptionID", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestSubmitHandler(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		var got models.Actor
+		mock := &MockService{
+			Submit_mock: func(actor models.Actor, adoption *models.Adoption) error {
+				got = actor
+				if adoption.PetID != 1 || adoption.Message != "we have a garden" {
+					t.Errorf("unexpected adoption %+v", adoption)
+				}
+				adoption.ID = 5
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest("POST", "/adoption", bytes.NewReader([]byte(`{"petId": 1, "message": "we have a garden"}`)))
+		w := httptest.NewRecorder()
+		newController(mock).Submit(w, asUser(req, "alice", models.RoleUser))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if got.Username != "alice" {
+			t.Errorf("expected the actor to be alice, got %+v", got)
+		}
+	})
+
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"bad body", `{"petId": `, nil, http.StatusBadRequest},
+		{"validation", `{}`, &validator.ValidationError{Errors: map[string]string{"petId": "must be provided"}}, http.StatusUnprocessableEntity},
+		{"no such pet", `{"petId": 9}`, service.ErrPetNotFound, http.StatusNotFound},
+		{"pet held", `{"petId": 1}`, service.ErrPetUnavailable, http.StatusConflict},
+		{"internal error", `{"petId": 1}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockService{
+				Submit_mock: func(actor models.Actor, adoption *models.Adoption) error { return tt.err },
+			}
+
+			req := httptest.NewRequest("POST", "/adoption", bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+			newController(mock).Submit(w, asUser(req, "alice", models.RoleUser))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		mock := &MockService{
+			List_mock: func(actor models.Actor, f models.AdoptionFilter, page filters.Filters) ([]models.Adoption, filters.Metadata, error) {
+				if f.PetID != 3 || len(f.Statuses) != 2 || page.Sort != "-created_at" {
+					t.Errorf("unexpected filter %+v, %+v", f, page)
+				}
+				return []models.Adoption{{ID: 1}}, filters.Metadata{}, nil
+			},
+		}
+
+		req := httptest.NewRequest("GET", "/adoption?pet_id=3&status=pending,approved", nil)
+		w := httptest.NewRecorder()
+		newController(mock).List(w, asUser(req, "clerk", models.RoleStaff))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/adoption?status=lost", nil)
+		w := httptest.NewRecorder()
+		newController(&MockService{}).List(w, asUser(req, "clerk", models.RoleStaff))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestDecisionHandlers(t *testing.T) {
+	t.Run("approve", func(t *testing.T) {
+		mock := &MockService{
+			Approve_mock: func(actor models.Actor, id int64) (*models.Adoption, error) {
+				if id != 5 || !actor.IsStaff() {
+					t.Errorf("unexpected approval of %d by %+v", id, actor)
+				}
+				return &models.Adoption{ID: id, Status: models.AdoptionApproved}, nil
+			},
+		}
+
+		req := httptest.NewRequest("POST", "/adoption/5/approve", nil)
+		w := httptest.NewRecorder()
+		newController(mock).Approve(w, withID(asUser(req, "clerk", models.RoleStaff), "5"))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		mock := &MockService{
+			Reject_mock: func(actor models.Actor, id int64, reason string) (*models.Adoption, error) {
+				if reason != "no garden" {
+					t.Errorf("unexpected reason %q", reason)
+				}
+				return &models.Adoption{ID: id, Status: models.AdoptionRejected}, nil
+			},
+		}
+
+		req := httptest.NewRequest("POST", "/adoption/5/reject", bytes.NewReader([]byte(`{"reason": "no garden"}`)))
+		w := httptest.NewRecorder()
+		newController(mock).Reject(w, withID(asUser(req, "clerk", models.RoleStaff), "5"))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"customer", service.ErrForbidden, http.StatusForbidden},
+		{"missing", service.ErrRecordNotFound, http.StatusNotFound},
+		{"decided", service.ErrNotPending, http.StatusConflict},
+		{"conflict", service.ErrEditConflict, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockService{
+				Approve_mock: func(actor models.Actor, id int64) (*models.Adoption, error) { return nil, tt.err },
+			}
+
+			req := httptest.NewRequest("POST", "/adoption/5/approve", nil)
+			w := httptest.NewRecorder()
+			newController(mock).Approve(w, withID(asUser(req, "alice", models.RoleUser), "5"))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+
+	t.Run("bad id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/adoption/x", nil)
+		w := httptest.NewRecorder()
+		newController(&MockService{}).GetByID(w, withID(asUser(req, "alice", models.RoleUser), "x"))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
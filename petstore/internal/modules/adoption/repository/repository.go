@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+type AdoptionStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+}
+
+func NewAdoptionStorage(db *sqlx.DB, logger *zap.Logger) IAdoptionStorage {
+	return &AdoptionStorage{
+		logger: logger,
+		DB:     db}
+}
+
+const selectAdoptions = `
+	SELECT id, pet_id, applicant, status, message, reason, decided_by, order_id,
+		created_at, decided_at, expires_at, version
+FROM adoptions
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAdoption(row rowScanner) (models.Adoption, error) {
+	var (
+		adoption models.Adoption
+		orderID  sql.NullInt64
+	)
+
+	err := row.Scan(
+		&adoption.ID,
+		&adoption.PetID,
+		&adoption.Applicant,
+		&adoption.Status,
+		&adoption.Message,
+		&adoption.Reason,
+		&adoption.DecidedBy,
+		&orderID,
+		&adoption.CreatedAt,
+		&adoption.DecidedAt,
+		&adoption.ExpiresAt,
+		&adoption.Version,
+	)
+	if orderID.Valid {
+		adoption.OrderID = &orderID.Int64
+	}
+
+	return adoption, err
+}
+
+// Create stores a new application. A pet has at most one pending
+// application; a second one fails with ErrDuplicatePending.
+func (as *AdoptionStorage) Create(adoption *models.Adoption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := as.DB.Rebind(`INSERT INTO adoptions (pet_id, applicant, status, message, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, version`)
+
+	args := []any{
+		adoption.PetID,
+		adoption.Applicant,
+		adoption.Status,
+		adoption.Message,
+		adoption.CreatedAt,
+		adoption.ExpiresAt,
+	}
+
+	err := as.DB.QueryRowContext(ctx, query, args...).Scan(&adoption.ID, &adoption.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicatePending
+		default:
+			as.logger.Error("error on inserting adoption", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (as *AdoptionStorage) GetByID(id int64) (*models.Adoption, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := as.DB.Rebind(selectAdoptions + `WHERE id = ?`)
+
+	adoption, err := scanAdoption(as.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			as.logger.Error("error on getting adoption", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return &adoption, nil
+}
+
+// Update writes the decision on an application if it is still at
+// adoption.Version, and bumps adoption.Version on success.
+func (as *AdoptionStorage) Update(adoption *models.Adoption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := as.DB.Rebind(`UPDATE adoptions
+		SET status = ?, reason = ?, decided_by = ?, order_id = ?, decided_at = ?, version = version + 1
+		WHERE id = ? AND version = ?
+		RETURNING version`)
+
+	args := []any{
+		adoption.Status,
+		adoption.Reason,
+		adoption.DecidedBy,
+		adoption.OrderID,
+		adoption.DecidedAt,
+		adoption.ID,
+		adoption.Version,
+	}
+
+	err := as.DB.QueryRowContext(ctx, query, args...).Scan(&adoption.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicatePending
+		default:
+			as.logger.Error("error on updating adoption", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (as *AdoptionStorage) GetAll(f models.AdoptionFilter, filters filter.Filters) ([]models.Adoption, filter.Metadata, error) {
+	where, args, err := adoptionFilterClause(f)
+	if err != nil {
+		as.logger.Error("error on building adoption filter", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	totalRecords := 0
+	err = as.DB.QueryRowContext(ctx, as.DB.Rebind(`SELECT count(*) FROM adoptions `+where), args...).Scan(&totalRecords)
+	if err != nil {
+		as.logger.Error("error on counting adoptions", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	query := as.DB.Rebind(fmt.Sprintf(`%s%s
+ORDER BY %s %s, id ASC
+LIMIT ? OFFSET ?`, selectAdoptions, where, filters.SortColumn(), filters.SortDirection()))
+
+	adoptions, err := as.query(ctx, query, append(args, filters.Limit(), filters.Offset())...)
+	if err != nil {
+		as.logger.Error("error on listing adoptions", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	return adoptions, filter.CalculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (as *AdoptionStorage) GetExpired(now time.Time) ([]models.Adoption, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := as.DB.Rebind(selectAdoptions + `WHERE status = ? AND expires_at <= ?
+ORDER BY expires_at, id`)
+
+	adoptions, err := as.query(ctx, query, models.AdoptionPending, now)
+	if err != nil {
+		as.logger.Error("error on getting expired adoptions", zap.Error(err))
+		return nil, err
+	}
+	return adoptions, nil
+}
+
+func (as *AdoptionStorage) query(ctx context.Context, query string, args ...any) ([]models.Adoption, error) {
+	rows, err := as.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adoptions := []models.Adoption{}
+	for rows.Next() {
+		adoption, err := scanAdoption(rows)
+		if err != nil {
+			return nil, err
+		}
+		adoptions = append(adoptions, adoption)
+	}
+	return adoptions, rows.Err()
+}
+
+// adoptionFilterClause turns f into a WHERE clause with ? placeholders.
+func adoptionFilterClause(f models.AdoptionFilter) (string, []any, error) {
+	conditions := []string{"1 = 1"}
+	args := []any{}
+
+	if f.PetID != 0 {
+		conditions = append(conditions, `pet_id = ?`)
+		args = append(args, f.PetID)
+	}
+
+	if f.Applicant != "" {
+		conditions = append(conditions, `applicant = ?`)
+		args = append(args, f.Applicant)
+	}
+
+	if len(f.Statuses) > 0 {
+		clause, statusArgs, err := sqlx.In(`status IN (?)`, f.Statuses)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, clause)
+		args = append(args, statusArgs...)
+	}
+
+	return "WHERE " + strings.Join(conditions, "\nAND "), args, nil
+}
+
+// isUniqueViolation recognizes unique index violations of postgres and of
+// sqlite, which the tests run on.
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "duplicate key value violates unique constraint") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
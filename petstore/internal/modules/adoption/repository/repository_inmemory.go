@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+// AdoptionStorage_map keeps applications in memory for the memory backend.
+// Like PetStorage_map it stores and hands out copies.
+type AdoptionStorage_map struct {
+	mu        sync.RWMutex
+	adoptions map[int64]*models.Adoption
+	lastID    int64
+}
+
+func NewAdoptionStorage_map() *AdoptionStorage_map {
+	return &AdoptionStorage_map{adoptions: make(map[int64]*models.Adoption)}
+}
+
+func (as *AdoptionStorage_map) Create(adoption *models.Adoption) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if adoption.Status == models.AdoptionPending && as.pendingFor(adoption.PetID, 0) {
+		return ErrDuplicatePending
+	}
+
+	as.lastID++
+	adoption.ID = as.lastID
+	adoption.Version = 1
+	as.adoptions[adoption.ID] = cloneAdoption(adoption)
+	return nil
+}
+
+func (as *AdoptionStorage_map) GetByID(id int64) (*models.Adoption, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	stored, ok := as.adoptions[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return cloneAdoption(stored), nil
+}
+
+func (as *AdoptionStorage_map) Update(adoption *models.Adoption) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	stored, ok := as.adoptions[adoption.ID]
+	if !ok || stored.Version != adoption.Version {
+		return ErrEditConflict
+	}
+	if adoption.Status == models.AdoptionPending && as.pendingFor(stored.PetID, stored.ID) {
+		return ErrDuplicatePending
+	}
+
+	updated := cloneAdoption(stored)
+	updated.Status = adoption.Status
+	updated.Reason = adoption.Reason
+	updated.DecidedBy = adoption.DecidedBy
+	updated.OrderID = cloneInt64(adoption.OrderID)
+	updated.DecidedAt = cloneTime(adoption.DecidedAt)
+	updated.Version++
+	as.adoptions[updated.ID] = updated
+
+	adoption.Version = updated.Version
+	return nil
+}
+
+func (as *AdoptionStorage_map) GetAll(f models.AdoptionFilter, filters filter.Filters) ([]models.Adoption, filter.Metadata, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	matching := []models.Adoption{}
+	for _, adoption := range as.adoptions {
+		if matchesAdoptionFilter(adoption, f) {
+			matching = append(matching, *cloneAdoption(adoption))
+		}
+	}
+
+	desc := filters.SortDirection() == "DESC"
+	column := filters.SortColumn()
+	sort.Slice(matching, func(i, j int) bool {
+		a, b := matching[i], matching[j]
+		var less, equal bool
+		switch column {
+		case "created_at":
+			less, equal = a.CreatedAt.Before(b.CreatedAt), a.CreatedAt.Equal(b.CreatedAt)
+		case "expires_at":
+			less, equal = a.ExpiresAt.Before(b.ExpiresAt), a.ExpiresAt.Equal(b.ExpiresAt)
+		case "status":
+			less, equal = a.Status < b.Status, a.Status == b.Status
+		default:
+			less, equal = a.ID < b.ID, a.ID == b.ID
+		}
+		if equal {
+			return a.ID < b.ID
+		}
+		return less != desc
+	})
+
+	metadata := filter.CalculateMetadata(len(matching), filters.Page, filters.PageSize)
+	start := filters.Offset()
+	if start > len(matching) {
+		start = len(matching)
+	}
+	end := start + filters.Limit()
+	if end > len(matching) {
+		end = len(matching)
+	}
+	return matching[start:end], metadata, nil
+}
+
+func (as *AdoptionStorage_map) GetExpired(now time.Time) ([]models.Adoption, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	expired := []models.Adoption{}
+	for _, adoption := range as.adoptions {
+		if adoption.Status == models.AdoptionPending && !adoption.ExpiresAt.After(now) {
+			expired = append(expired, *cloneAdoption(adoption))
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].ExpiresAt.Equal(expired[j].ExpiresAt) {
+			return expired[i].ID < expired[j].ID
+		}
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	return expired, nil
+}
+
+// pendingFor reports whether petID has a pending application other than
+// except.
+func (as *AdoptionStorage_map) pendingFor(petID, except int64) bool {
+	for id, adoption := range as.adoptions {
+		if id != except && adoption.PetID == petID && adoption.Status == models.AdoptionPending {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAdoptionFilter(adoption *models.Adoption, f models.AdoptionFilter) bool {
+	if f.PetID != 0 && adoption.PetID != f.PetID {
+		return false
+	}
+	if f.Applicant != "" && adoption.Applicant != f.Applicant {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		for _, status := range f.Statuses {
+			if adoption.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func cloneAdoption(adoption *models.Adoption) *models.Adoption {
+	clone := *adoption
+	clone.OrderID = cloneInt64(adoption.OrderID)
+	clone.DecidedAt = cloneTime(adoption.DecidedAt)
+	return &clone
+}
+
+func cloneInt64(n *int64) *int64 {
+	if n == nil {
+		return nil
+	}
+	clone := *n
+	return &clone
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+var (
+	ErrRecordNotFound   = errors.New("record not found")
+	ErrEditConflict     = errors.New("edit conflict")
+	ErrDuplicatePending = errors.New("pet already has a pending application")
+)
+
+type IAdoptionStorage interface {
+	Create(adoption *models.Adoption) error
+	GetByID(id int64) (*models.Adoption, error)
+	Update(adoption *models.Adoption) error
+	GetAll(f models.AdoptionFilter, filters filter.Filters) ([]models.Adoption, filter.Metadata, error)
+	// GetExpired returns the pending applications that expired at or before
+	// now, oldest first.
+	GetExpired(now time.Time) ([]models.Adoption, error)
+}
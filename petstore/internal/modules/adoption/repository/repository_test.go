@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"errors"
+	"test/internal/infrastructure/dbtest"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sqliteSchema mirrors migration 000011 without the foreign keys to pets and
+// orders.
+const sqliteSchema = `
+CREATE TABLE adoptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pet_id INTEGER NOT NULL,
+	applicant TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	message TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	decided_by TEXT NOT NULL DEFAULT '',
+	order_id INTEGER,
+	created_at TIMESTAMP NOT NULL,
+	decided_at TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX adoptions_pending_pet_idx ON adoptions (pet_id) WHERE status = 'pending';
+`
+
+func newSQLiteStorage(tb testing.TB) *AdoptionStorage {
+	tb.Helper()
+
+	db := dbtest.OpenSQLite(tb, sqliteSchema)
+	return NewAdoptionStorage(db, zap.NewNop()).(*AdoptionStorage)
+}
+
+var start = time.Date(2024, 10, 12, 12, 0, 0, 0, time.UTC)
+
+func testAdoption(petID int64, applicant string, expiresIn time.Duration) *models.Adoption {
+	return &models.Adoption{
+		PetID:     petID,
+		Applicant: applicant,
+		Status:    models.AdoptionPending,
+		Message:   "we have a garden",
+		CreatedAt: start,
+		ExpiresAt: start.Add(expiresIn),
+	}
+}
+
+func TestAdoptionStorage(t *testing.T) {
+	backends := map[string]func(t *testing.T) IAdoptionStorage{
+		"sql":    func(t *testing.T) IAdoptionStorage { return newSQLiteStorage(t) },
+		"memory": func(t *testing.T) IAdoptionStorage { return NewAdoptionStorage_map() },
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("create and get", func(t *testing.T) {
+				storage := newStorage(t)
+
+				adoption := testAdoption(1, "alice", time.Hour)
+				if err := storage.Create(adoption); err != nil {
+					t.Fatal(err)
+				}
+				if adoption.ID == 0 || adoption.Version != 1 {
+					t.Fatalf("expected id and version 1, got %d and %d", adoption.ID, adoption.Version)
+				}
+
+				got, err := storage.GetByID(adoption.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Applicant != "alice" || got.Status != models.AdoptionPending || got.Message != "we have a garden" {
+					t.Errorf("unexpected adoption %+v", got)
+				}
+				if !got.ExpiresAt.Equal(start.Add(time.Hour)) || got.OrderID != nil || got.DecidedAt != nil {
+					t.Errorf("unexpected adoption %+v", got)
+				}
+
+				if _, err := storage.GetByID(adoption.ID + 1); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+			})
+
+			t.Run("one pending application per pet", func(t *testing.T) {
+				storage := newStorage(t)
+
+				first := testAdoption(1, "alice", time.Hour)
+				if err := storage.Create(first); err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.Create(testAdoption(1, "bob", time.Hour)); !errors.Is(err, ErrDuplicatePending) {
+					t.Fatalf("expected ErrDuplicatePending, got %v", err)
+				}
+				if err := storage.Create(testAdoption(2, "bob", time.Hour)); err != nil {
+					t.Fatalf("another pet: %v", err)
+				}
+
+				first.Status = models.AdoptionRejected
+				first.Reason = "no garden"
+				if err := storage.Update(first); err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.Create(testAdoption(1, "bob", time.Hour)); err != nil {
+					t.Fatalf("after rejection: %v", err)
+				}
+			})
+
+			t.Run("update", func(t *testing.T) {
+				storage := newStorage(t)
+
+				adoption := testAdoption(1, "alice", time.Hour)
+				if err := storage.Create(adoption); err != nil {
+					t.Fatal(err)
+				}
+				stale := *adoption
+
+				orderID := int64(7)
+				decidedAt := start.Add(time.Minute)
+				adoption.Status = models.AdoptionApproved
+				adoption.DecidedBy = "clerk"
+				adoption.OrderID = &orderID
+				adoption.DecidedAt = &decidedAt
+				if err := storage.Update(adoption); err != nil {
+					t.Fatal(err)
+				}
+				if adoption.Version != 2 {
+					t.Errorf("expected version 2, got %d", adoption.Version)
+				}
+
+				got, err := storage.GetByID(adoption.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != models.AdoptionApproved || got.DecidedBy != "clerk" {
+					t.Errorf("unexpected adoption %+v", got)
+				}
+				if got.OrderID == nil || *got.OrderID != 7 || got.DecidedAt == nil || !got.DecidedAt.Equal(decidedAt) {
+					t.Errorf("unexpected adoption %+v", got)
+				}
+
+				stale.Status = models.AdoptionRejected
+				if err := storage.Update(&stale); !errors.Is(err, ErrEditConflict) {
+					t.Errorf("expected ErrEditConflict, got %v", err)
+				}
+			})
+
+			t.Run("get all", func(t *testing.T) {
+				storage := newStorage(t)
+
+				for i, applicant := range []string{"alice", "bob", "alice"} {
+					adoption := testAdoption(int64(i+1), applicant, time.Hour)
+					adoption.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+					if err := storage.Create(adoption); err != nil {
+						t.Fatal(err)
+					}
+				}
+				rejected, _ := storage.GetByID(2)
+				rejected.Status = models.AdoptionRejected
+				if err := storage.Update(rejected); err != nil {
+					t.Fatal(err)
+				}
+
+				page := filter.Filters{Page: 1, PageSize: 20, Sort: "-created_at", SortSafelist: []string{"-created_at"}}
+
+				got, metadata, err := storage.GetAll(models.AdoptionFilter{Applicant: "alice"}, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 2 || got[0].ID != 3 || got[1].ID != 1 || metadata.TotalRecords != 2 {
+					t.Errorf("unexpected applications %+v, %+v", got, metadata)
+				}
+
+				got, _, err = storage.GetAll(models.AdoptionFilter{Statuses: []string{models.AdoptionRejected}}, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 || got[0].ID != 2 {
+					t.Errorf("unexpected applications %+v", got)
+				}
+
+				got, _, err = storage.GetAll(models.AdoptionFilter{PetID: 3}, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 || got[0].ID != 3 {
+					t.Errorf("unexpected applications %+v", got)
+				}
+			})
+
+			t.Run("get expired", func(t *testing.T) {
+				storage := newStorage(t)
+
+				for i, expiresIn := range []time.Duration{2 * time.Hour, time.Hour, 3 * time.Hour} {
+					if err := storage.Create(testAdoption(int64(i+1), "alice", expiresIn)); err != nil {
+						t.Fatal(err)
+					}
+				}
+				decided, _ := storage.GetByID(2)
+				decided.Status = models.AdoptionRejected
+				if err := storage.Update(decided); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := storage.GetExpired(start.Add(2 * time.Hour))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 || got[0].ID != 1 {
+					t.Errorf("unexpected expired applications %+v", got)
+				}
+
+				got, err = storage.GetExpired(start.Add(4 * time.Hour))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 2 || got[0].ID != 1 || got[1].ID != 3 {
+					t.Errorf("unexpected expired applications %+v", got)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/adoption/repository"
+	pet_service "test/internal/modules/pet/service"
+	store_service "test/internal/modules/store/service"
+)
+
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("edit conflict")
+	ErrForbidden      = errors.New("not allowed")
+	ErrPetNotFound    = errors.New("pet not found")
+	ErrPetUnavailable = errors.New("pet is not available for adoption")
+	ErrNotPending     = errors.New("application has already been decided")
+)
+
+const (
+	maxMessageLength = 2000
+	maxReasonLength  = 1000
+)
+
+// systemActor is who expires stale applications in the pet history.
+var systemActor = models.Actor{Username: "system", Role: models.RoleStaff}
+
+// r.Post("/adoption", ctrl.AdoptionHandler.Submit)
+// r.Get("/adoption", ctrl.AdoptionHandler.List)
+// r.Get("/adoption/{adoptionID}", ctrl.AdoptionHandler.GetByID)
+// r.Post("/adoption/{adoptionID}/approve", ctrl.AdoptionHandler.Approve)
+// r.Post("/adoption/{adoptionID}/reject", ctrl.AdoptionHandler.Reject)
+
+type IAdoptionService interface {
+	Submit(actor models.Actor, adoption *models.Adoption) error
+	GetByID(actor models.Actor, id int64) (*models.Adoption, error)
+	List(actor models.Actor, f models.AdoptionFilter, filters filters.Filters) ([]models.Adoption, filters.Metadata, error)
+	Approve(actor models.Actor, id int64) (*models.Adoption, error)
+	Reject(actor models.Actor, id int64, reason string) (*models.Adoption, error)
+	ExpireStale(now time.Time) (int, error)
+}
+
+// Pets is what adoptions need from the pet service.
+type Pets interface {
+	GetByID(id int64) (*models.Pet, error)
+	Transition(actor models.Actor, id int64, from, to string) error
+}
+
+// Orders is what adoptions need from the store service.
+type Orders interface {
+	CreateAdopted(actor models.Actor, order *models.Order) error
+	Delete(id int64) error
+}
+
+type AdoptionService struct {
+	storage repository.IAdoptionStorage
+	pets    Pets
+	orders  Orders
+	ttl     time.Duration
+}
+
+// NewAdoptionService returns a service whose pending applications expire
+// after ttl.
+func NewAdoptionService(repo repository.IAdoptionStorage, pets Pets, orders Orders, ttl time.Duration) *AdoptionService {
+	return &AdoptionService{storage: repo, pets: pets, orders: orders, ttl: ttl}
+}
+
+// Submit files an application by actor for an available pet and moves the
+// pet to pending, so nobody else can apply for it until staff decide.
+func (s *AdoptionService) Submit(actor models.Actor, adoption *models.Adoption) error {
+	v := validator.New()
+	v.Check(adoption.PetID > 0, "petId", "must be provided")
+	v.Check(utf8.RuneCountInString(adoption.Message) <= maxMessageLength, "message", fmt.Sprintf("must not be more than %d characters long", maxMessageLength))
+	if !v.Valid() {
+		return &validator.ValidationError{Errors: v.Errors}
+	}
+
+	pet, err := s.pets.GetByID(adoption.PetID)
+	if err != nil {
+		return ErrPetNotFound
+	}
+	if pet.Status != models.PetStatusAvailable {
+		return ErrPetUnavailable
+	}
+
+	err = s.pets.Transition(actor, pet.ID, models.PetStatusAvailable, models.PetStatusPending)
+	if err != nil {
+		return unavailable(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	adoption.Applicant = actor.Username
+	adoption.Status = models.AdoptionPending
+	adoption.Reason, adoption.DecidedBy = "", ""
+	adoption.OrderID, adoption.DecidedAt = nil, nil
+	adoption.CreatedAt = now
+	adoption.ExpiresAt = now.Add(s.ttl)
+
+	err = s.storage.Create(adoption)
+	if err != nil {
+		undoErr := s.pets.Transition(actor, pet.ID, models.PetStatusPending, models.PetStatusAvailable)
+		if errors.Is(err, repository.ErrDuplicatePending) {
+			err = ErrPetUnavailable
+		}
+		return undone(err, undoErr)
+	}
+	return nil
+}
+
+// GetByID returns an application to its applicant or to staff.
+func (s *AdoptionService) GetByID(actor models.Actor, id int64) (*models.Adoption, error) {
+	adoption, err := s.storage.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if !actor.IsStaff() && adoption.Applicant != actor.Username {
+		return nil, ErrForbidden
+	}
+	return adoption, nil
+}
+
+// List returns applications matching f. Customers only ever see their own.
+func (s *AdoptionService) List(actor models.Actor, f models.AdoptionFilter, filters filters.Filters) ([]models.Adoption, filters.Metadata, error) {
+	if !actor.IsStaff() {
+		f.Applicant = actor.Username
+	}
+	return s.storage.GetAll(f, filters)
+}
+
+// Approve sells the pet to the applicant: it places a store order for the
+// pet, marks the application approved and the pet sold. When a step fails
+// the earlier ones are undone; if that fails too, the returned error says so.
+func (s *AdoptionService) Approve(actor models.Actor, id int64) (*models.Adoption, error) {
+	adoption, err := s.pending(actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	order := &models.Order{
+		PetID:    adoption.PetID,
+		Quantity: 1,
+		ShipDate: now,
+		Status:   "approved",
+	}
+	err = s.orders.CreateAdopted(actor, order)
+	if err != nil {
+		if errors.Is(err, store_service.ErrPetHeld) || errors.Is(err, store_service.ErrPetUnavailable) || errors.Is(err, store_service.ErrDuplicateRecord) {
+			return nil, ErrPetUnavailable
+		}
+		return nil, fmt.Errorf("placing order: %w", err)
+	}
+
+	decided := *adoption
+	decided.Status = models.AdoptionApproved
+	decided.DecidedBy = actor.Username
+	decided.DecidedAt = &now
+	decided.OrderID = &order.ID
+	err = s.storage.Update(&decided)
+	if err != nil {
+		return nil, undone(conflict(err), s.orders.Delete(order.ID))
+	}
+
+	err = s.pets.Transition(actor, adoption.PetID, models.PetStatusPending, models.PetStatusSold)
+	if err != nil {
+		orderErr := s.orders.Delete(order.ID)
+		adoption.Version = decided.Version
+		adoptionErr := s.storage.Update(adoption)
+		return nil, undone(unavailable(err), orderErr, adoptionErr)
+	}
+
+	return &decided, nil
+}
+
+// Reject turns an application down for reason and makes the pet available
+// again.
+func (s *AdoptionService) Reject(actor models.Actor, id int64, reason string) (*models.Adoption, error) {
+	reason = strings.TrimSpace(reason)
+	v := validator.New()
+	v.Check(reason != "", "reason", "must be provided")
+	v.Check(utf8.RuneCountInString(reason) <= maxReasonLength, "reason", fmt.Sprintf("must not be more than %d characters long", maxReasonLength))
+	if !v.Valid() {
+		return nil, &validator.ValidationError{Errors: v.Errors}
+	}
+
+	adoption, err := s.pending(actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	adoption.Status = models.AdoptionRejected
+	adoption.Reason = reason
+	adoption.DecidedBy = actor.Username
+	adoption.DecidedAt = &now
+	err = s.storage.Update(adoption)
+	if err != nil {
+		return nil, conflict(err)
+	}
+
+	err = s.release(actor, adoption.PetID)
+	if err != nil {
+		return nil, err
+	}
+	return adoption, nil
+}
+
+// ExpireStale expires the applications still pending at their expiry time
+// and releases their pets. It returns how many it expired; applications
+// decided in the meantime are skipped.
+func (s *AdoptionService) ExpireStale(now time.Time) (int, error) {
+	stale, err := s.storage.GetExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	decidedAt := now.UTC().Truncate(time.Second)
+	for i := range stale {
+		adoption := &stale[i]
+		adoption.Status = models.AdoptionExpired
+		adoption.DecidedAt = &decidedAt
+		err = s.storage.Update(adoption)
+		if errors.Is(err, repository.ErrEditConflict) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+
+		err = s.release(systemActor, adoption.PetID)
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+// pending loads an application for a staff decision.
+func (s *AdoptionService) pending(actor models.Actor, id int64) (*models.Adoption, error) {
+	if !actor.IsStaff() {
+		return nil, ErrForbidden
+	}
+
+	adoption, err := s.storage.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if adoption.Status != models.AdoptionPending {
+		return nil, ErrNotPending
+	}
+	return adoption, nil
+}
+
+// release makes a pet held by an application available again. Pets that
+// have been deleted or moved on by staff in the meantime are left alone.
+func (s *AdoptionService) release(actor models.Actor, petID int64) error {
+	err := s.pets.Transition(actor, petID, models.PetStatusPending, models.PetStatusAvailable)
+	if err != nil && !errors.Is(err, pet_service.ErrUnexpectedStatus) && !errors.Is(err, pet_service.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// unavailable reports a pet that changed under an adoption as unavailable.
+func unavailable(err error) error {
+	switch {
+	case errors.Is(err, pet_service.ErrUnexpectedStatus), errors.Is(err, pet_service.ErrEditConflict), errors.Is(err, pet_service.ErrRecordNotFound):
+		return ErrPetUnavailable
+	default:
+		return err
+	}
+}
+
+// undone returns err, the reason a step failed, together with whatever
+// failed while undoing the steps before it.
+func undone(err error, undoErrs ...error) error {
+	failed := []string{}
+	for _, undoErr := range undoErrs {
+		if undoErr != nil {
+			failed = append(failed, undoErr.Error())
+		}
+	}
+	if len(failed) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (undoing: %s)", err, strings.Join(failed, "; "))
+}
+
+func conflict(err error) error {
+	if errors.Is(err, repository.ErrEditConflict) {
+		return ErrEditConflict
+	}
+	return err
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/adoption/repository"
+	pet_service "test/internal/modules/pet/service"
+	"testing"
+	"time"
+)
+
+// fakePets keeps pet statuses and applies transitions like the pet service.
+type fakePets struct {
+	statuses      map[int64]string
+	transitionErr error
+}
+
+func (f *fakePets) GetByID(id int64) (*models.Pet, error) {
+	status, ok := f.statuses[id]
+	if !ok {
+		return nil, pet_service.ErrRecordNotFound
+	}
+	return &models.Pet{ID: id, Status: status}, nil
+}
+
+func (f *fakePets) Transition(actor models.Actor, id int64, from, to string) error {
+	if f.transitionErr != nil && to == models.PetStatusSold {
+		return f.transitionErr
+	}
+	status, ok := f.statuses[id]
+	if !ok {
+		return pet_service.ErrRecordNotFound
+	}
+	if status != from {
+		return pet_service.ErrUnexpectedStatus
+	}
+	f.statuses[id] = to
+	return nil
+}
+
+type fakeOrders struct {
+	orders    map[int64]*models.Order
+	lastID    int64
+	createErr error
+	deleteErr error
+}
+
+func (f *fakeOrders) CreateAdopted(actor models.Actor, order *models.Order) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.lastID++
+	order.ID = f.lastID
+	f.orders[order.ID] = order
+	return nil
+}
+
+func (f *fakeOrders) Delete(id int64) error {
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	delete(f.orders, id)
+	return nil
+}
+
+var (
+	alice = models.Actor{Username: "alice", Role: models.RoleUser}
+	bob   = models.Actor{Username: "bob", Role: models.RoleUser}
+	clerk = models.Actor{Username: "clerk", Role: models.RoleStaff}
+)
+
+func newTestService() (*AdoptionService, *fakePets, *fakeOrders) {
+	pets := &fakePets{statuses: map[int64]string{
+		1: models.PetStatusAvailable,
+		2: models.PetStatusSold,
+	}}
+	orders := &fakeOrders{orders: map[int64]*models.Order{}}
+	return NewAdoptionService(repository.NewAdoptionStorage_map(), pets, orders, time.Hour), pets, orders
+}
+
+func TestSubmit(t *testing.T) {
+	t.Run("holds the pet", func(t *testing.T) {
+		s, pets, _ := newTestService()
+
+		adoption := &models.Adoption{PetID: 1, Message: "we have a garden"}
+		if err := s.Submit(alice, adoption); err != nil {
+			t.Fatal(err)
+		}
+		if adoption.Applicant != "alice" || adoption.Status != models.AdoptionPending {
+			t.Errorf("unexpected adoption %+v", adoption)
+		}
+		if !adoption.ExpiresAt.Equal(adoption.CreatedAt.Add(time.Hour)) {
+			t.Errorf("expected expiry an hour after %v, got %v", adoption.CreatedAt, adoption.ExpiresAt)
+		}
+		if pets.statuses[1] != models.PetStatusPending {
+			t.Errorf("expected pet to be pending, got %q", pets.statuses[1])
+		}
+
+		err := s.Submit(bob, &models.Adoption{PetID: 1})
+		if !errors.Is(err, ErrPetUnavailable) {
+			t.Errorf("expected ErrPetUnavailable for a held pet, got %v", err)
+		}
+	})
+
+	t.Run("unavailable pets", func(t *testing.T) {
+		s, _, _ := newTestService()
+
+		if err := s.Submit(alice, &models.Adoption{PetID: 2}); !errors.Is(err, ErrPetUnavailable) {
+			t.Errorf("expected ErrPetUnavailable, got %v", err)
+		}
+		if err := s.Submit(alice, &models.Adoption{PetID: 3}); !errors.Is(err, ErrPetNotFound) {
+			t.Errorf("expected ErrPetNotFound, got %v", err)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		s, _, _ := newTestService()
+
+		var validationErr *validator.ValidationError
+		err := s.Submit(alice, &models.Adoption{})
+		if !errors.As(err, &validationErr) || validationErr.Errors["petId"] == "" {
+			t.Errorf("expected a petId error, got %v", err)
+		}
+	})
+}
+
+func TestGetAndList(t *testing.T) {
+	s, _, _ := newTestService()
+
+	adoption := &models.Adoption{PetID: 1}
+	if err := s.Submit(alice, adoption); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetByID(alice, adoption.ID); err != nil {
+		t.Errorf("applicant: %v", err)
+	}
+	if _, err := s.GetByID(clerk, adoption.ID); err != nil {
+		t.Errorf("staff: %v", err)
+	}
+	if _, err := s.GetByID(bob, adoption.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+
+	page := pageOf("-created_at")
+	got, _, err := s.List(bob, models.AdoptionFilter{Applicant: "alice"}, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected customers to only see their own applications, got %+v", got)
+	}
+
+	got, _, err = s.List(clerk, models.AdoptionFilter{Applicant: "alice"}, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("expected staff to see alice's application, got %+v", got)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	t.Run("sells the pet", func(t *testing.T) {
+		s, pets, orders := newTestService()
+
+		adoption := &models.Adoption{PetID: 1}
+		if err := s.Submit(alice, adoption); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Approve(alice, adoption.ID); !errors.Is(err, ErrForbidden) {
+			t.Errorf("expected ErrForbidden for a customer, got %v", err)
+		}
+
+		approved, err := s.Approve(clerk, adoption.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if approved.Status != models.AdoptionApproved || approved.DecidedBy != "clerk" || approved.DecidedAt == nil {
+			t.Errorf("unexpected adoption %+v", approved)
+		}
+		if approved.OrderID == nil || orders.orders[*approved.OrderID] == nil {
+			t.Fatalf("expected an order, got %+v", approved)
+		}
+		if order := orders.orders[*approved.OrderID]; order.PetID != 1 || order.Quantity != 1 {
+			t.Errorf("unexpected order %+v", order)
+		}
+		if pets.statuses[1] != models.PetStatusSold {
+			t.Errorf("expected pet to be sold, got %q", pets.statuses[1])
+		}
+
+		if _, err := s.Approve(clerk, adoption.ID); !errors.Is(err, ErrNotPending) {
+			t.Errorf("expected ErrNotPending, got %v", err)
+		}
+		if _, err := s.Approve(clerk, adoption.ID+1); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound, got %v", err)
+		}
+	})
+
+	t.Run("rolls back", func(t *testing.T) {
+		s, pets, orders := newTestService()
+
+		adoption := &models.Adoption{PetID: 1}
+		if err := s.Submit(alice, adoption); err != nil {
+			t.Fatal(err)
+		}
+
+		pets.transitionErr = pet_service.ErrEditConflict
+		if _, err := s.Approve(clerk, adoption.ID); !errors.Is(err, ErrPetUnavailable) {
+			t.Fatalf("expected ErrPetUnavailable, got %v", err)
+		}
+		if len(orders.orders) != 0 {
+			t.Errorf("expected the order to be deleted, got %+v", orders.orders)
+		}
+		got, err := s.GetByID(clerk, adoption.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != models.AdoptionPending || got.OrderID != nil {
+			t.Errorf("expected the application to be pending again, got %+v", got)
+		}
+
+		pets.transitionErr = nil
+		orders.createErr = errors.New("db down")
+		if _, err := s.Approve(clerk, adoption.ID); err == nil {
+			t.Fatal("expected an error")
+		}
+		if got, _ := s.GetByID(clerk, adoption.ID); got.Status != models.AdoptionPending {
+			t.Errorf("expected the application to stay pending, got %+v", got)
+		}
+	})
+
+	t.Run("reports failed rollbacks", func(t *testing.T) {
+		s, pets, orders := newTestService()
+
+		adoption := &models.Adoption{PetID: 1}
+		if err := s.Submit(alice, adoption); err != nil {
+			t.Fatal(err)
+		}
+
+		pets.transitionErr = pet_service.ErrEditConflict
+		orders.deleteErr = errors.New("db down")
+		_, err := s.Approve(clerk, adoption.ID)
+		if !errors.Is(err, ErrPetUnavailable) || !strings.Contains(err.Error(), "db down") {
+			t.Fatalf("expected ErrPetUnavailable along with the failed order delete, got %v", err)
+		}
+	})
+}
+
+func TestReject(t *testing.T) {
+	s, pets, _ := newTestService()
+
+	adoption := &models.Adoption{PetID: 1}
+	if err := s.Submit(alice, adoption); err != nil {
+		t.Fatal(err)
+	}
+
+	var validationErr *validator.ValidationError
+	if _, err := s.Reject(clerk, adoption.ID, "  "); !errors.As(err, &validationErr) || validationErr.Errors["reason"] == "" {
+		t.Errorf("expected a reason error, got %v", err)
+	}
+	if _, err := s.Reject(bob, adoption.ID, "no garden"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+
+	rejected, err := s.Reject(clerk, adoption.ID, "no garden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Status != models.AdoptionRejected || rejected.Reason != "no garden" || rejected.DecidedBy != "clerk" {
+		t.Errorf("unexpected adoption %+v", rejected)
+	}
+	if pets.statuses[1] != models.PetStatusAvailable {
+		t.Errorf("expected pet to be available again, got %q", pets.statuses[1])
+	}
+
+	if err := s.Submit(bob, &models.Adoption{PetID: 1}); err != nil {
+		t.Errorf("expected the pet to accept new applications, got %v", err)
+	}
+}
+
+func TestExpireStale(t *testing.T) {
+	s, pets, _ := newTestService()
+	pets.statuses[3] = models.PetStatusAvailable
+
+	stale := &models.Adoption{PetID: 1}
+	if err := s.Submit(alice, stale); err != nil {
+		t.Fatal(err)
+	}
+	decided := &models.Adoption{PetID: 3}
+	if err := s.Submit(bob, decided); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reject(clerk, decided.ID, "no garden"); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.ExpireStale(time.Now())
+	if err != nil || n != 0 {
+		t.Fatalf("expected nothing to expire yet, got %d, %v", n, err)
+	}
+
+	n, err = s.ExpireStale(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 expired application, got %d", n)
+	}
+	got, _ := s.GetByID(clerk, stale.ID)
+	if got.Status != models.AdoptionExpired || got.DecidedAt == nil {
+		t.Errorf("unexpected adoption %+v", got)
+	}
+	if pets.statuses[1] != models.PetStatusAvailable {
+		t.Errorf("expected pet to be available again, got %q", pets.statuses[1])
+	}
+}
+
+func pageOf(sort string) filters.Filters {
+	return filters.Filters{Page: 1, PageSize: 20, Sort: sort, SortSafelist: []string{sort}}
+}
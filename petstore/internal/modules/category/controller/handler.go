@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test/internal/infrastructure/responder"
+	"test/internal/models"
+	"test/internal/modules/category/service"
+
+	"github.com/go-chi/chi"
+)
+
+// r.Get("/category", ctrl.CategoryHandler.ListCategories)
+// r.Get("/category/{categoryID}", ctrl.CategoryHandler.GetCategoryByID)
+// r.Post("/category", ctrl.CategoryHandler.CreateCategory)
+// r.Put("/category/{categoryID}", ctrl.CategoryHandler.UpdateCategory)
+// r.Delete("/category/{categoryID}", ctrl.CategoryHandler.DeleteCategory)
+
+type ICategoryController interface {
+	ListCategories(w http.ResponseWriter, r *http.Request)
+	GetCategoryByID(w http.ResponseWriter, r *http.Request)
+	CreateCategory(w http.ResponseWriter, r *http.Request)
+	UpdateCategory(w http.ResponseWriter, r *http.Request)
+	DeleteCategory(w http.ResponseWriter, r *http.Request)
+}
+
+type CategoryController struct {
+	responder responder.Responder
+	service   service.ICategoryService
+}
+
+func NewCategoryController(responder responder.Responder, service service.ICategoryService) *CategoryController {
+	return &CategoryController{
+		responder: responder,
+		service:   service,
+	}
+}
+
+func (c *CategoryController) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := c.service.GetAll()
+	if err != nil {
+		c.responder.ErrorInternal(w, err)
+		return
+	}
+
+	c.responder.OutputJSON(w, categories)
+}
+
+func (c *CategoryController) GetCategoryByID(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		c.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	category, err := c.service.GetByID(int64(categoryID))
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	c.responder.OutputJSON(w, category)
+}
+
+func (c *CategoryController) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		c.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = c.service.Create(&category)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	c.responder.OutputJSON(w, category)
+}
+
+func (c *CategoryController) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		c.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	var category models.Category
+	err = json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		c.responder.ErrorBadRequest(w, err)
+		return
+	}
+	category.ID = int64(categoryID)
+
+	err = c.service.Update(&category)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	c.responder.OutputJSON(w, category)
+}
+
+func (c *CategoryController) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		c.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = c.service.Delete(int64(categoryID))
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	c.responder.OutputJSON(w, "Category deleted successfully")
+}
+
+func (c *CategoryController) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		c.responder.ErrorBadRequest(w, errors.New("Category name must be provided"))
+	case errors.Is(err, service.ErrRecordNotFound):
+		c.responder.ErrorNotFound(w, errors.New("Category not found"))
+	case errors.Is(err, service.ErrDuplicateRecord):
+		c.responder.ErrorConflict(w, errors.New("Category already exists"))
+	case errors.Is(err, service.ErrCategoryInUse):
+		c.responder.ErrorConflict(w, errors.New("Category is still used by pets"))
+	default:
+		c.responder.ErrorInternal(w, err)
+	}
+}
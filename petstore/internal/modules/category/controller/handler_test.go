@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/infrastructure/responder"
+	"test/internal/models"
+	"test/internal/modules/category/repository"
+	"test/internal/modules/category/service"
+	"testing"
+
+	"github.com/go-chi/chi"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+type MockStorage struct {
+	Create_mock  func(category *models.Category) error
+	Update_mock  func(category *models.Category) error
+	Delete_mock  func(id int64) error
+	GetByID_mock func(id int64) (*models.Category, error)
+	GetAll_mock  func() ([]*models.Category, error)
+}
+
+func (m *MockStorage) Create(category *models.Category) error {
+	return m.Create_mock(category)
+}
+
+func (m *MockStorage) Update(category *models.Category) error {
+	return m.Update_mock(category)
+}
+
+func (m *MockStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+
+func (m *MockStorage) GetByID(id int64) (*models.Category, error) {
+	return m.GetByID_mock(id)
+}
+
+func (m *MockStorage) GetAll() ([]*models.Category, error) {
+	return m.GetAll_mock()
+}
+
+func newTestController(mock *MockStorage) *CategoryController {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		DisallowUnknownFields:  true,
+	})
+
+	return NewCategoryController(responder.NewResponder(decoder, logger), service.NewCategoryService(mock))
+}
+
+func withCategoryID(req *http.Request, id string) *http.Request {
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("categoryID", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+}
+
+func TestCreateCategoryHandler(t *testing.T) {
+
+	t.Run("happy path", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/category", bytes.NewReader([]byte(`{"name": "dogs"}`)))
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{
+			Create_mock: func(category *models.Category) error { return nil },
+		})
+		controller.CreateCategory(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/category", bytes.NewReader([]byte(`{"name": "dogs"}`)))
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{
+			Create_mock: func(category *models.Category) error { return repository.ErrDuplicateCategory },
+		})
+		controller.CreateCategory(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, w.Code)
+		}
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/category", bytes.NewReader([]byte(`{"name": `)))
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{})
+		controller.CreateCategory(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestGetCategoryHandler(t *testing.T) {
+
+	t.Run("happy path", func(t *testing.T) {
+		req := withCategoryID(httptest.NewRequest("GET", "/category/1", nil), "1")
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{
+			GetByID_mock: func(id int64) (*models.Category, error) { return &models.Category{ID: id}, nil },
+		})
+		controller.GetCategoryByID(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		req := withCategoryID(httptest.NewRequest("GET", "/category/1", nil), "1")
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{
+			GetByID_mock: func(id int64) (*models.Category, error) { return nil, repository.ErrCategoryNotFound },
+		})
+		controller.GetCategoryByID(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestDeleteCategoryHandler(t *testing.T) {
+
+	t.Run("in use", func(t *testing.T) {
+		req := withCategoryID(httptest.NewRequest("DELETE", "/category/1", nil), "1")
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{
+			Delete_mock: func(id int64) error { return repository.ErrCategoryInUse },
+		})
+		controller.DeleteCategory(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, w.Code)
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		req := withCategoryID(httptest.NewRequest("DELETE", "/category/x", nil), "x")
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{})
+		controller.DeleteCategory(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"test/internal/models"
+)
+
+type CategoryStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+}
+
+func NewCategoryStorage(db *sqlx.DB, logger *zap.Logger) ICategoryStorage {
+	return &CategoryStorage{
+		logger: logger,
+		DB:     db}
+}
+
+func (cs *CategoryStorage) Create(category *models.Category) error {
+	query := `INSERT INTO categories (name) VALUES ($1) RETURNING id`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := cs.DB.QueryRowContext(ctx, query, category.Name).Scan(&category.ID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "duplicate key value violates unique constraint"):
+			return ErrDuplicateCategory
+		default:
+			cs.logger.Error("error on inserting category", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cs *CategoryStorage) Update(category *models.Category) error {
+	query := `UPDATE categories SET name = $1 WHERE id = $2 RETURNING id`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := cs.DB.QueryRowContext(ctx, query, category.Name, category.ID).Scan(&category.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrCategoryNotFound
+		case strings.Contains(err.Error(), "duplicate key value violates unique constraint"):
+			return ErrDuplicateCategory
+		default:
+			cs.logger.Error("error on updating category", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cs *CategoryStorage) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := cs.DB.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "violates foreign key constraint"):
+			return ErrCategoryInUse
+		default:
+			cs.logger.Error("error on deleting category", zap.Error(err))
+			return err
+		}
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		cs.logger.Error("error on deleting category", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+func (cs *CategoryStorage) GetByID(id int64) (*models.Category, error) {
+	query := `SELECT id, name FROM categories WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	category := &models.Category{}
+	err := cs.DB.QueryRowContext(ctx, query, id).Scan(&category.ID, &category.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCategoryNotFound
+		default:
+			cs.logger.Error("error on getting category by id", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return category, nil
+}
+
+func (cs *CategoryStorage) GetAll() ([]*models.Category, error) {
+	query := `SELECT id, name FROM categories ORDER BY name ASC`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := cs.DB.QueryContext(ctx, query)
+	if err != nil {
+		cs.logger.Error("error on getting categories", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*models.Category{}
+	for rows.Next() {
+		var category models.Category
+		err := rows.Scan(&category.ID, &category.Name)
+		if err != nil {
+			cs.logger.Error("error on scanning category", zap.Error(err))
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		cs.logger.Error("error on iterating categories", zap.Error(err))
+		return nil, err
+	}
+
+	return categories, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+
+	"test/internal/models"
+	pet_storage "test/internal/modules/pet/repository"
+)
+
+// PetCatalog is what CategoryStorage_map needs from the in-memory pet storage
+// whose categories it serves.
+type PetCatalog interface {
+	ListCategories() []*models.Category
+	GetCategory(id int64) (*models.Category, error)
+	CreateCategory(category *models.Category) error
+	RenameCategory(category *models.Category) error
+	DeleteCategory(id int64) error
+}
+
+type CategoryStorage_map struct {
+	pets PetCatalog
+}
+
+func NewCategoryStorage_map(pets PetCatalog) ICategoryStorage {
+	return &CategoryStorage_map{pets: pets}
+}
+
+func (cs *CategoryStorage_map) Create(category *models.Category) error {
+	return categoryError(cs.pets.CreateCategory(category))
+}
+
+func (cs *CategoryStorage_map) Update(category *models.Category) error {
+	return categoryError(cs.pets.RenameCategory(category))
+}
+
+func (cs *CategoryStorage_map) Delete(id int64) error {
+	return categoryError(cs.pets.DeleteCategory(id))
+}
+
+func (cs *CategoryStorage_map) GetByID(id int64) (*models.Category, error) {
+	category, err := cs.pets.GetCategory(id)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return category, nil
+}
+
+func (cs *CategoryStorage_map) GetAll() ([]*models.Category, error) {
+	return cs.pets.ListCategories(), nil
+}
+
+// categoryError turns the errors of the pet storage into those of this one.
+func categoryError(err error) error {
+	switch {
+	case errors.Is(err, pet_storage.ErrCategoryNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, pet_storage.ErrDuplicateCategory):
+		return ErrDuplicateCategory
+	case errors.Is(err, pet_storage.ErrCategoryInUse):
+		return ErrCategoryInUse
+	}
+	return err
+}
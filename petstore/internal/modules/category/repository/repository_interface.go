@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"errors"
+
+	"test/internal/models"
+)
+
+var (
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrDuplicateCategory = errors.New("duplicate pet category")
+	ErrCategoryInUse     = errors.New("category is in use")
+)
+
+type ICategoryStorage interface {
+	Create(category *models.Category) error
+	Update(category *models.Category) error
+	Delete(id int64) error
+	GetByID(id int64) (*models.Category, error)
+	GetAll() ([]*models.Category, error)
+}
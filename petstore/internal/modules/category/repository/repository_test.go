@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"test/internal/models"
+	"testing"
+)
+
+func NewMockStorage() *MockStorage {
+	return &MockStorage{
+		Create_mock: func(category *models.Category) error {
+			return nil
+		},
+		Update_mock: func(category *models.Category) error {
+			return nil
+		},
+		Delete_mock: func(id int64) error {
+			return nil
+		},
+		GetByID_mock: func(id int64) (*models.Category, error) {
+			return &models.Category{}, nil
+		},
+		GetAll_mock: func() ([]*models.Category, error) {
+			return []*models.Category{}, nil
+		},
+	}
+}
+
+type MockStorage struct {
+	Create_mock  func(category *models.Category) error
+	Update_mock  func(category *models.Category) error
+	Delete_mock  func(id int64) error
+	GetByID_mock func(id int64) (*models.Category, error)
+	GetAll_mock  func() ([]*models.Category, error)
+}
+
+func (m *MockStorage) Create(category *models.Category) error {
+	return m.Create_mock(category)
+}
+
+func (m *MockStorage) Update(category *models.Category) error {
+	return m.Update_mock(category)
+}
+
+func (m *MockStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+
+func (m *MockStorage) GetByID(id int64) (*models.Category, error) {
+	return m.GetByID_mock(id)
+}
+
+func (m *MockStorage) GetAll() ([]*models.Category, error) {
+	return m.GetAll_mock()
+}
+
+func TestRepo(t *testing.T) {
+	var categoryRepository ICategoryStorage = NewMockStorage()
+
+	t.Run("Create", func(t *testing.T) {
+		resp := categoryRepository.Create(&models.Category{})
+		if resp != nil {
+			t.Errorf("expected nil got %v", resp)
+		}
+	})
+
+	t.Run("Get by ID", func(t *testing.T) {
+		resp, _ := categoryRepository.GetByID(0)
+		if resp == nil {
+			t.Errorf("expected category got nil")
+		}
+	})
+
+	t.Run("Get all", func(t *testing.T) {
+		resp, _ := categoryRepository.GetAll()
+		if resp == nil {
+			t.Errorf("expected categories got nil")
+		}
+	})
+}
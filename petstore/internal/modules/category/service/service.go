@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"test/internal/infrastructure/validator"
+	"test/internal/modules/category/repository"
+
+	"test/internal/models"
+)
+
+var (
+	ErrValidation      = errors.New("validation error")
+	ErrRecordNotFound  = errors.New("record not found")
+	ErrDuplicateRecord = errors.New("duplicate record")
+	ErrCategoryInUse   = errors.New("category is in use")
+)
+
+// r.Get("/category", ctrl.CategoryHandler.ListCategories)
+// r.Get("/category/{categoryID}", ctrl.CategoryHandler.GetCategoryByID)
+// r.Post("/category", ctrl.CategoryHandler.CreateCategory)
+// r.Put("/category/{categoryID}", ctrl.CategoryHandler.UpdateCategory)
+// r.Delete("/category/{categoryID}", ctrl.CategoryHandler.DeleteCategory)
+
+type ICategoryService interface {
+	Create(category *models.Category) error
+	Update(category *models.Category) error
+	Delete(id int64) error
+	GetByID(id int64) (*models.Category, error)
+	GetAll() ([]*models.Category, error)
+}
+
+type CategoryService struct {
+	storage repository.ICategoryStorage
+}
+
+func NewCategoryService(repo repository.ICategoryStorage) *CategoryService {
+	return &CategoryService{storage: repo}
+}
+
+func (s *CategoryService) Create(category *models.Category) error {
+	v := validator.New()
+	if ValidateCategory(v, category); !v.Valid() {
+		return ErrValidation
+	}
+
+	err := s.storage.Create(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicateCategory):
+			return ErrDuplicateRecord
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CategoryService) Update(category *models.Category) error {
+	v := validator.New()
+	if ValidateCategory(v, category); !v.Valid() {
+		return ErrValidation
+	}
+
+	err := s.storage.Update(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			return ErrRecordNotFound
+		case errors.Is(err, repository.ErrDuplicateCategory):
+			return ErrDuplicateRecord
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CategoryService) Delete(id int64) error {
+	err := s.storage.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			return ErrRecordNotFound
+		case errors.Is(err, repository.ErrCategoryInUse):
+			return ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CategoryService) GetByID(id int64) (*models.Category, error) {
+	category, err := s.storage.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return category, nil
+}
+
+func (s *CategoryService) GetAll() ([]*models.Category, error) {
+	return s.storage.GetAll()
+}
+
+func ValidateCategory(v *validator.Validator, category *models.Category) {
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 255, "name", "must not be more than 255 bytes long")
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"test/internal/models"
+	"test/internal/modules/category/repository"
+	"testing"
+)
+
+type MockStorage struct {
+	Create_mock  func(category *models.Category) error
+	Update_mock  func(category *models.Category) error
+	Delete_mock  func(id int64) error
+	GetByID_mock func(id int64) (*models.Category, error)
+	GetAll_mock  func() ([]*models.Category, error)
+}
+
+func (m *MockStorage) Create(category *models.Category) error {
+	return m.Create_mock(category)
+}
+
+func (m *MockStorage) Update(category *models.Category) error {
+	return m.Update_mock(category)
+}
+
+func (m *MockStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+
+func (m *MockStorage) GetByID(id int64) (*models.Category, error) {
+	return m.GetByID_mock(id)
+}
+
+func (m *MockStorage) GetAll() ([]*models.Category, error) {
+	return m.GetAll_mock()
+}
+
+func TestMockService(t *testing.T) {
+	mockStorage := MockStorage{}
+
+	mockStorage.Create_mock = func(category *models.Category) error {
+		if category.Name == "dogs" {
+			return repository.ErrDuplicateCategory
+		}
+		category.ID = 1
+		return nil
+	}
+	mockStorage.Update_mock = func(category *models.Category) error {
+		return repository.ErrCategoryNotFound
+	}
+	mockStorage.Delete_mock = func(id int64) error {
+		return repository.ErrCategoryInUse
+	}
+	mockStorage.GetByID_mock = func(id int64) (*models.Category, error) {
+		return &models.Category{ID: id, Name: "cats"}, nil
+	}
+	mockStorage.GetAll_mock = func() ([]*models.Category, error) {
+		return []*models.Category{}, nil
+	}
+
+	categoryService := NewCategoryService(&mockStorage)
+
+	t.Run("Create", func(t *testing.T) {
+		category := &models.Category{Name: "cats"}
+		err := categoryService.Create(category)
+		if err != nil || category.ID != 1 {
+			t.Errorf("expected created category got %v", err)
+		}
+	})
+
+	t.Run("Create duplicate", func(t *testing.T) {
+		err := categoryService.Create(&models.Category{Name: "dogs"})
+		if !errors.Is(err, ErrDuplicateRecord) {
+			t.Errorf("expected %v got %v", ErrDuplicateRecord, err)
+		}
+	})
+
+	t.Run("Create without name", func(t *testing.T) {
+		err := categoryService.Create(&models.Category{})
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("expected %v got %v", ErrValidation, err)
+		}
+	})
+
+	t.Run("Update missing", func(t *testing.T) {
+		err := categoryService.Update(&models.Category{ID: 5, Name: "cats"})
+		if !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("expected %v got %v", ErrRecordNotFound, err)
+		}
+	})
+
+	t.Run("Delete in use", func(t *testing.T) {
+		err := categoryService.Delete(1)
+		if !errors.Is(err, ErrCategoryInUse) {
+			t.Errorf("expected %v got %v", ErrCategoryInUse, err)
+		}
+	})
+
+	t.Run("Get by ID", func(t *testing.T) {
+		resp, _ := categoryService.GetByID(1)
+		if resp == nil {
+			t.Errorf("expected category got nil")
+		}
+	})
+}
@@ -2,21 +2,35 @@ package modules
 
 import (
 	"test/internal/infrastructure/components"
+	adoption_controller "test/internal/modules/adoption/controller"
+	category_controller "test/internal/modules/category/controller"
+	medical_controller "test/internal/modules/medical/controller"
 	pet_controller "test/internal/modules/pet/controllers"
 	store_controller "test/internal/modules/store/controller"
+	tag_controller "test/internal/modules/tag/controller"
 	user_controller "test/internal/modules/user/controller"
 )
 
 type Controllers struct {
-	UserHandler  user_controller.IUserHandler
-	PetHandler   pet_controller.IPetController
-	StoreHandler store_controller.IStoreController
+	UserHandler     user_controller.IUserHandler
+	PetHandler      pet_controller.IPetController
+	FavoriteHandler pet_controller.IFavoriteController
+	StoreHandler    store_controller.IStoreController
+	CategoryHandler category_controller.ICategoryController
+	TagHandler      tag_controller.ITagController
+	AdoptionHandler adoption_controller.IAdoptionController
+	MedicalHandler  medical_controller.IMedicalController
 }
 
 func NewControllers(services *Services, components *components.Components) *Controllers {
 	return &Controllers{
-		UserHandler:  user_controller.NewUserHandler(components.Responder, services.UserService),
-		PetHandler:   pet_controller.NewPetController(components.Responder, services.PetService),
-		StoreHandler: store_controller.NewStoreController(components.Responder, services.StoreService),
+		UserHandler:     user_controller.NewUserHandler(components.Responder, services.UserService),
+		PetHandler:      pet_controller.NewPetController(components.Responder, services.PetService, services.MedicalService),
+		FavoriteHandler: pet_controller.NewFavoriteController(components.Responder, services.FavoriteService),
+		StoreHandler:    store_controller.NewStoreController(components.Responder, services.StoreService),
+		CategoryHandler: category_controller.NewCategoryController(components.Responder, services.CategoryService),
+		TagHandler:      tag_controller.NewTagController(components.Responder, services.TagService),
+		AdoptionHandler: adoption_controller.NewAdoptionController(components.Responder, services.AdoptionService),
+		MedicalHandler:  medical_controller.NewMedicalController(components.Responder, services.MedicalService),
 	}
 }
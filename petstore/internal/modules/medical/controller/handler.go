@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/medical/service"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// r.Get("/pet/{petID}/records", ctrl.MedicalHandler.List)
+// r.Post("/pet/{petID}/records", ctrl.MedicalHandler.Create)
+// r.Get("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.GetByID)
+// r.Put("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.Update)
+// r.Delete("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.Delete)
+// r.Get("/pet/vaccinations/due", ctrl.MedicalHandler.DueVaccinations)
+
+type IMedicalController interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	GetByID(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	DueVaccinations(w http.ResponseWriter, r *http.Request)
+}
+
+type MedicalController struct {
+	responder responder.Responder
+	service   service.IMedicalService
+}
+
+func NewMedicalController(responder responder.Responder, service service.IMedicalService) *MedicalController {
+	return &MedicalController{
+		responder: responder,
+		service:   service,
+	}
+}
+
+// recordInput is the body of record writes.
+type recordInput struct {
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Notes       string       `json:"notes"`
+	Vet         string       `json:"vet"`
+	PerformedOn models.Date  `json:"performedOn"`
+	DueOn       *models.Date `json:"dueOn"`
+}
+
+func (in recordInput) record(petID int64) *models.MedicalRecord {
+	return &models.MedicalRecord{
+		PetID:       petID,
+		Type:        in.Type,
+		Title:       in.Title,
+		Notes:       in.Notes,
+		Vet:         in.Vet,
+		PerformedOn: in.PerformedOn,
+		DueOn:       in.DueOn,
+	}
+}
+
+// List serves GET /pet/{petID}/records, optionally narrowed to one type with
+// ?type=vaccination.
+func (m *MedicalController) List(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	recordType := r.URL.Query().Get("type")
+	if recordType != "" && !validator.PermittedValue(recordType, models.MedicalRecordTypes...) {
+		m.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	records, err := m.service.List(int64(petID), recordType)
+	if err != nil {
+		m.error(w, err)
+		return
+	}
+	m.responder.OutputJSON(w, records)
+}
+
+func (m *MedicalController) Create(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	var input recordInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	record := input.record(int64(petID))
+	err = m.service.Add(helpers.ActorFromContext(r.Context()), record)
+	if err != nil {
+		m.error(w, err)
+		return
+	}
+	m.responder.OutputJSON(w, record)
+}
+
+func (m *MedicalController) GetByID(w http.ResponseWriter, r *http.Request) {
+	petID, recordID, err := recordIDs(r)
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	record, err := m.service.GetByID(petID, recordID)
+	if err != nil {
+		m.error(w, err)
+		return
+	}
+	m.responder.OutputJSON(w, record)
+}
+
+func (m *MedicalController) Update(w http.ResponseWriter, r *http.Request) {
+	petID, recordID, err := recordIDs(r)
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	var input recordInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	record := input.record(petID)
+	record.ID = recordID
+	err = m.service.Update(helpers.ActorFromContext(r.Context()), record)
+	if err != nil {
+		m.error(w, err)
+		return
+	}
+	m.responder.OutputJSON(w, record)
+}
+
+func (m *MedicalController) Delete(w http.ResponseWriter, r *http.Request) {
+	petID, recordID, err := recordIDs(r)
+	if err != nil {
+		m.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = m.service.Delete(helpers.ActorFromContext(r.Context()), petID, recordID)
+	if err != nil {
+		m.error(w, err)
+		return
+	}
+	m.responder.OutputJSON(w, "Record deleted successfully")
+}
+
+// DueVaccinations serves GET /pet/vaccinations/due?days=30, listing the
+// vaccinations of every pet that fall due within the next days, overdue ones
+// first.
+func (m *MedicalController) DueVaccinations(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	days := helpers.ReadInt(r.URL.Query(), "days", 30, v)
+	v.Check(days >= 0, "days", "must not be negative")
+	v.Check(days <= service.MaxDueDays, "days", "must be a year at most")
+	if !v.Valid() {
+		m.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	records, err := m.service.DueVaccinations(time.Now(), days)
+	if err != nil {
+		m.responder.ErrorInternal(w, err)
+		return
+	}
+	m.responder.OutputJSON(w, records)
+}
+
+func recordIDs(r *http.Request) (int64, int64, error) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		return 0, 0, err
+	}
+	recordID, err := strconv.Atoi(chi.URLParam(r, "recordID"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(petID), int64(recordID), nil
+}
+
+func (m *MedicalController) error(w http.ResponseWriter, err error) {
+	var validationErr *validator.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		m.responder.ErrorValidation(w, validationErr.Errors)
+	case errors.Is(err, service.ErrRecordNotFound), errors.Is(err, service.ErrPetNotFound):
+		m.responder.ErrorNotFound(w, err)
+	case errors.Is(err, service.ErrForbidden):
+		m.responder.ErrorForbidden(w, err)
+	default:
+		m.responder.ErrorInternal(w, err)
+	}
+}
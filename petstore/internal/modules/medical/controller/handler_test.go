@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
+	"test/internal/models"
+	"test/internal/modules/medical/repository"
+	"test/internal/modules/medical/service"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+// fakePets knows pet 1 only.
+type fakePets struct{}
+
+func (fakePets) GetByID(id int64) (*models.Pet, error) {
+	if id != 1 {
+		return nil, errors.New("not found")
+	}
+	return &models.Pet{ID: id}, nil
+}
+
+func newController() *MedicalController {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		DisallowUnknownFields:  true,
+	})
+	s := service.NewMedicalService(repository.NewMedicalRecordStorage_map(), fakePets{})
+	return NewMedicalController(responder.NewResponder(decoder, logger), s)
+}
+
+func request(method, target, body, username, role string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	token, err := helpers.TokenAuth.Decode(helpers.GenerateToken(username, role))
+	ctx := jwtauth.NewContext(req.Context(), token, err)
+
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+const rabies = `{"type": "vaccination", "title": "Rabies", "performedOn": "2024-01-10", "dueOn": "2025-01-10"}`
+
+func TestRecordHandlers(t *testing.T) {
+	controller := newController()
+	pet := map[string]string{"petID": "1"}
+
+	w := httptest.NewRecorder()
+	controller.Create(w, request("POST", "/pet/1/records", rabies, "clerk", models.RoleStaff, pet))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var created models.MedicalRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.CreatedBy != "clerk" || created.DueOn == nil || created.DueOn.String() != "2025-01-10" {
+		t.Errorf("unexpected record %+v", created)
+	}
+
+	w = httptest.NewRecorder()
+	controller.List(w, request("GET", "/pet/1/records?type=vaccination", "", "alice", models.RoleUser, pet))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+	}
+	var listed []models.MedicalRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil || len(listed) != 1 {
+		t.Errorf("expected one record, got %s", w.Body)
+	}
+
+	both := map[string]string{"petID": "1", "recordID": "1"}
+	w = httptest.NewRecorder()
+	controller.Update(w, request("PUT", "/pet/1/records/1", `{"type": "vaccination", "title": "Rabies", "performedOn": "2024-01-10"}`, "clerk", models.RoleStaff, both))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	controller.Delete(w, request("DELETE", "/pet/1/records/1", "", "clerk", models.RoleStaff, both))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	controller.GetByID(w, request("GET", "/pet/1/records/1", "", "clerk", models.RoleStaff, both))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status code %d but got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRecordHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		user   string
+		role   string
+		params map[string]string
+		code   int
+	}{
+		{"customer", rabies, "alice", models.RoleUser, map[string]string{"petID": "1"}, http.StatusForbidden},
+		{"no such pet", rabies, "clerk", models.RoleStaff, map[string]string{"petID": "2"}, http.StatusNotFound},
+		{"bad pet id", rabies, "clerk", models.RoleStaff, map[string]string{"petID": "x"}, http.StatusBadRequest},
+		{"bad date", `{"type": "checkup", "title": "Yearly", "performedOn": "10/01/2024"}`, "clerk", models.RoleStaff, map[string]string{"petID": "1"}, http.StatusBadRequest},
+		{"invalid", `{"type": "surgery", "title": "Yearly", "performedOn": "2024-01-10"}`, "clerk", models.RoleStaff, map[string]string{"petID": "1"}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newController().Create(w, request("POST", "/pet/records", tt.body, tt.user, tt.role, tt.params))
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestDueVaccinationsHandler(t *testing.T) {
+	controller := newController()
+
+	w := httptest.NewRecorder()
+	controller.Create(w, request("POST", "/pet/1/records", `{"type": "vaccination", "title": "Rabies", "performedOn": "2020-01-10", "dueOn": "2021-01-10"}`, "clerk", models.RoleStaff, map[string]string{"petID": "1"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	controller.DueVaccinations(w, request("GET", "/pet/vaccinations/due?days=7", "", "clerk", models.RoleStaff, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+	}
+	var due []models.MedicalRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &due); err != nil || len(due) != 1 {
+		t.Errorf("expected the overdue rabies shot, got %s", w.Body)
+	}
+
+	for _, days := range []string{"-1", "366", "soon"} {
+		w = httptest.NewRecorder()
+		controller.DueVaccinations(w, request("GET", "/pet/vaccinations/due?days="+days, "", "clerk", models.RoleStaff, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: expected status code %d but got %d", days, http.StatusBadRequest, w.Code)
+		}
+	}
+}
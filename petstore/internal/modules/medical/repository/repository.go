@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"test/internal/models"
+)
+
+type MedicalRecordStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+}
+
+func NewMedicalRecordStorage(db *sqlx.DB, logger *zap.Logger) IMedicalRecordStorage {
+	return &MedicalRecordStorage{
+		logger: logger,
+		DB:     db}
+}
+
+const selectRecords = `
+	SELECT id, pet_id, type, title, notes, vet, performed_on, due_on, created_by, created_at
+FROM medical_records
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row rowScanner) (models.MedicalRecord, error) {
+	var (
+		record models.MedicalRecord
+		dueOn  *models.Date
+	)
+
+	err := row.Scan(
+		&record.ID,
+		&record.PetID,
+		&record.Type,
+		&record.Title,
+		&record.Notes,
+		&record.Vet,
+		&record.PerformedOn,
+		&dueOn,
+		&record.CreatedBy,
+		&record.CreatedAt,
+	)
+	record.DueOn = dueOn
+
+	return record, err
+}
+
+func (ms *MedicalRecordStorage) Create(record *models.MedicalRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := ms.DB.Rebind(`INSERT INTO medical_records (pet_id, type, title, notes, vet, performed_on, due_on, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`)
+
+	args := []any{
+		record.PetID,
+		record.Type,
+		record.Title,
+		record.Notes,
+		record.Vet,
+		record.PerformedOn,
+		record.DueOn,
+		record.CreatedBy,
+		record.CreatedAt,
+	}
+
+	err := ms.DB.QueryRowContext(ctx, query, args...).Scan(&record.ID)
+	if err != nil {
+		ms.logger.Error("error on inserting medical record", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (ms *MedicalRecordStorage) GetByID(id int64) (*models.MedicalRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	record, err := scanRecord(ms.DB.QueryRowContext(ctx, ms.DB.Rebind(selectRecords+`WHERE id = ?`), id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			ms.logger.Error("error on getting medical record", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return &record, nil
+}
+
+func (ms *MedicalRecordStorage) GetByPet(petID int64, recordType string) ([]models.MedicalRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	where := `WHERE pet_id = ?`
+	args := []any{petID}
+	if recordType != "" {
+		where += ` AND type = ?`
+		args = append(args, recordType)
+	}
+
+	query := ms.DB.Rebind(selectRecords + where + `
+ORDER BY performed_on DESC, id DESC`)
+
+	records, err := ms.query(ctx, query, args...)
+	if err != nil {
+		ms.logger.Error("error on getting medical records", zap.Error(err))
+		return nil, err
+	}
+	return records, nil
+}
+
+func (ms *MedicalRecordStorage) Update(record *models.MedicalRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := ms.DB.Rebind(`UPDATE medical_records
+		SET type = ?, title = ?, notes = ?, vet = ?, performed_on = ?, due_on = ?
+		WHERE id = ?`)
+
+	args := []any{
+		record.Type,
+		record.Title,
+		record.Notes,
+		record.Vet,
+		record.PerformedOn,
+		record.DueOn,
+		record.ID,
+	}
+
+	result, err := ms.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		ms.logger.Error("error on updating medical record", zap.Error(err))
+		return err
+	}
+	return expectOne(result)
+}
+
+func (ms *MedicalRecordStorage) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := ms.DB.ExecContext(ctx, ms.DB.Rebind(`DELETE FROM medical_records WHERE id = ?`), id)
+	if err != nil {
+		ms.logger.Error("error on deleting medical record", zap.Error(err))
+		return err
+	}
+	return expectOne(result)
+}
+
+func (ms *MedicalRecordStorage) GetDueVaccinations(until models.Date) ([]models.MedicalRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := ms.DB.Rebind(`SELECT r.id, r.pet_id, r.type, r.title, r.notes, r.vet, r.performed_on, r.due_on, r.created_by, r.created_at
+FROM medical_records r
+WHERE r.type = ? AND r.due_on IS NOT NULL AND r.due_on <= ?
+AND NOT EXISTS (
+	SELECT 1 FROM medical_records later
+	WHERE later.pet_id = r.pet_id AND later.type = r.type AND later.title = r.title
+	AND (later.performed_on > r.performed_on OR (later.performed_on = r.performed_on AND later.id > r.id))
+)
+ORDER BY r.due_on, r.pet_id, r.id`)
+
+	records, err := ms.query(ctx, query, models.RecordVaccination, until)
+	if err != nil {
+		ms.logger.Error("error on getting due vaccinations", zap.Error(err))
+		return nil, err
+	}
+	return records, nil
+}
+
+func (ms *MedicalRecordStorage) query(ctx context.Context, query string, args ...any) ([]models.MedicalRecord, error) {
+	rows, err := ms.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.MedicalRecord{}
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func expectOne(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"test/internal/models"
+)
+
+// MedicalRecordStorage_map keeps medical records in memory for the memory
+// backend. It stores and hands out copies.
+type MedicalRecordStorage_map struct {
+	mu      sync.RWMutex
+	records map[int64]*models.MedicalRecord
+	lastID  int64
+}
+
+func NewMedicalRecordStorage_map() *MedicalRecordStorage_map {
+	return &MedicalRecordStorage_map{records: make(map[int64]*models.MedicalRecord)}
+}
+
+func (ms *MedicalRecordStorage_map) Create(record *models.MedicalRecord) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.lastID++
+	record.ID = ms.lastID
+	ms.records[record.ID] = cloneRecord(record)
+	return nil
+}
+
+func (ms *MedicalRecordStorage_map) GetByID(id int64) (*models.MedicalRecord, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	stored, ok := ms.records[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return cloneRecord(stored), nil
+}
+
+func (ms *MedicalRecordStorage_map) GetByPet(petID int64, recordType string) ([]models.MedicalRecord, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	records := []models.MedicalRecord{}
+	for _, record := range ms.records {
+		if record.PetID == petID && (recordType == "" || record.Type == recordType) {
+			records = append(records, *cloneRecord(record))
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return later(&records[i], &records[j])
+	})
+	return records, nil
+}
+
+func (ms *MedicalRecordStorage_map) Update(record *models.MedicalRecord) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored, ok := ms.records[record.ID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	updated := cloneRecord(stored)
+	updated.Type = record.Type
+	updated.Title = record.Title
+	updated.Notes = record.Notes
+	updated.Vet = record.Vet
+	updated.PerformedOn = record.PerformedOn
+	updated.DueOn = cloneDate(record.DueOn)
+	ms.records[record.ID] = updated
+	return nil
+}
+
+func (ms *MedicalRecordStorage_map) Delete(id int64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.records[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(ms.records, id)
+	return nil
+}
+
+func (ms *MedicalRecordStorage_map) GetDueVaccinations(until models.Date) ([]models.MedicalRecord, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	type kind struct {
+		petID int64
+		title string
+	}
+	latest := map[kind]*models.MedicalRecord{}
+	for _, record := range ms.records {
+		if record.Type != models.RecordVaccination {
+			continue
+		}
+		k := kind{record.PetID, record.Title}
+		if current, ok := latest[k]; !ok || later(record, current) {
+			latest[k] = record
+		}
+	}
+
+	due := []models.MedicalRecord{}
+	for _, record := range latest {
+		if record.DueOn != nil && !record.DueOn.After(until.Time) {
+			due = append(due, *cloneRecord(record))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		a, b := due[i], due[j]
+		if !a.DueOn.Equal(b.DueOn.Time) {
+			return a.DueOn.Before(b.DueOn.Time)
+		}
+		if a.PetID != b.PetID {
+			return a.PetID < b.PetID
+		}
+		return a.ID < b.ID
+	})
+	return due, nil
+}
+
+// later reports whether a was performed after b, telling records of the
+// same day apart by id.
+func later(a, b *models.MedicalRecord) bool {
+	if !a.PerformedOn.Equal(b.PerformedOn.Time) {
+		return a.PerformedOn.After(b.PerformedOn.Time)
+	}
+	return a.ID > b.ID
+}
+
+func cloneRecord(record *models.MedicalRecord) *models.MedicalRecord {
+	clone := *record
+	clone.DueOn = cloneDate(record.DueOn)
+	return &clone
+}
+
+func cloneDate(d *models.Date) *models.Date {
+	if d == nil {
+		return nil
+	}
+	clone := *d
+	return &clone
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"errors"
+
+	"test/internal/models"
+)
+
+var (
+	ErrRecordNotFound = errors.New("record not found")
+)
+
+type IMedicalRecordStorage interface {
+	Create(record *models.MedicalRecord) error
+	GetByID(id int64) (*models.MedicalRecord, error)
+	// GetByPet returns the records of a pet, latest first, of recordType
+	// or of every type when it is empty.
+	GetByPet(petID int64, recordType string) ([]models.MedicalRecord, error)
+	Update(record *models.MedicalRecord) error
+	Delete(id int64) error
+	// GetDueVaccinations returns the vaccinations due on or before until,
+	// earliest first. Only the latest vaccination of a kind per pet counts;
+	// a booster supersedes the shot before it.
+	GetDueVaccinations(until models.Date) ([]models.MedicalRecord, error)
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"errors"
+	"test/internal/infrastructure/dbtest"
+	"test/internal/models"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sqliteSchema mirrors migration 000013 without the foreign key to pets.
+const sqliteSchema = `
+CREATE TABLE medical_records (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pet_id INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	title TEXT NOT NULL,
+	notes TEXT NOT NULL DEFAULT '',
+	vet TEXT NOT NULL DEFAULT '',
+	performed_on DATE NOT NULL,
+	due_on DATE,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+`
+
+func newSQLiteStorage(tb testing.TB) *MedicalRecordStorage {
+	tb.Helper()
+
+	db := dbtest.OpenSQLite(tb, sqliteSchema)
+	return NewMedicalRecordStorage(db, zap.NewNop()).(*MedicalRecordStorage)
+}
+
+func date(s string) models.Date {
+	d, err := models.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func datePtr(s string) *models.Date {
+	d := date(s)
+	return &d
+}
+
+func testRecord(petID int64, recordType, title, performedOn string, dueOn *models.Date) *models.MedicalRecord {
+	return &models.MedicalRecord{
+		PetID:       petID,
+		Type:        recordType,
+		Title:       title,
+		Vet:         "Dr. Who",
+		PerformedOn: date(performedOn),
+		DueOn:       dueOn,
+		CreatedBy:   "clerk",
+		CreatedAt:   time.Date(2024, 10, 12, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestMedicalRecordStorage(t *testing.T) {
+	backends := map[string]func(t *testing.T) IMedicalRecordStorage{
+		"sql":    func(t *testing.T) IMedicalRecordStorage { return newSQLiteStorage(t) },
+		"memory": func(t *testing.T) IMedicalRecordStorage { return NewMedicalRecordStorage_map() },
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("create, get and update", func(t *testing.T) {
+				storage := newStorage(t)
+
+				record := testRecord(1, models.RecordVaccination, "Rabies", "2024-01-10", datePtr("2025-01-10"))
+				if err := storage.Create(record); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := storage.GetByID(record.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Title != "Rabies" || got.Vet != "Dr. Who" || got.CreatedBy != "clerk" {
+					t.Errorf("unexpected record %+v", got)
+				}
+				if got.PerformedOn.String() != "2024-01-10" || got.DueOn == nil || got.DueOn.String() != "2025-01-10" {
+					t.Errorf("unexpected dates %v and %v", got.PerformedOn, got.DueOn)
+				}
+
+				record.Title = "Rabies booster"
+				record.DueOn = nil
+				if err := storage.Update(record); err != nil {
+					t.Fatal(err)
+				}
+				got, err = storage.GetByID(record.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Title != "Rabies booster" || got.DueOn != nil {
+					t.Errorf("unexpected record %+v", got)
+				}
+
+				if err := storage.Delete(record.ID); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := storage.GetByID(record.ID); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+				if err := storage.Update(record); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+				if err := storage.Delete(record.ID); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+			})
+
+			t.Run("get by pet", func(t *testing.T) {
+				storage := newStorage(t)
+
+				for _, record := range []*models.MedicalRecord{
+					testRecord(1, models.RecordCheckup, "Yearly", "2024-02-01", nil),
+					testRecord(1, models.RecordVaccination, "Rabies", "2024-03-01", nil),
+					testRecord(2, models.RecordCheckup, "Yearly", "2024-04-01", nil),
+					testRecord(1, models.RecordTreatment, "Deworming", "2024-01-01", nil),
+				} {
+					if err := storage.Create(record); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				got, err := storage.GetByPet(1, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 3 || got[0].Title != "Rabies" || got[1].Title != "Yearly" || got[2].Title != "Deworming" {
+					t.Errorf("expected pet 1's records latest first, got %+v", got)
+				}
+
+				got, err = storage.GetByPet(1, models.RecordCheckup)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 || got[0].Type != models.RecordCheckup {
+					t.Errorf("expected pet 1's check-up, got %+v", got)
+				}
+			})
+
+			t.Run("due vaccinations", func(t *testing.T) {
+				storage := newStorage(t)
+
+				for _, record := range []*models.MedicalRecord{
+					// superseded by the booster below
+					testRecord(1, models.RecordVaccination, "Rabies", "2023-01-10", datePtr("2024-01-10")),
+					testRecord(1, models.RecordVaccination, "Rabies", "2024-01-10", datePtr("2025-01-10")),
+					testRecord(1, models.RecordVaccination, "Distemper", "2024-01-10", datePtr("2024-11-01")),
+					testRecord(2, models.RecordVaccination, "Rabies", "2023-10-01", datePtr("2024-10-01")),
+					testRecord(3, models.RecordVaccination, "Rabies", "2024-06-01", datePtr("2025-06-01")),
+					testRecord(3, models.RecordTreatment, "Antibiotics", "2024-06-01", datePtr("2024-06-10")),
+				} {
+					if err := storage.Create(record); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				got, err := storage.GetDueVaccinations(date("2024-11-01"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 2 || got[0].PetID != 2 || got[1].PetID != 1 || got[1].Title != "Distemper" {
+					t.Errorf("expected pet 2's rabies and pet 1's distemper, got %+v", got)
+				}
+
+				got, err = storage.GetDueVaccinations(date("2025-01-10"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 3 || got[2].Title != "Rabies" || got[2].DueOn.String() != "2025-01-10" {
+					t.Errorf("expected pet 1's rabies booster last, got %+v", got)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/medical/repository"
+)
+
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrForbidden      = errors.New("only staff can change medical records")
+	ErrPetNotFound    = errors.New("pet not found")
+)
+
+const (
+	maxTitleLength = 255
+	maxNotesLength = 5000
+	maxVetLength   = 255
+
+	// MaxDueDays is how far ahead due vaccinations can be looked up.
+	MaxDueDays = 365
+)
+
+// r.Get("/pet/{petID}/records", ctrl.MedicalHandler.List)
+// r.Post("/pet/{petID}/records", ctrl.MedicalHandler.Create)
+// r.Get("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.GetByID)
+// r.Put("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.Update)
+// r.Delete("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.Delete)
+// r.Get("/pet/vaccinations/due", ctrl.MedicalHandler.DueVaccinations)
+
+type IMedicalService interface {
+	Add(actor models.Actor, record *models.MedicalRecord) error
+	List(petID int64, recordType string) ([]models.MedicalRecord, error)
+	GetByID(petID, id int64) (*models.MedicalRecord, error)
+	Update(actor models.Actor, record *models.MedicalRecord) error
+	Delete(actor models.Actor, petID, id int64) error
+	DueVaccinations(now time.Time, days int) ([]models.MedicalRecord, error)
+	Summary(petID int64, now time.Time) (*models.MedicalSummary, error)
+}
+
+// Pets is what medical records need from the pet service.
+type Pets interface {
+	GetByID(id int64) (*models.Pet, error)
+}
+
+type MedicalService struct {
+	storage repository.IMedicalRecordStorage
+	pets    Pets
+}
+
+func NewMedicalService(repo repository.IMedicalRecordStorage, pets Pets) *MedicalService {
+	return &MedicalService{storage: repo, pets: pets}
+}
+
+// Add enters a record into a pet's medical file on behalf of staff.
+func (s *MedicalService) Add(actor models.Actor, record *models.MedicalRecord) error {
+	if !actor.IsStaff() {
+		return ErrForbidden
+	}
+	if err := validateRecord(record, time.Now()); err != nil {
+		return err
+	}
+	if _, err := s.pets.GetByID(record.PetID); err != nil {
+		return ErrPetNotFound
+	}
+
+	record.CreatedBy = actor.Username
+	record.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	return s.storage.Create(record)
+}
+
+// List returns a pet's records of recordType, or all of them when it is
+// empty, latest first.
+func (s *MedicalService) List(petID int64, recordType string) ([]models.MedicalRecord, error) {
+	if _, err := s.pets.GetByID(petID); err != nil {
+		return nil, ErrPetNotFound
+	}
+	return s.storage.GetByPet(petID, recordType)
+}
+
+// GetByID returns a record of the given pet.
+func (s *MedicalService) GetByID(petID, id int64) (*models.MedicalRecord, error) {
+	record, err := s.storage.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if record.PetID != petID {
+		return nil, ErrRecordNotFound
+	}
+	return record, nil
+}
+
+// Update replaces the details of a record. Who entered it and when stays.
+func (s *MedicalService) Update(actor models.Actor, record *models.MedicalRecord) error {
+	if !actor.IsStaff() {
+		return ErrForbidden
+	}
+	stored, err := s.GetByID(record.PetID, record.ID)
+	if err != nil {
+		return err
+	}
+	if err := validateRecord(record, time.Now()); err != nil {
+		return err
+	}
+
+	err = s.storage.Update(record)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+	record.CreatedBy = stored.CreatedBy
+	record.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+func (s *MedicalService) Delete(actor models.Actor, petID, id int64) error {
+	if !actor.IsStaff() {
+		return ErrForbidden
+	}
+	if _, err := s.GetByID(petID, id); err != nil {
+		return err
+	}
+
+	err := s.storage.Delete(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// DueVaccinations returns the vaccinations falling due within days of now,
+// including those already overdue.
+func (s *MedicalService) DueVaccinations(now time.Time, days int) ([]models.MedicalRecord, error) {
+	until := models.NewDate(now.AddDate(0, 0, days))
+	return s.storage.GetDueVaccinations(until)
+}
+
+// Summary sums up a pet's medical file as of now. Only the latest record of
+// a kind counts towards what is due.
+func (s *MedicalService) Summary(petID int64, now time.Time) (*models.MedicalSummary, error) {
+	records, err := s.storage.GetByPet(petID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.MedicalSummary{Records: len(records), Vaccinations: []models.MedicalRecord{}}
+	today := models.NewDate(now)
+	seen := map[string]bool{}
+	// records come latest first, so the first of a kind is the one in force
+	for i := range records {
+		record := records[i]
+		if record.Type == models.RecordCheckup && summary.LastCheckup == nil {
+			performedOn := record.PerformedOn
+			summary.LastCheckup = &performedOn
+		}
+
+		kind := record.Type + "\x00" + record.Title
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+
+		if record.Type == models.RecordVaccination {
+			summary.Vaccinations = append(summary.Vaccinations, record)
+		}
+		if record.DueOn == nil {
+			continue
+		}
+		if summary.NextDue == nil || record.DueOn.Before(summary.NextDue.Time) {
+			dueOn := *record.DueOn
+			summary.NextDue = &dueOn
+		}
+		if record.DueOn.Before(today.Time) {
+			summary.Overdue = append(summary.Overdue, record.Title)
+		}
+	}
+
+	sort.Slice(summary.Vaccinations, func(i, j int) bool {
+		return summary.Vaccinations[i].Title < summary.Vaccinations[j].Title
+	})
+	sort.Strings(summary.Overdue)
+	return summary, nil
+}
+
+// validateRecord checks a record as entered at now.
+func validateRecord(record *models.MedicalRecord, now time.Time) error {
+	record.Title = strings.TrimSpace(record.Title)
+
+	v := validator.New()
+	v.Check(validator.PermittedValue(record.Type, models.MedicalRecordTypes...), "type", "must be one of vaccination, treatment or checkup")
+	v.Check(record.Title != "", "title", "must be provided")
+	v.Check(utf8.RuneCountInString(record.Title) <= maxTitleLength, "title", fmt.Sprintf("must not be more than %d characters long", maxTitleLength))
+	v.Check(utf8.RuneCountInString(record.Notes) <= maxNotesLength, "notes", fmt.Sprintf("must not be more than %d characters long", maxNotesLength))
+	v.Check(utf8.RuneCountInString(record.Vet) <= maxVetLength, "vet", fmt.Sprintf("must not be more than %d characters long", maxVetLength))
+	v.Check(!record.PerformedOn.IsZero(), "performedOn", "must be provided")
+	v.Check(!record.PerformedOn.After(models.NewDate(now).Time), "performedOn", "must not be in the future")
+	if record.DueOn != nil {
+		v.Check(record.DueOn.After(record.PerformedOn.Time), "dueOn", "must be after performedOn")
+	}
+	if !v.Valid() {
+		return &validator.ValidationError{Errors: v.Errors}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"errors"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/medical/repository"
+	"testing"
+	"time"
+)
+
+// fakePets knows pets 1 and 2.
+type fakePets struct{}
+
+func (fakePets) GetByID(id int64) (*models.Pet, error) {
+	if id != 1 && id != 2 {
+		return nil, errors.New("not found")
+	}
+	return &models.Pet{ID: id}, nil
+}
+
+var (
+	alice = models.Actor{Username: "alice", Role: models.RoleUser}
+	clerk = models.Actor{Username: "clerk", Role: models.RoleStaff}
+	today = time.Date(2024, 10, 12, 12, 0, 0, 0, time.UTC)
+)
+
+func date(s string) models.Date {
+	d, err := models.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func datePtr(s string) *models.Date {
+	d := date(s)
+	return &d
+}
+
+func newTestService() *MedicalService {
+	return NewMedicalService(repository.NewMedicalRecordStorage_map(), fakePets{})
+}
+
+func TestAdd(t *testing.T) {
+	s := newTestService()
+
+	record := &models.MedicalRecord{PetID: 1, Type: models.RecordVaccination, Title: " Rabies ", PerformedOn: date("2024-01-10"), DueOn: datePtr("2025-01-10")}
+	if err := s.Add(alice, record); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for a customer, got %v", err)
+	}
+	if err := s.Add(clerk, record); err != nil {
+		t.Fatal(err)
+	}
+	if record.ID == 0 || record.Title != "Rabies" || record.CreatedBy != "clerk" || record.CreatedAt.IsZero() {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	missing := &models.MedicalRecord{PetID: 3, Type: models.RecordCheckup, Title: "Yearly", PerformedOn: date("2024-01-10")}
+	if err := s.Add(clerk, missing); !errors.Is(err, ErrPetNotFound) {
+		t.Errorf("expected ErrPetNotFound, got %v", err)
+	}
+
+	if _, err := s.GetByID(2, record.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected another pet's record to be not found, got %v", err)
+	}
+	if err := s.Delete(clerk, 2, record.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected another pet's record to be not found, got %v", err)
+	}
+}
+
+func TestValidateRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record models.MedicalRecord
+		field  string
+	}{
+		{"type", models.MedicalRecord{Type: "surgery", Title: "x", PerformedOn: date("2024-01-10")}, "type"},
+		{"title", models.MedicalRecord{Type: models.RecordCheckup, Title: "  ", PerformedOn: date("2024-01-10")}, "title"},
+		{"no date", models.MedicalRecord{Type: models.RecordCheckup, Title: "x"}, "performedOn"},
+		{"future", models.MedicalRecord{Type: models.RecordCheckup, Title: "x", PerformedOn: date("2024-10-13")}, "performedOn"},
+		{"due before", models.MedicalRecord{Type: models.RecordVaccination, Title: "x", PerformedOn: date("2024-01-10"), DueOn: datePtr("2024-01-10")}, "dueOn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *validator.ValidationError
+			err := validateRecord(&tt.record, today)
+			if !errors.As(err, &validationErr) || validationErr.Errors[tt.field] == "" {
+				t.Errorf("expected a %s error, got %v", tt.field, err)
+			}
+		})
+	}
+
+	valid := models.MedicalRecord{Type: models.RecordCheckup, Title: "Yearly", PerformedOn: date("2024-10-12")}
+	if err := validateRecord(&valid, today); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	s := newTestService()
+
+	record := &models.MedicalRecord{PetID: 1, Type: models.RecordTreatment, Title: "Deworming", PerformedOn: date("2024-01-10")}
+	if err := s.Add(clerk, record); err != nil {
+		t.Fatal(err)
+	}
+
+	update := &models.MedicalRecord{ID: record.ID, PetID: 1, Type: models.RecordTreatment, Title: "Deworming", Notes: "half a tablet", PerformedOn: date("2024-01-11")}
+	if err := s.Update(alice, update); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if err := s.Update(clerk, update); err != nil {
+		t.Fatal(err)
+	}
+	if update.CreatedBy != "clerk" || !update.CreatedAt.Equal(record.CreatedAt) {
+		t.Errorf("expected the author to stay, got %+v", update)
+	}
+
+	got, err := s.GetByID(1, record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Notes != "half a tablet" || got.PerformedOn.String() != "2024-01-11" {
+		t.Errorf("unexpected record %+v", got)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	s := newTestService()
+
+	for _, record := range []*models.MedicalRecord{
+		{PetID: 1, Type: models.RecordCheckup, Title: "Yearly", PerformedOn: date("2023-09-01")},
+		{PetID: 1, Type: models.RecordCheckup, Title: "Yearly", PerformedOn: date("2024-09-01")},
+		{PetID: 1, Type: models.RecordVaccination, Title: "Rabies", PerformedOn: date("2023-01-10"), DueOn: datePtr("2024-01-10")},
+		{PetID: 1, Type: models.RecordVaccination, Title: "Rabies", PerformedOn: date("2024-01-10"), DueOn: datePtr("2025-01-10")},
+		{PetID: 1, Type: models.RecordVaccination, Title: "Distemper", PerformedOn: date("2023-09-01"), DueOn: datePtr("2024-09-01")},
+		{PetID: 2, Type: models.RecordVaccination, Title: "Rabies", PerformedOn: date("2024-01-10"), DueOn: datePtr("2024-02-01")},
+	} {
+		if err := s.Add(clerk, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary, err := s.Summary(1, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 5 {
+		t.Errorf("expected 5 records, got %d", summary.Records)
+	}
+	if summary.LastCheckup == nil || summary.LastCheckup.String() != "2024-09-01" {
+		t.Errorf("expected the last check-up on 2024-09-01, got %v", summary.LastCheckup)
+	}
+	if len(summary.Vaccinations) != 2 || summary.Vaccinations[0].Title != "Distemper" || summary.Vaccinations[1].PerformedOn.String() != "2024-01-10" {
+		t.Errorf("expected the latest distemper and rabies shots, got %+v", summary.Vaccinations)
+	}
+	if summary.NextDue == nil || summary.NextDue.String() != "2024-09-01" {
+		t.Errorf("expected distemper due first, got %v", summary.NextDue)
+	}
+	if len(summary.Overdue) != 1 || summary.Overdue[0] != "Distemper" {
+		t.Errorf("expected distemper overdue, got %v", summary.Overdue)
+	}
+
+	empty, err := s.Summary(3, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Records != 0 || empty.Vaccinations == nil || empty.NextDue != nil {
+		t.Errorf("unexpected summary of an empty file %+v", empty)
+	}
+}
+
+func TestDueVaccinations(t *testing.T) {
+	s := newTestService()
+
+	for _, record := range []*models.MedicalRecord{
+		{PetID: 1, Type: models.RecordVaccination, Title: "Rabies", PerformedOn: date("2024-01-10"), DueOn: datePtr("2024-11-01")},
+		{PetID: 2, Type: models.RecordVaccination, Title: "Rabies", PerformedOn: date("2024-01-10"), DueOn: datePtr("2025-01-10")},
+	} {
+		if err := s.Add(clerk, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	due, err := s.DueVaccinations(today, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].PetID != 1 {
+		t.Errorf("expected pet 1 due within 30 days, got %+v", due)
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"test/internal/models"
+)
+
+var errETagMismatch = errors.New("If-Match doesn't match any version of the pet")
+
+// etag renders a pet version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// petETag tags a pet as served. Its favorite count changes without the
+// version, so a pet with favorites gets a weak tag of both instead, which
+// If-None-Match still compares but If-Match never accepts.
+func petETag(pet *models.Pet) string {
+	if pet.FavoriteCount == 0 {
+		return etag(pet.Version)
+	}
+	return `W/"` + strconv.Itoa(pet.Version) + "-" + strconv.Itoa(pet.FavoriteCount) + `"`
+}
+
+// ifMatchVersion reads the version a write is conditional on. It returns 0
+// when the request has no If-Match header or uses "*", and errETagMismatch
+// for tags we never issued, which can't match any version.
+func ifMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 || !strings.HasPrefix(header, `"`) {
+		return 0, errETagMismatch
+	}
+	return version, nil
+}
+
+// notModified reports whether If-None-Match already names the current tag.
+// Weak tags compare equal to strong ones, as RFC 9110 asks for GET.
+func notModified(r *http.Request, current string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	current = strings.TrimPrefix(current, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
+	"test/internal/modules/pet/service"
+
+	"github.com/go-chi/chi"
+)
+
+// r.Get("/user/me/favorites", ctrl.FavoriteHandler.FavoriteList)
+// r.Post("/user/me/favorites/{petID}", ctrl.FavoriteHandler.FavoriteAdd)
+// r.Delete("/user/me/favorites/{petID}", ctrl.FavoriteHandler.FavoriteRemove)
+
+type IFavoriteController interface {
+	FavoriteList(w http.ResponseWriter, r *http.Request)
+	FavoriteAdd(w http.ResponseWriter, r *http.Request)
+	FavoriteRemove(w http.ResponseWriter, r *http.Request)
+}
+
+type FavoriteController struct {
+	responder responder.Responder
+	service   service.IFavoriteService
+}
+
+func NewFavoriteController(responder responder.Responder, service service.IFavoriteService) *FavoriteController {
+	return &FavoriteController{
+		responder: responder,
+		service:   service,
+	}
+}
+
+// FavoriteList serves GET /user/me/favorites?page=1&page_size=20, the
+// favorite pets of the user behind the token, most recently added first.
+func (f *FavoriteController) FavoriteList(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	page := filters.Filters{
+		Page:         helpers.ReadInt(qs, "page", 1, v),
+		PageSize:     helpers.ReadInt(qs, "page_size", 20, v),
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+	if filters.ValidateFilters(v, page); !v.Valid() {
+		f.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	pets, metadata, err := f.service.List(helpers.ActorFromContext(r.Context()), page)
+	if err != nil {
+		f.error(w, err)
+		return
+	}
+	f.responder.OutputJSON(w, map[string]interface{}{"metadata": metadata, "data": pets})
+}
+
+func (f *FavoriteController) FavoriteAdd(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		f.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = f.service.Add(helpers.ActorFromContext(r.Context()), int64(petID))
+	if err != nil {
+		f.error(w, err)
+		return
+	}
+	f.responder.OutputJSON(w, "Pet added to favorites")
+}
+
+func (f *FavoriteController) FavoriteRemove(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		f.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = f.service.Remove(helpers.ActorFromContext(r.Context()), int64(petID))
+	if err != nil {
+		f.error(w, err)
+		return
+	}
+	f.responder.OutputJSON(w, "Pet removed from favorites")
+}
+
+func (f *FavoriteController) error(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrRecordNotFound), errors.Is(err, service.ErrFavoriteNotFound):
+		f.responder.ErrorNotFound(w, err)
+	case errors.Is(err, service.ErrNoUser):
+		f.responder.ErrorUnauthorized(w, err)
+	default:
+		f.responder.ErrorInternal(w, err)
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/infrastructure/responder"
+	"test/internal/models"
+	"test/internal/modules/pet/repository"
+	"test/internal/modules/pet/service"
+	"testing"
+
+	"github.com/go-chi/chi"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+func TestFavoriteHandlers(t *testing.T) {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+	pets := repository.NewPetStorage_map(zap.NewNop())
+	name := "rex"
+	rex := &models.Pet{Name: &name, Status: models.PetStatusAvailable}
+	if err := pets.Create(rex, nil); err != nil {
+		t.Fatal(err)
+	}
+	controller := NewFavoriteController(responder.NewResponder(decoder, logger), service.NewFavoriteService(repository.NewFavoriteStorage_map(pets)))
+
+	newRequest := func(method, petID, username string) *http.Request {
+		req := httptest.NewRequest(method, "/user/me/favorites/"+petID, nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("petID", petID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		if username == "" {
+			return req
+		}
+		return asUser(req, username, models.RoleUser)
+	}
+
+	w := httptest.NewRecorder()
+	controller.FavoriteAdd(w, newRequest("POST", "1", "alice"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	controller.FavoriteList(w, asUser(httptest.NewRequest("GET", "/user/me/favorites", nil), "alice", models.RoleUser))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+	}
+	var got struct {
+		Metadata filter.Metadata `json:"metadata"`
+		Data     []models.Pet    `json:"data"`
+	}
+	if err := jsoniter.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Data) != 1 || got.Data[0].ID != rex.ID || got.Data[0].FavoriteCount != 1 || got.Metadata.TotalRecords != 1 {
+		t.Errorf("unexpected favorites %s", w.Body)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		petID    string
+		username string
+		code     int
+	}{
+		{"unknown pet", "POST", "2", "alice", http.StatusNotFound},
+		{"bad pet id", "POST", "x", "alice", http.StatusBadRequest},
+		{"anonymous", "POST", "1", "", http.StatusUnauthorized},
+		{"someone else's favorite", "DELETE", "1", "bob", http.StatusNotFound},
+		{"remove", "DELETE", "1", "alice", http.StatusOK},
+		{"removed already", "DELETE", "1", "alice", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := newRequest(tt.method, tt.petID, tt.username)
+			if tt.method == "POST" {
+				controller.FavoriteAdd(w, req)
+			} else {
+				controller.FavoriteRemove(w, req)
+			}
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+}
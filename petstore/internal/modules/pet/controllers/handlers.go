@@ -1,43 +1,83 @@
 package controller
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/jsonpatch"
 	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
 	"test/internal/models"
 	"test/internal/modules/pet/service"
+	"time"
 
 	"github.com/go-chi/chi"
 )
 
+// r.Get("/pet", ctrl.petController.PetList)
 // r.Post("/pet", ctrl.petController.PetCreate)
 // r.Post("/pet/{petID}", ctrl.petController.PetUpdate)
 // r.Get("/pet/{petID}", ctrl.petController.PetGetByID)
 // r.Get("/pet/findByStatus", ctrl.petController.PetGetByStatus)
+// r.Get("/pet/findByTags", ctrl.petController.PetGetByTags)
+// r.Post("/pet/import", ctrl.petController.PetImport)
+// r.Get("/pet/export", ctrl.petController.PetExport)
+// r.Post("/pet/{petID}/uploadImage", ctrl.petController.PetUploadImage)
+// r.Get("/pet/{petID}/history", ctrl.petController.PetHistory)
+// r.Get("/pet/{petID}/similar", ctrl.petController.PetSimilar)
+// r.Get("/user/{username}/pets", ctrl.petController.UserPets)
+// r.Get("/media/*", ctrl.petController.MediaGet)
 // r.Put("/pet/{petID}", ctrl.petController.PetUpdate)
+// r.Patch("/pet/{petID}", ctrl.petController.PetPatch)
 // r.Delete("/pet/{petID}", ctrl.petController.PetDelete)
 
 type IPetController interface {
+	PetList(w http.ResponseWriter, r *http.Request)
 	PetCreate(w http.ResponseWriter, r *http.Request)
 	PetUpdate(w http.ResponseWriter, r *http.Request)
 	PetUpdate_post(w http.ResponseWriter, r *http.Request)
 	PetGetByID(w http.ResponseWriter, r *http.Request)
 	PetGetByStatus(w http.ResponseWriter, r *http.Request)
+	PetGetByTags(w http.ResponseWriter, r *http.Request)
 	PetDelete(w http.ResponseWriter, r *http.Request)
+	PetUploadImage(w http.ResponseWriter, r *http.Request)
+	PetHistory(w http.ResponseWriter, r *http.Request)
+	PetSimilar(w http.ResponseWriter, r *http.Request)
+	PetPatch(w http.ResponseWriter, r *http.Request)
+	PetImport(w http.ResponseWriter, r *http.Request)
+	PetExport(w http.ResponseWriter, r *http.Request)
+	UserPets(w http.ResponseWriter, r *http.Request)
+	MediaGet(w http.ResponseWriter, r *http.Request)
+}
+
+// MedicalSummaries sums up the medical records of a pet for PetGetByID.
+type MedicalSummaries interface {
+	Summary(petID int64, now time.Time) (*models.MedicalSummary, error)
 }
 
 type PetController struct {
 	responder responder.Responder
 	service   service.IPetstoreService
+	records   MedicalSummaries
 }
 
-func NewPetController(responder responder.Responder, service service.IPetstoreService) *PetController {
+// NewPetController returns a controller serving medical summaries from
+// records, which may be nil when there are none to serve.
+func NewPetController(responder responder.Responder, service service.IPetstoreService, records MedicalSummaries) *PetController {
 	return &PetController{
 		responder: responder,
 		service:   service,
+		records:   records,
 	}
 }
 
@@ -49,16 +89,125 @@ func (p *PetController) PetCreate(w http.ResponseWriter, r *http.Request) {
 		p.responder.ErrorBadRequest(w, err)
 		return
 	}
+	if pet == nil {
+		p.responder.ErrorBadRequest(w, errors.New("Invalid request body"))
+		return
+	}
 
-	err = p.service.Create(pet)
+	err = p.service.Create(helpers.ActorFromContext(r.Context()), pet)
 	if err != nil {
-		p.responder.ErrorInternal(w, err)
+		var validationErr *validator.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			p.responder.ErrorValidation(w, validationErr.Errors)
+		case errors.Is(err, service.ErrNoCategory), errors.Is(err, service.ErrNoTag), errors.Is(err, service.ErrInvalidStatus):
+			p.responder.ErrorBadRequest(w, err)
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
 		return
 	}
 
+	w.Header().Set("ETag", petETag(pet))
 	p.responder.OutputJSON(w, pet)
 }
 
+var petSortSafelist = []string{"id", "name", "status", "price", "-id", "-name", "-status", "-price"}
+
+// readPetFilter reads the pet listing filters shared by GET /pet and
+// GET /pet/export.
+func readPetFilter(qs url.Values, v *validator.Validator) models.PetFilter {
+	f := models.PetFilter{
+		Name:       helpers.ReadString(qs, "name", ""),
+		Category:   helpers.ReadString(qs, "category", ""),
+		CategoryID: int64(helpers.ReadInt(qs, "category_id", 0, v)),
+		Tag:        helpers.ReadString(qs, "tag", ""),
+		Statuses:   helpers.ReadCSV(qs, "status", nil),
+		Breed:      helpers.ReadString(qs, "breed", ""),
+		MinPrice:   readBound(qs, "min_price", v),
+		MaxPrice:   readBound(qs, "max_price", v),
+	}
+	for _, status := range f.Statuses {
+		v.Check(validator.PermittedValue(status, models.PetStatuses...), "status", "invalid status value")
+	}
+	v.Check(f.MinPrice == nil || f.MaxPrice == nil || *f.MinPrice <= *f.MaxPrice, "max_price", "must not be less than min_price")
+
+	if minAge := readBound(qs, "min_age", v); minAge != nil {
+		age := int(*minAge)
+		f.MinAge = &age
+	}
+	if maxAge := readBound(qs, "max_age", v); maxAge != nil {
+		age := int(*maxAge)
+		f.MaxAge = &age
+	}
+	v.Check(f.MinAge == nil || f.MaxAge == nil || *f.MinAge <= *f.MaxAge, "max_age", "must not be less than min_age")
+	return f
+}
+
+// readBound reads an optional non-negative integer, nil when key is absent.
+func readBound(qs url.Values, key string, v *validator.Validator) *int64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		v.AddError(key, "must be a non-negative integer value")
+		return nil
+	}
+	return &n
+}
+
+// PetList serves GET /pet. Besides the usual page, page_size and sort
+// parameters it filters on name (prefix), category, category_id, tag, a
+// comma-separated list of statuses, breed, min_price and max_price in minor
+// units, and min_age and max_age in completed years.
+func (p *PetController) PetList(w http.ResponseWriter, r *http.Request) {
+	p.listPets(w, r, "")
+}
+
+// UserPets serves GET /user/{username}/pets, the pets owned by username,
+// with the parameters of GET /pet.
+func (p *PetController) UserPets(w http.ResponseWriter, r *http.Request) {
+	p.listPets(w, r, chi.URLParam(r, "username"))
+}
+
+// listPets lists pets, only those of owner unless it is empty.
+func (p *PetController) listPets(w http.ResponseWriter, r *http.Request, owner string) {
+	var input struct {
+		models.PetFilter
+		filters.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.PetFilter = readPetFilter(qs, v)
+	input.PetFilter.CreatedBy = owner
+
+	input.Filters.Page = helpers.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = helpers.ReadInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = helpers.ReadString(qs, "sort", "id")
+	input.Filters.SortSafelist = petSortSafelist
+	filters.ReadKeyset(qs, &input.Filters, v)
+
+	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
+		p.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	pets, metadata, err := p.service.List(input.PetFilter, input.Filters)
+	if err != nil {
+		p.responder.ErrorInternal(w, err)
+		return
+	}
+	p.responder.OutputJSON(w, map[string]interface{}{"metadata": metadata, "data": pets})
+}
+
+// PetGetByID serves GET /pet/{petID}. With ?include=medical_summary the pet
+// comes with a summary of its medical records.
 func (p *PetController) PetGetByID(w http.ResponseWriter, r *http.Request) {
 	var (
 		err      error
@@ -80,6 +229,25 @@ func (p *PetController) PetGetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the summary changes with the records, not with the pet's version, so a
+	// pet served with it has no ETag
+	if p.records != nil && slices.Contains(helpers.ReadCSV(r.URL.Query(), "include", nil), "medical_summary") {
+		pet.MedicalSummary, err = p.records.Summary(pet.ID, time.Now())
+		if err != nil {
+			p.responder.ErrorInternal(w, err)
+			return
+		}
+		p.responder.OutputJSON(w, pet)
+		return
+	}
+
+	tag := petETag(pet)
+	w.Header().Set("ETag", tag)
+	if notModified(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	p.responder.OutputJSON(w, pet)
 }
 
@@ -92,7 +260,7 @@ func (p *PetController) PetGetByStatus(w http.ResponseWriter, r *http.Request) {
 
 	petStatus = r.URL.Query().Get("status")
 
-	if petStatus != "available" && petStatus != "pending" && petStatus != "sold" {
+	if petStatus != models.PetStatusAvailable && petStatus != models.PetStatusPending && petStatus != models.PetStatusSold {
 		p.responder.ErrorBadRequest(w, errors.New("Invalid request body"))
 		return
 	}
@@ -107,6 +275,32 @@ func (p *PetController) PetGetByStatus(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// PetGetByTags serves /pet/findByTags?tags=a,b. By default a pet matches when
+// it carries any of the tags; match=all requires every one of them.
+func (p *PetController) PetGetByTags(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	tags := helpers.ReadCSV(qs, "tags", nil)
+	if len(tags) == 0 {
+		p.responder.ErrorBadRequest(w, errors.New("At least one tag must be provided"))
+		return
+	}
+
+	match := helpers.ReadString(qs, "match", "any")
+	if match != "any" && match != "all" {
+		p.responder.ErrorBadRequest(w, errors.New("match must be either any or all"))
+		return
+	}
+
+	pets, err := p.service.GetByTags(tags, match == "all")
+	if err != nil {
+		p.responder.ErrorInternal(w, err)
+		return
+	}
+
+	p.responder.OutputJSON(w, pets)
+}
+
 func (p *PetController) PetUpdate(w http.ResponseWriter, r *http.Request) {
 	var pet *models.Pet
 	err := json.NewDecoder(r.Body).Decode(&pet)
@@ -115,17 +309,40 @@ func (p *PetController) PetUpdate(w http.ResponseWriter, r *http.Request) {
 		p.responder.ErrorBadRequest(w, err)
 		return
 	}
+	if pet == nil {
+		p.responder.ErrorBadRequest(w, errors.New("Invalid request body"))
+		return
+	}
 
-	err = p.service.Update_put(pet)
+	version, err := ifMatchVersion(r)
 	if err != nil {
+		p.responder.ErrorPreconditionFailed(w, err)
+		return
+	}
+
+	err = p.service.Update_put(helpers.ActorFromContext(r.Context()), pet, version)
+	if err != nil {
+		var transitionErr *service.StatusTransitionError
+		var validationErr *validator.ValidationError
 		switch {
-		case errors.Is(err, service.ErrEditConflict):
-			p.responder.ErrorInternal(w, errors.New("Error while updating user"))
+		case errors.As(err, &validationErr):
+			p.responder.ErrorValidation(w, validationErr.Errors)
+		case errors.Is(err, service.ErrNoCategory), errors.Is(err, service.ErrNoTag), errors.Is(err, service.ErrInvalidStatus):
+			p.responder.ErrorBadRequest(w, err)
+		case errors.Is(err, service.ErrForbidden):
+			p.responder.ErrorForbidden(w, err)
+		case errors.Is(err, service.ErrVersionMismatch):
+			p.responder.ErrorPreconditionFailed(w, err)
+		case errors.As(err, &transitionErr), errors.Is(err, service.ErrEditConflict):
+			p.responder.ErrorConflict(w, err)
 		case errors.Is(err, service.ErrRecordNotFound):
 			p.responder.ErrorInternal(w, errors.New("User not found"))
+		default:
+			p.responder.ErrorInternal(w, err)
 		}
 		return
 	}
+	w.Header().Set("ETag", petETag(pet))
 	p.responder.OutputJSON(w, pet)
 }
 
@@ -158,13 +375,31 @@ func (p *PetController) PetUpdate_post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = p.service.Update(&name, &status, int64(ID))
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		p.responder.ErrorPreconditionFailed(w, err)
+		return
+	}
+
+	err = p.service.Update(helpers.ActorFromContext(r.Context()), &name, &status, int64(ID), version)
 	if err != nil {
+		var transitionErr *service.StatusTransitionError
+		var validationErr *validator.ValidationError
 		switch {
-		case errors.Is(err, service.ErrEditConflict):
-			p.responder.ErrorInternal(w, errors.New("Error while updating user"))
+		case errors.As(err, &validationErr):
+			p.responder.ErrorValidation(w, validationErr.Errors)
+		case errors.Is(err, service.ErrInvalidStatus):
+			p.responder.ErrorBadRequest(w, err)
+		case errors.Is(err, service.ErrForbidden):
+			p.responder.ErrorForbidden(w, err)
+		case errors.Is(err, service.ErrVersionMismatch):
+			p.responder.ErrorPreconditionFailed(w, err)
+		case errors.As(err, &transitionErr), errors.Is(err, service.ErrEditConflict):
+			p.responder.ErrorConflict(w, err)
 		case errors.Is(err, service.ErrRecordNotFound):
 			p.responder.ErrorInternal(w, errors.New("User not found"))
+		default:
+			p.responder.ErrorInternal(w, err)
 		}
 		return
 	}
@@ -183,10 +418,431 @@ func (p *PetController) PetDelete(w http.ResponseWriter, r *http.Request) {
 		p.responder.ErrorBadRequest(w, err)
 		return
 	}
-	err = p.service.Delete(int64(ID))
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		p.responder.ErrorInternal(w, err)
+		p.responder.ErrorPreconditionFailed(w, err)
+		return
+	}
+	err = p.service.Delete(helpers.ActorFromContext(r.Context()), int64(ID), version)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrForbidden):
+			p.responder.ErrorForbidden(w, err)
+		case errors.Is(err, service.ErrVersionMismatch):
+			p.responder.ErrorPreconditionFailed(w, err)
+		case errors.Is(err, service.ErrEditConflict):
+			p.responder.ErrorConflict(w, err)
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
 		return
 	}
 	p.responder.OutputJSON(w, "Pet deleted successfully")
 }
+
+// maxPatchSize bounds PATCH bodies, which are read into memory whole.
+const maxPatchSize = 1 << 20
+
+const acceptPatch = "application/merge-patch+json, application/json-patch+json"
+
+// PetPatch serves PATCH /pet/{petID}. The body is either a JSON Merge Patch
+// or a JSON Patch against the pet document as returned by GET, picked by its
+// Content-Type.
+func (p *PetController) PetPatch(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		p.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	var format service.PatchFormat
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json":
+		format = service.MergePatch
+	case "application/json-patch+json":
+		format = service.JSONPatch
+	default:
+		w.Header().Set("Accept-Patch", acceptPatch)
+		p.responder.ErrorUnsupportedMediaType(w, fmt.Errorf("Content-Type must be one of %s", acceptPatch))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		p.responder.ErrorPreconditionFailed(w, err)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			p.responder.ErrorTooLarge(w, err)
+			return
+		}
+		p.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	pet, err := p.service.Patch(helpers.ActorFromContext(r.Context()), int64(petID), version, format, patch)
+	if err != nil {
+		var transitionErr *service.StatusTransitionError
+		var validationErr *validator.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			p.responder.ErrorValidation(w, validationErr.Errors)
+		case errors.Is(err, service.ErrRecordNotFound):
+			p.responder.ErrorNotFound(w, err)
+		case errors.Is(err, jsonpatch.ErrMalformed), errors.Is(err, service.ErrNoCategory), errors.Is(err, service.ErrNoTag):
+			p.responder.ErrorBadRequest(w, err)
+		case errors.Is(err, jsonpatch.ErrUnprocessable), errors.Is(err, service.ErrValidation), errors.Is(err, service.ErrInvalidStatus):
+			p.responder.ErrorUnprocessableEntity(w, err)
+		case errors.Is(err, service.ErrForbidden):
+			p.responder.ErrorForbidden(w, err)
+		case errors.Is(err, service.ErrVersionMismatch):
+			p.responder.ErrorPreconditionFailed(w, err)
+		case errors.Is(err, jsonpatch.ErrTestFailed), errors.As(err, &transitionErr), errors.Is(err, service.ErrEditConflict):
+			p.responder.ErrorConflict(w, err)
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", petETag(pet))
+	p.responder.OutputJSON(w, pet)
+}
+
+var exportColumns = []string{"id", "name", "category_id", "category", "status", "tags", "photo_urls", "breed", "birth_date", "sex", "weight_grams", "description", "price", "status_changed_by", "status_changed_at"}
+
+// exportFlushEvery is the number of rows written between flushes, so that
+// clients see the export progress instead of one burst at the end.
+const exportFlushEvery = 100
+
+// PetExport serves GET /pet/export?format=csv|ndjson with the filters and sort
+// of GET /pet but without paging. Rows are written as they come out of the
+// database. In csv, tags and photo urls are joined with | the way
+// POST /pet/import reads them, so an export can be imported again.
+func (p *PetController) PetExport(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	f := readPetFilter(qs, v)
+	sort := filters.Filters{Sort: helpers.ReadString(qs, "sort", "id"), SortSafelist: petSortSafelist}
+	v.Check(validator.PermittedValue(sort.Sort, sort.SortSafelist...), "sort", "invalid sort value")
+
+	format := helpers.ReadString(qs, "format", "csv")
+	v.Check(validator.PermittedValue(format, "csv", "ndjson"), "format", "must be csv or ndjson")
+
+	if !v.Valid() {
+		p.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	var (
+		write func(*models.Pet) error
+		flush func() error
+	)
+	switch format {
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		write = func(pet *models.Pet) error { return enc.Encode(pet) }
+		flush = func() error { return nil }
+	default:
+		w.Header().Set("Content-Type", "text/csv;charset=utf-8")
+		cw := csv.NewWriter(w)
+		header := false
+		write = func(pet *models.Pet) error {
+			if !header {
+				header = true
+				if err := cw.Write(exportColumns); err != nil {
+					return err
+				}
+			}
+			return cw.Write(exportRecord(pet))
+		}
+		flush = func() error {
+			if !header {
+				header = true
+				cw.Write(exportColumns)
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pets.%s"`, format))
+
+	flusher, _ := w.(http.Flusher)
+	rows := 0
+	err := p.service.Export(r.Context(), f, sort, func(pet *models.Pet) error {
+		if err := write(pet); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		if rows == 0 {
+			w.Header().Del("Content-Disposition")
+			p.responder.ErrorInternal(w, err)
+		}
+		// Once rows went out the status line is gone; the truncated body is
+		// all the client gets.
+		return
+	}
+}
+
+func exportRecord(pet *models.Pet) []string {
+	name := ""
+	if pet.Name != nil {
+		name = *pet.Name
+	}
+
+	var categoryID int64
+	category := ""
+	if pet.Category != nil {
+		categoryID, category = pet.Category.ID, pet.Category.Name
+	}
+
+	tags := make([]string, 0, len(pet.Tags))
+	for _, tag := range pet.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	changedAt := ""
+	if pet.StatusChangedAt != nil {
+		changedAt = pet.StatusChangedAt.UTC().Format(time.RFC3339)
+	}
+
+	birthDate := ""
+	if pet.BirthDate != nil {
+		birthDate = pet.BirthDate.String()
+	}
+
+	return []string{
+		strconv.FormatInt(pet.ID, 10),
+		name,
+		strconv.FormatInt(categoryID, 10),
+		category,
+		pet.Status,
+		strings.Join(tags, "|"),
+		strings.Join(pet.PhotoUrls, "|"),
+		pet.Breed,
+		birthDate,
+		pet.Sex,
+		strconv.FormatInt(pet.WeightGrams, 10),
+		pet.Description,
+		strconv.FormatInt(pet.Price, 10),
+		pet.StatusChangedBy,
+		changedAt,
+	}
+}
+
+// maxImportSize bounds POST /pet/import bodies. Rows are streamed, so this
+// only guards against runaway uploads.
+const maxImportSize = 32 << 20
+
+// PetImport serves POST /pet/import. The body is csv (text/csv) or NDJSON
+// (application/x-ndjson); ?format=csv|ndjson overrides the Content-Type.
+// With ?dry_run=true rows are validated without being stored. The response
+// reports every row with its new id or its errors; an import that stopped
+// after storing some rows is still answered with its report, which gives the
+// reason in error.
+func (p *PetController) PetImport(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	format := helpers.ReadString(qs, "format", "")
+	if format == "" {
+		format, _, _ = mime.ParseMediaType(r.Header.Get("Content-Type"))
+	}
+
+	var importFormat service.ImportFormat
+	switch format {
+	case "csv", "text/csv":
+		importFormat = service.ImportCSV
+	case "ndjson", "application/x-ndjson", "application/ndjson":
+		importFormat = service.ImportNDJSON
+	default:
+		p.responder.ErrorUnsupportedMediaType(w, errors.New("body must be text/csv or application/x-ndjson"))
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(helpers.ReadString(qs, "dry_run", "false"))
+	if err != nil {
+		p.responder.ErrorBadRequest(w, errors.New("dry_run must be a boolean value"))
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	report, err := p.service.Import(helpers.ActorFromContext(r.Context()), importFormat, body, dryRun)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			p.responder.ErrorTooLarge(w, err)
+		case errors.Is(err, service.ErrInvalidImport):
+			p.responder.ErrorBadRequest(w, err)
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+
+	p.responder.OutputJSON(w, report)
+}
+
+// PetHistory serves GET /pet/{petID}/history, the pet's recorded changes
+// oldest first. History of deleted pets stays available.
+func (p *PetController) PetHistory(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		p.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	events, err := p.service.History(int64(petID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRecordNotFound):
+			p.responder.ErrorNotFound(w, err)
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+
+	p.responder.OutputJSON(w, events)
+}
+
+// maxSimilarPageSize caps the page size of PetSimilar, which is meant for a
+// short list of suggestions rather than browsing the store.
+const maxSimilarPageSize = 50
+
+// PetSimilar serves GET /pet/{petID}/similar?page=1&page_size=10, the
+// available pets most similar to the given one.
+func (p *PetController) PetSimilar(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		p.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	page := filters.Filters{
+		Page:         helpers.ReadInt(qs, "page", 1, v),
+		PageSize:     helpers.ReadInt(qs, "page_size", 10, v),
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+	v.Check(page.PageSize <= maxSimilarPageSize, "page_size", "must be a maximum of 50")
+	if filters.ValidateFilters(v, page); !v.Valid() {
+		p.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	pets, metadata, err := p.service.Similar(int64(petID), page)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRecordNotFound):
+			p.responder.ErrorNotFound(w, err)
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+	p.responder.OutputJSON(w, map[string]interface{}{"metadata": metadata, "data": pets})
+}
+
+// PetUploadImage accepts a multipart/form-data body and stores its "file"
+// part as a new photo of the pet. The part is streamed to the blob store, so
+// the size limit is enforced by the service rather than by buffering here.
+func (p *PetController) PetUploadImage(w http.ResponseWriter, r *http.Request) {
+	petID, err := strconv.Atoi(chi.URLParam(r, "petID"))
+	if err != nil {
+		p.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		p.responder.ErrorUnsupportedMediaType(w, errors.New("Request body must be multipart/form-data"))
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			p.responder.ErrorBadRequest(w, errors.New("Missing file part"))
+			return
+		}
+		if err != nil {
+			p.responder.ErrorBadRequest(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		pet, err := p.service.UploadImage(helpers.ActorFromContext(r.Context()), int64(petID), part)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrRecordNotFound):
+				p.responder.ErrorNotFound(w, errors.New("Pet not found"))
+			case errors.Is(err, service.ErrForbidden):
+				p.responder.ErrorForbidden(w, err)
+			case errors.Is(err, service.ErrUnsupportedImage):
+				p.responder.ErrorUnsupportedMediaType(w, err)
+			case errors.Is(err, service.ErrImageTooLarge):
+				p.responder.ErrorTooLarge(w, err)
+			default:
+				p.responder.ErrorInternal(w, err)
+			}
+			return
+		}
+
+		p.responder.OutputJSON(w, pet)
+		return
+	}
+}
+
+// MediaGet serves a stored image. Keys are random and never reused, so the
+// response may be cached indefinitely.
+func (p *PetController) MediaGet(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+
+	file, err := p.service.OpenImage(key)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRecordNotFound):
+			p.responder.ErrorNotFound(w, errors.New("Image not found"))
+		default:
+			p.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		p.responder.ErrorInternal(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, key, info.ModTime(), file)
+}
@@ -4,14 +4,27 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"test/internal/infrastructure/blobstore"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
 	"test/internal/infrastructure/responder"
 	"test/internal/models"
+	"test/internal/modules/pet/repository"
 	"test/internal/modules/pet/service"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth/v5"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ptflp/godecoder"
 	"go.uber.org/zap"
@@ -33,26 +46,40 @@ import (
 
 type MockStorage struct {
 	Create_mock      func(pet *models.Pet) error
+	CreateBatch_mock func(pets []*models.Pet) ([]error, error)
 	Update_mock      func(pet *models.Pet) error
 	Update_put_mock  func(pet *models.Pet) error
+	AddPhoto_mock    func(id int64, photo models.Photo) error
 	Delete_mock      func(id int64) error
 	GetByID_mock     func(id int64) (*models.Pet, error)
 	GetByStatus_mock func(status string) ([]models.Pet, error)
+	GetByTags_mock   func(tags []string, matchAll bool) ([]models.Pet, error)
+	GetAll_mock      func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	GetSimilar_mock  func(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	Export_mock      func(f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error
 }
 
-func (m *MockStorage) Create(pet *models.Pet) error {
+func (m *MockStorage) Create(pet *models.Pet, record repository.Recorder) error {
 	return m.Create_mock(pet)
 }
 
-func (m *MockStorage) Update(pet *models.Pet) error {
+func (m *MockStorage) CreateBatch(pets []*models.Pet, record repository.Recorder) ([]error, error) {
+	return m.CreateBatch_mock(pets)
+}
+
+func (m *MockStorage) Update(pet *models.Pet, record repository.Recorder) error {
 	return m.Update_mock(pet)
 }
 
-func (m *MockStorage) Update_put(pet *models.Pet) error {
+func (m *MockStorage) Update_put(pet *models.Pet, record repository.Recorder) error {
 	return m.Update_put_mock(pet)
 }
 
-func (m *MockStorage) Delete(id int64) error {
+func (m *MockStorage) AddPhoto(id int64, photo models.Photo) error {
+	return m.AddPhoto_mock(id, photo)
+}
+
+func (m *MockStorage) Delete(id int64, version int, record repository.Recorder) error {
 	return m.Delete_mock(id)
 }
 
@@ -64,6 +91,43 @@ func (m *MockStorage) GetByStatus(status string) ([]models.Pet, error) {
 	return m.GetByStatus_mock(status)
 }
 
+func (m *MockStorage) GetByTags(tags []string, matchAll bool) ([]models.Pet, error) {
+	return m.GetByTags_mock(tags, matchAll)
+}
+
+func (m *MockStorage) GetAll(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	return m.GetAll_mock(f, filters)
+}
+
+func (m *MockStorage) GetSimilar(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	return m.GetSimilar_mock(pet, filters)
+}
+
+func (m *MockStorage) Export(ctx context.Context, f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error {
+	return m.Export_mock(f, sort, fn)
+}
+
+// MockEventStorage keeps recorded events in memory.
+type MockEventStorage struct {
+	Events []models.PetEvent
+}
+
+func (m *MockEventStorage) Add(event *models.PetEvent) error {
+	event.ID = int64(len(m.Events) + 1)
+	m.Events = append(m.Events, *event)
+	return nil
+}
+
+func (m *MockEventStorage) GetByPet(petID int64) ([]models.PetEvent, error) {
+	events := []models.PetEvent{}
+	for _, event := range m.Events {
+		if event.PetID == petID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
 func TestCreatePetHandler(t *testing.T) {
 
 	t.Run("happy path", func(t *testing.T) {
@@ -75,9 +139,9 @@ func TestCreatePetHandler(t *testing.T) {
   },
   "name": "CAT",
   "photoUrls": [
-    "string",
-    "PIC1",
-    "PIC2"
+    "https://example.com/string.png",
+    "https://example.com/PIC1.png",
+    "/media/PIC2.png"
   ],
   "tags": [
     {
@@ -106,9 +170,9 @@ func TestCreatePetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetCreate(w, req)
 
 		if w.Code != http.StatusOK {
@@ -145,9 +209,9 @@ func TestCreatePetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetCreate(w, req)
 
 		if w.Code != http.StatusBadRequest {
@@ -165,9 +229,9 @@ func TestCreatePetHandler(t *testing.T) {
   },
   "name": "CAT",
   "photoUrls": [
-    "string",
-    "PIC1",
-    "PIC2"
+    "https://example.com/string.png",
+    "https://example.com/PIC1.png",
+    "/media/PIC2.png"
   ],
   "tags": [
     {
@@ -196,9 +260,9 @@ func TestCreatePetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetCreate(w, req)
 
 		if w.Code != http.StatusInternalServerError {
@@ -207,6 +271,43 @@ func TestCreatePetHandler(t *testing.T) {
 
 	})
 
+	t.Run("validation", func(t *testing.T) {
+		mock := &MockStorage{
+			Create_mock: func(pet *models.Pet) error {
+				t.Fatal("invalid pet reached the storage")
+				return nil
+			},
+		}
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		controller := NewPetController(responder.NewResponder(godecoder.NewDecoder(jsoniter.Config{}), logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+
+		w := httptest.NewRecorder()
+		controller.PetCreate(w, httptest.NewRequest("POST", "/pet", strings.NewReader(`{"name": "", "photoUrls": ["PIC1"], "status": "lost"}`)))
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status code %d but got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+		var resp struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := jsoniter.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"name", "category", "photoUrls", "status"} {
+			if resp.Data[field] == "" {
+				t.Errorf("expected an error for %s got %v", field, resp.Data)
+			}
+		}
+
+		w = httptest.NewRecorder()
+		controller.PetCreate(w, httptest.NewRequest("POST", "/pet", strings.NewReader(`null`)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d for a null body but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
 }
 
 func TestGetByIDPetHandler(t *testing.T) {
@@ -233,14 +334,14 @@ func TestGetByIDPetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 		chiCtx := chi.NewRouteContext()
 
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 
 		chiCtx.URLParams.Add("petID", "1")
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetGetByID(w, req)
 
 		if w.Code != http.StatusOK {
@@ -271,14 +372,14 @@ func TestGetByIDPetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 		chiCtx := chi.NewRouteContext()
 
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 
 		chiCtx.URLParams.Add("id", "OIUUOU")
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetGetByID(w, req)
 
 		if w.Code != http.StatusBadRequest {
@@ -309,14 +410,14 @@ func TestGetByIDPetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 		chiCtx := chi.NewRouteContext()
 
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 
 		chiCtx.URLParams.Add("petID", "1")
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetGetByID(w, req)
 
 		if w.Code != http.StatusInternalServerError {
@@ -352,9 +453,9 @@ func TestGetByStatusPetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetGetByStatus(w, req)
 
 		if w.Code != http.StatusOK {
@@ -386,9 +487,9 @@ func TestGetByStatusPetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetGetByStatus(w, req)
 
 		if w.Code != http.StatusBadRequest {
@@ -420,9 +521,9 @@ func TestGetByStatusPetHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
 		controller.PetGetByStatus(w, req)
 
 		if w.Code != http.StatusInternalServerError {
@@ -443,7 +544,8 @@ func TestPetDeleteHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		mock := &MockStorage{
-			Delete_mock: func(id int64) error { return nil },
+			GetByID_mock: func(id int64) (*models.Pet, error) { return &models.Pet{ID: id, CreatedBy: "clerk"}, nil },
+			Delete_mock:  func(id int64) error { return nil },
 		}
 
 		logger, err := zap.NewProduction()
@@ -457,14 +559,14 @@ func TestPetDeleteHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 		chiCtx := chi.NewRouteContext()
 
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 
 		chiCtx.URLParams.Add("petID", "1")
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
-		controller.PetDelete(w, req)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.PetDelete(w, asUser(req, "clerk", models.RoleUser))
 
 		if w.Code != http.StatusOK {
 			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
@@ -480,7 +582,8 @@ func TestPetDeleteHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		mock := &MockStorage{
-			Delete_mock: func(id int64) error { return nil },
+			GetByID_mock: func(id int64) (*models.Pet, error) { return &models.Pet{ID: id, CreatedBy: "clerk"}, nil },
+			Delete_mock:  func(id int64) error { return nil },
 		}
 
 		logger, err := zap.NewProduction()
@@ -494,14 +597,14 @@ func TestPetDeleteHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 		chiCtx := chi.NewRouteContext()
 
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 
 		chiCtx.URLParams.Add("id", "GDGD")
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
-		controller.PetDelete(w, req)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.PetDelete(w, asUser(req, "clerk", models.RoleUser))
 
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, w.Code)
@@ -509,6 +612,40 @@ func TestPetDeleteHandler(t *testing.T) {
 
 	})
 
+	t.Run("someone else's pet", func(t *testing.T) {
+		mock := &MockStorage{
+			GetByID_mock: func(id int64) (*models.Pet, error) { return &models.Pet{ID: id, CreatedBy: "alice"}, nil },
+			Delete_mock: func(id int64) error {
+				t.Error("pet deleted by someone other than its owner")
+				return nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+		controller := NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+
+		for role, code := range map[string]int{models.RoleUser: http.StatusForbidden, models.RoleStaff: http.StatusOK} {
+			if role == models.RoleStaff {
+				mock.Delete_mock = func(id int64) error { return nil }
+			}
+			req := httptest.NewRequest("DELETE", "/pet/1", nil)
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("petID", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+
+			w := httptest.NewRecorder()
+			controller.PetDelete(w, asUser(req, "bob", role))
+
+			if w.Code != code {
+				t.Errorf("%s: expected status code %d but got %d", role, code, w.Code)
+			}
+		}
+	})
+
 	t.Run("INTERNAL SERVER ERROR", func(t *testing.T) {
 
 		req := httptest.NewRequest("DELETE", "/pet/", nil)
@@ -517,7 +654,8 @@ func TestPetDeleteHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		mock := &MockStorage{
-			Delete_mock: func(id int64) error { return errors.New("some error") },
+			GetByID_mock: func(id int64) (*models.Pet, error) { return &models.Pet{ID: id, CreatedBy: "clerk"}, nil },
+			Delete_mock:  func(id int64) error { return errors.New("some error") },
 		}
 
 		logger, err := zap.NewProduction()
@@ -531,14 +669,14 @@ func TestPetDeleteHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewPetService(mock)
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
 		chiCtx := chi.NewRouteContext()
 
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 
 		chiCtx.URLParams.Add("petID", "1")
-		controller := NewPetController(responder.NewResponder(decoder, logger), service)
-		controller.PetDelete(w, req)
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.PetDelete(w, asUser(req, "clerk", models.RoleUser))
 
 		if w.Code != http.StatusInternalServerError {
 			t.Errorf("expected status code %d but got %d", http.StatusInternalServerError, w.Code)
@@ -547,3 +685,1068 @@ func TestPetDeleteHandler(t *testing.T) {
 	})
 
 }
+
+func TestGetByTagsPetHandler(t *testing.T) {
+
+	t.Run("happy path", func(t *testing.T) {
+
+		req := httptest.NewRequest("GET", "/pet/findByTags", nil)
+		req.URL.RawQuery = "tags=friendly,small&match=all"
+
+		w := httptest.NewRecorder()
+
+		var gotTags []string
+		var gotAll bool
+		mock := &MockStorage{
+			GetByTags_mock: func(tags []string, matchAll bool) ([]models.Pet, error) {
+				gotTags, gotAll = tags, matchAll
+				return []models.Pet{}, nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
+
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.PetGetByTags(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if len(gotTags) != 2 || !gotAll {
+			t.Errorf("expected all-of lookup for 2 tags, got %v %v", gotTags, gotAll)
+		}
+
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+
+		for _, query := range []string{"", "tags=friendly&match=some"} {
+			req := httptest.NewRequest("GET", "/pet/findByTags", nil)
+			req.URL.RawQuery = query
+
+			w := httptest.NewRecorder()
+
+			logger, err := zap.NewProduction()
+			if err != nil {
+				panic(err)
+			}
+			decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+			service := service.NewPetService(&MockStorage{}, &MockEventStorage{}, service.ImageConfig{})
+
+			controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+			controller.PetGetByTags(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%q: expected status code %d but got %d", query, http.StatusBadRequest, w.Code)
+			}
+		}
+
+	})
+
+}
+
+func TestListPetHandler(t *testing.T) {
+
+	t.Run("happy path", func(t *testing.T) {
+
+		req := httptest.NewRequest("GET", "/pet", nil)
+		req.URL.RawQuery = "name=re&tag=friendly&status=available,pending&sort=-name&page_size=5"
+
+		w := httptest.NewRecorder()
+
+		var gotFilter models.PetFilter
+		var gotFilters filter.Filters
+		mock := &MockStorage{
+			GetAll_mock: func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+				gotFilter, gotFilters = f, filters
+				return []models.Pet{}, filter.Metadata{}, nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
+
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.PetList(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if gotFilter.Name != "re" || gotFilter.Tag != "friendly" || len(gotFilter.Statuses) != 2 {
+			t.Errorf("unexpected pet filter %+v", gotFilter)
+		}
+		if gotFilters.Sort != "-name" || gotFilters.PageSize != 5 {
+			t.Errorf("unexpected filters %+v", gotFilters)
+		}
+		if gotFilter.MinPrice != nil || gotFilter.MaxPrice != nil || gotFilter.MinAge != nil || gotFilter.MaxAge != nil {
+			t.Errorf("expected unbounded price and age got %+v", gotFilter)
+		}
+
+	})
+
+	t.Run("profile filters", func(t *testing.T) {
+
+		req := httptest.NewRequest("GET", "/pet", nil)
+		req.URL.RawQuery = "breed=beagle&min_price=0&max_price=3000000000&min_age=1&max_age=3&sort=-price"
+
+		w := httptest.NewRecorder()
+
+		var gotFilter models.PetFilter
+		mock := &MockStorage{
+			GetAll_mock: func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+				gotFilter = f
+				return []models.Pet{}, filter.Metadata{}, nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
+
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.PetList(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if gotFilter.Breed != "beagle" || gotFilter.MinPrice == nil || *gotFilter.MinPrice != 0 || gotFilter.MaxPrice == nil || *gotFilter.MaxPrice != 3_000_000_000 {
+			t.Errorf("unexpected price filter %+v", gotFilter)
+		}
+		if gotFilter.MinAge == nil || *gotFilter.MinAge != 1 || gotFilter.MaxAge == nil || *gotFilter.MaxAge != 3 {
+			t.Errorf("unexpected age filter %+v", gotFilter)
+		}
+
+	})
+
+	t.Run("user pets", func(t *testing.T) {
+
+		req := httptest.NewRequest("GET", "/user/alice/pets", nil)
+		req.URL.RawQuery = "name=re&breed=beagle"
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("username", "alice")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+
+		w := httptest.NewRecorder()
+
+		var gotFilter models.PetFilter
+		mock := &MockStorage{
+			GetAll_mock: func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+				gotFilter = f
+				return []models.Pet{}, filter.Metadata{}, nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{})
+
+		controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+		controller.UserPets(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if gotFilter.CreatedBy != "alice" || gotFilter.Name != "re" || gotFilter.Breed != "beagle" {
+			t.Errorf("unexpected pet filter %+v", gotFilter)
+		}
+
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+
+		for _, query := range []string{"status=lost", "sort=category", "page_size=1000",
+			"min_price=-1", "max_price=cheap", "min_price=10&max_price=5", "min_age=4&max_age=2"} {
+			req := httptest.NewRequest("GET", "/pet", nil)
+			req.URL.RawQuery = query
+
+			w := httptest.NewRecorder()
+
+			logger, err := zap.NewProduction()
+			if err != nil {
+				panic(err)
+			}
+			decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+			service := service.NewPetService(&MockStorage{}, &MockEventStorage{}, service.ImageConfig{})
+
+			controller := NewPetController(responder.NewResponder(decoder, logger), service, nil)
+			controller.PetList(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%q: expected status code %d but got %d", query, http.StatusBadRequest, w.Code)
+			}
+		}
+
+	})
+
+}
+
+func newUploadRequest(t *testing.T, petID string, field string, content []byte) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile(field, "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/pet/"+petID+"/uploadImage", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("petID", petID)
+	return asUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)), "clerk", models.RoleUser)
+}
+
+// asUser attaches a verified token for username to req, as jwtauth.Verifier
+// does for requests coming through the router.
+func asUser(req *http.Request, username, role string) *http.Request {
+	token, err := helpers.TokenAuth.Decode(helpers.GenerateToken(username, role))
+	return req.WithContext(jwtauth.NewContext(req.Context(), token, err))
+}
+
+func TestPetUploadImageHandler(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, src); err != nil {
+		t.Fatal(err)
+	}
+	img := buf.Bytes()
+	broken := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+	newController := func(store blobstore.BlobStore, mock *MockStorage) *PetController {
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+		service := service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{
+			Store:          store,
+			MaxSize:        int64(len(img)) + 64,
+			URLPrefix:      "/media/",
+			ThumbnailSizes: []int{160, 64},
+		})
+
+		return NewPetController(responder.NewResponder(decoder, logger), service, nil)
+	}
+
+	t.Run("happy path", func(t *testing.T) {
+		store := blobstore.NewLocalStore(t.TempDir())
+		var photo models.Photo
+		mock := &MockStorage{
+			GetByID_mock: func(id int64) (*models.Pet, error) {
+				return &models.Pet{ID: id, CreatedBy: "clerk", PhotoUrls: []string{photo.URL}, Photos: models.Photos{photo}}, nil
+			},
+			AddPhoto_mock: func(id int64, p models.Photo) error {
+				photo = p
+				return nil
+			},
+		}
+
+		w := httptest.NewRecorder()
+		newController(store, mock).PetUploadImage(w, newUploadRequest(t, "1", "file", img))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		stored := photo.URL
+		if !strings.HasPrefix(stored, "/media/pets/1/") || !strings.HasSuffix(stored, ".png") {
+			t.Fatalf("unexpected photo url %q", stored)
+		}
+
+		req := httptest.NewRequest("GET", stored, nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("*", strings.TrimPrefix(stored, "/media/"))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+
+		w = httptest.NewRecorder()
+		newController(store, mock).MediaGet(w, req)
+
+		if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), img) {
+			t.Errorf("expected stored image to be served, got %d", w.Code)
+		}
+		if w.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("expected image/png got %s", w.Header().Get("Content-Type"))
+		}
+
+		want := []models.PhotoVariant{{Size: 64, Width: 64, Height: 42}, {Size: 160, Width: 160, Height: 106}}
+		if len(photo.Variants) != len(want) {
+			t.Fatalf("expected %d variants got %d", len(want), len(photo.Variants))
+		}
+		for i, variant := range photo.Variants {
+			if variant.Size != want[i].Size || variant.Width != want[i].Width || variant.Height != want[i].Height {
+				t.Errorf("unexpected variant %+v", variant)
+			}
+			thumb, err := store.Open(strings.TrimPrefix(variant.URL, "/media/"))
+			if err != nil {
+				t.Fatalf("variant %q not stored: %v", variant.URL, err)
+			}
+			cfg, err := png.DecodeConfig(thumb)
+			thumb.Close()
+			if err != nil || cfg.Width != variant.Width || cfg.Height != variant.Height {
+				t.Errorf("stored variant %q doesn't match %+v", variant.URL, variant)
+			}
+		}
+	})
+
+	t.Run("rejected uploads", func(t *testing.T) {
+		mock := &MockStorage{
+			GetByID_mock:  func(id int64) (*models.Pet, error) { return &models.Pet{ID: id, CreatedBy: "clerk"}, nil },
+			AddPhoto_mock: func(id int64, photo models.Photo) error { return nil },
+		}
+
+		tests := []struct {
+			name    string
+			field   string
+			content []byte
+			code    int
+		}{
+			{"not an image", "file", []byte("hello, world"), http.StatusUnsupportedMediaType},
+			{"undecodable", "file", broken, http.StatusUnsupportedMediaType},
+			{"too large", "file", append(img[:len(img):len(img)], bytes.Repeat([]byte{1}, 256)...), http.StatusRequestEntityTooLarge},
+			{"missing file", "image", img, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				w := httptest.NewRecorder()
+				newController(blobstore.NewLocalStore(dir), mock).PetUploadImage(w, newUploadRequest(t, "1", tt.field, tt.content))
+
+				if w.Code != tt.code {
+					t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+				}
+				if entries, _ := os.ReadDir(filepath.Join(dir, "pets", "1")); len(entries) != 0 {
+					t.Errorf("expected rejected upload to be removed, found %d files", len(entries))
+				}
+			})
+		}
+	})
+
+	t.Run("missing pet", func(t *testing.T) {
+		mock := &MockStorage{
+			GetByID_mock: func(id int64) (*models.Pet, error) { return nil, errors.New("not found") },
+		}
+
+		w := httptest.NewRecorder()
+		newController(blobstore.NewLocalStore(t.TempDir()), mock).PetUploadImage(w, newUploadRequest(t, "1", "file", img))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestPetUpdateStatusHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		role string
+		code int
+	}{
+		{"user can't unsell", models.RoleUser, http.StatusConflict},
+		{"admin override", models.RoleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *models.Pet
+			mock := &MockStorage{
+				GetByID_mock: func(id int64) (*models.Pet, error) {
+					name := "CAT"
+					return &models.Pet{ID: id, Name: &name, Category: &models.Category{ID: 1}, Status: models.PetStatusSold, CreatedBy: "clerk"}, nil
+				},
+				Update_mock: func(pet *models.Pet) error {
+					stored = pet
+					return nil
+				},
+			}
+
+			logger, err := zap.NewProduction()
+			if err != nil {
+				panic(err)
+			}
+			decoder := godecoder.NewDecoder(jsoniter.Config{})
+			controller := NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+
+			form := url.Values{"name": {"CAT"}, "status": {models.PetStatusAvailable}}
+			req := httptest.NewRequest("POST", "/pet/1", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer "+helpers.GenerateToken("clerk", tt.role))
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("petID", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+
+			w := httptest.NewRecorder()
+			jwtauth.Verifier(helpers.TokenAuth)(http.HandlerFunc(controller.PetUpdate_post)).ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Fatalf("expected status code %d but got %d", tt.code, w.Code)
+			}
+			if tt.code == http.StatusOK && (stored == nil || stored.StatusChangedBy != "clerk") {
+				t.Errorf("expected transition to be recorded for clerk, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestPetHistoryHandler(t *testing.T) {
+	newRequest := func(petID string) *http.Request {
+		req := httptest.NewRequest("GET", "/pet/"+petID+"/history", nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("petID", petID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+	events := &MockEventStorage{}
+	events.Add(&models.PetEvent{PetID: 1, Action: models.PetEventDeleted, Actor: "clerk"})
+	mock := &MockStorage{
+		GetByID_mock: func(id int64) (*models.Pet, error) { return nil, errors.New("not found") },
+	}
+	controller := NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, events, service.ImageConfig{}), nil)
+
+	t.Run("deleted pet", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		controller.PetHistory(w, newRequest("1"))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		var got []models.PetEvent
+		if err := jsoniter.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Actor != "clerk" {
+			t.Errorf("unexpected history %s", w.Body.String())
+		}
+	})
+
+	t.Run("unknown pet", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		controller.PetHistory(w, newRequest("2"))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestPetSimilarHandler(t *testing.T) {
+	newRequest := func(petID, query string) *http.Request {
+		req := httptest.NewRequest("GET", "/pet/"+petID+"/similar?"+query, nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("petID", petID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+	var gotPet *models.Pet
+	var gotFilters filter.Filters
+	mock := &MockStorage{
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			if id != 1 {
+				return nil, errors.New("not found")
+			}
+			return &models.Pet{ID: 1}, nil
+		},
+		GetSimilar_mock: func(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+			gotPet, gotFilters = pet, filters
+			return []models.Pet{{ID: 2}}, filter.CalculateMetadata(1, filters.Page, filters.PageSize), nil
+		},
+	}
+	controller := NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+
+	t.Run("happy path", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		controller.PetSimilar(w, newRequest("1", ""))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if gotPet == nil || gotPet.ID != 1 || gotFilters.Page != 1 || gotFilters.PageSize != 10 {
+			t.Errorf("unexpected pet %+v or filters %+v", gotPet, gotFilters)
+		}
+		var got struct {
+			Metadata filter.Metadata `json:"metadata"`
+			Data     []models.Pet    `json:"data"`
+		}
+		if err := jsoniter.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Data) != 1 || got.Data[0].ID != 2 || got.Metadata.TotalRecords != 1 {
+			t.Errorf("unexpected similar pets %s", w.Body.String())
+		}
+	})
+
+	tests := []struct {
+		name  string
+		petID string
+		query string
+		code  int
+	}{
+		{"unknown pet", "2", "", http.StatusNotFound},
+		{"bad pet id", "x", "", http.StatusBadRequest},
+		{"page too large", "1", "page_size=51", http.StatusBadRequest},
+		{"bad page", "1", "page=0", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			controller.PetSimilar(w, newRequest(tt.petID, tt.query))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestPetConditionalRequests(t *testing.T) {
+	newController := func(mock *MockStorage) *PetController {
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+		return NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+	}
+	withPetID := func(req *http.Request) *http.Request {
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("petID", "1")
+		return asUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)), "clerk", models.RoleUser)
+	}
+	newMock := func() *MockStorage {
+		return &MockStorage{
+			GetByID_mock: func(id int64) (*models.Pet, error) {
+				name := "CAT"
+				return &models.Pet{ID: id, Name: &name, Category: &models.Category{ID: 1}, PhotoUrls: []string{"/media/cat.png"}, Status: models.PetStatusAvailable, CreatedBy: "clerk", Version: 3}, nil
+			},
+			Update_mock:     func(pet *models.Pet) error { pet.Version++; return nil },
+			Update_put_mock: func(pet *models.Pet) error { pet.Version++; return nil },
+			Delete_mock:     func(id int64) error { return nil },
+		}
+	}
+
+	t.Run("GET returns ETag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newController(newMock()).PetGetByID(w, withPetID(httptest.NewRequest("GET", "/pet/1", nil)))
+
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+			t.Errorf("expected 200 with ETag \"3\", got %d %q", w.Code, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("GET If-None-Match", func(t *testing.T) {
+		for header, code := range map[string]int{`"3"`: http.StatusNotModified, `W/"3"`: http.StatusNotModified, `"1", "3"`: http.StatusNotModified, `"2"`: http.StatusOK} {
+			req := httptest.NewRequest("GET", "/pet/1", nil)
+			req.Header.Set("If-None-Match", header)
+			w := httptest.NewRecorder()
+			newController(newMock()).PetGetByID(w, withPetID(req))
+
+			if w.Code != code {
+				t.Errorf("If-None-Match %s: expected %d got %d", header, code, w.Code)
+			}
+			if code == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("If-None-Match %s: expected empty body", header)
+			}
+		}
+	})
+
+	t.Run("GET favorited", func(t *testing.T) {
+		mock := newMock()
+		getByID := mock.GetByID_mock
+		mock.GetByID_mock = func(id int64) (*models.Pet, error) {
+			pet, err := getByID(id)
+			pet.FavoriteCount = 2
+			return pet, err
+		}
+		req := httptest.NewRequest("GET", "/pet/1", nil)
+		req.Header.Set("If-None-Match", `"3"`)
+		w := httptest.NewRecorder()
+		newController(mock).PetGetByID(w, withPetID(req))
+
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `W/"3-2"` {
+			t.Errorf("expected 200 with a weak ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+		}
+
+		for _, header := range []string{`W/"3-2"`, `"3-2"`} {
+			req = httptest.NewRequest("GET", "/pet/1", nil)
+			req.Header.Set("If-None-Match", header)
+			w = httptest.NewRecorder()
+			newController(mock).PetGetByID(w, withPetID(req))
+
+			if w.Code != http.StatusNotModified {
+				t.Errorf("If-None-Match %q: expected 304 got %d", header, w.Code)
+			}
+		}
+	})
+
+	t.Run("PUT If-Match", func(t *testing.T) {
+		for header, code := range map[string]int{"": http.StatusOK, `"3"`: http.StatusOK, "*": http.StatusOK, `"2"`: http.StatusPreconditionFailed, "garbage": http.StatusPreconditionFailed} {
+			req := httptest.NewRequest("PUT", "/pet", strings.NewReader(`{"id": 1, "name": "DOG", "category": {"id": 1}, "photoUrls": ["/media/dog.png"], "status": "available"}`))
+			if header != "" {
+				req.Header.Set("If-Match", header)
+			}
+			w := httptest.NewRecorder()
+			newController(newMock()).PetUpdate(w, asUser(req, "clerk", models.RoleUser))
+
+			if w.Code != code {
+				t.Errorf("If-Match %q: expected %d got %d", header, code, w.Code)
+			}
+			if code == http.StatusOK && w.Header().Get("ETag") != `"4"` {
+				t.Errorf("If-Match %q: expected new ETag \"4\" got %q", header, w.Header().Get("ETag"))
+			}
+		}
+	})
+
+	t.Run("POST form If-Match", func(t *testing.T) {
+		form := url.Values{"name": {"DOG"}, "status": {models.PetStatusPending}}
+		req := httptest.NewRequest("POST", "/pet/1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("If-Match", `"2"`)
+		w := httptest.NewRecorder()
+		newController(newMock()).PetUpdate_post(w, withPetID(req))
+
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected %d got %d", http.StatusPreconditionFailed, w.Code)
+		}
+	})
+
+	t.Run("DELETE If-Match", func(t *testing.T) {
+		for header, code := range map[string]int{`"3"`: http.StatusOK, `"2"`: http.StatusPreconditionFailed} {
+			req := httptest.NewRequest("DELETE", "/pet/1", nil)
+			req.Header.Set("If-Match", header)
+			w := httptest.NewRecorder()
+			newController(newMock()).PetDelete(w, withPetID(req))
+
+			if w.Code != code {
+				t.Errorf("If-Match %s: expected %d got %d", header, code, w.Code)
+			}
+		}
+	})
+
+	t.Run("concurrent write", func(t *testing.T) {
+		mock := newMock()
+		mock.Update_put_mock = func(pet *models.Pet) error { return errors.New("edit conflict") }
+		req := httptest.NewRequest("PUT", "/pet", strings.NewReader(`{"id": 1, "name": "DOG", "category": {"id": 1}, "photoUrls": ["/media/dog.png"]}`))
+		w := httptest.NewRecorder()
+		newController(mock).PetUpdate(w, asUser(req, "clerk", models.RoleUser))
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected %d got %d", http.StatusConflict, w.Code)
+		}
+	})
+}
+
+func TestPetPatchHandler(t *testing.T) {
+	newRequest := func(contentType, body string) *http.Request {
+		req := httptest.NewRequest("PATCH", "/pet/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("petID", "1")
+		return asUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)), "clerk", models.RoleUser)
+	}
+	newController := func() (*PetController, **models.Pet) {
+		name := "CAT"
+		stored := &models.Pet{
+			ID:        1,
+			Name:      &name,
+			Category:  &models.Category{ID: 1, Name: "cats"},
+			Status:    models.PetStatusAvailable,
+			PhotoUrls: []string{"/img/a.png"},
+			Tags:      []*models.Tag{{ID: 1, Name: "fluffy"}},
+			CreatedBy: "clerk",
+			Version:   2,
+		}
+		mock := &MockStorage{
+			GetByID_mock: func(id int64) (*models.Pet, error) {
+				pet := *stored
+				return &pet, nil
+			},
+			Update_put_mock: func(pet *models.Pet) error {
+				pet.Version++
+				stored = pet
+				return nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+		return NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil), &stored
+	}
+
+	t.Run("merge patch", func(t *testing.T) {
+		controller, stored := newController()
+		w := httptest.NewRecorder()
+		controller.PetPatch(w, newRequest("application/merge-patch+json", `{"name": "DOG", "category": {"id": 2, "name": null}, "tags": [{"name": "loud"}]}`))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		pet := *stored
+		if *pet.Name != "DOG" || pet.Category.ID != 2 || len(pet.Tags) != 1 || pet.Tags[0].Name != "loud" || len(pet.PhotoUrls) != 1 {
+			t.Errorf("unexpected pet after patch %+v", pet)
+		}
+		if w.Header().Get("ETag") != `"3"` {
+			t.Errorf("expected ETag \"3\" got %q", w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("json patch", func(t *testing.T) {
+		controller, stored := newController()
+		w := httptest.NewRecorder()
+		controller.PetPatch(w, newRequest("application/json-patch+json", `[
+			{"op": "test", "path": "/status", "value": "available"},
+			{"op": "replace", "path": "/status", "value": "pending"},
+			{"op": "add", "path": "/photoUrls/-", "value": "/img/b.png"},
+			{"op": "remove", "path": "/tags/0"}
+		]`))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		pet := *stored
+		if pet.Status != models.PetStatusPending || len(pet.PhotoUrls) != 2 || len(pet.Tags) != 0 {
+			t.Errorf("unexpected pet after patch %+v", pet)
+		}
+	})
+
+	t.Run("renamed category and tag", func(t *testing.T) {
+		for _, tt := range []struct {
+			contentType string
+			body        string
+		}{
+			{"application/merge-patch+json", `{"category": {"name": "dogs"}, "tags": [{"id": 1, "name": "loud"}]}`},
+			{"application/json-patch+json", `[
+				{"op": "replace", "path": "/category/name", "value": "dogs"},
+				{"op": "replace", "path": "/tags/0/name", "value": "loud"}
+			]`},
+		} {
+			controller, stored := newController()
+			w := httptest.NewRecorder()
+			controller.PetPatch(w, newRequest(tt.contentType, tt.body))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			pet := *stored
+			if pet.Category.ID != 0 || pet.Category.Name != "dogs" || pet.Tags[0].ID != 0 || pet.Tags[0].Name != "loud" {
+				t.Errorf("%s: expected the category and tag to be looked up by name, got %+v %+v", tt.contentType, pet.Category, pet.Tags[0])
+			}
+		}
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"unsupported content type", "application/json", `{"name": "DOG"}`, http.StatusUnsupportedMediaType},
+		{"malformed patch", "application/json-patch+json", `{"op": "add"}`, http.StatusBadRequest},
+		{"missing path", "application/json-patch+json", `[{"op": "replace", "path": "/nope", "value": 1}]`, http.StatusUnprocessableEntity},
+		{"failed test", "application/json-patch+json", `[{"op": "test", "path": "/name", "value": "DOG"}]`, http.StatusConflict},
+		{"invalid result", "application/merge-patch+json", `{"name": ""}`, http.StatusUnprocessableEntity},
+		{"changed id", "application/merge-patch+json", `{"id": 5}`, http.StatusUnprocessableEntity},
+		{"wrong type", "application/merge-patch+json", `{"name": 5}`, http.StatusUnprocessableEntity},
+		{"unknown field", "application/merge-patch+json", `{"colour": "black"}`, http.StatusUnprocessableEntity},
+		{"unknown status", "application/merge-patch+json", `{"status": "lost"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller, stored := newController()
+			before := *stored
+			w := httptest.NewRecorder()
+			controller.PetPatch(w, newRequest(tt.contentType, tt.body))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if *stored != before {
+				t.Errorf("expected rejected patch not to be stored")
+			}
+		})
+	}
+}
+
+func TestPetExportImport(t *testing.T) {
+	name := "rex"
+	changedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	exported := &models.Pet{ID: 7, Name: &name, Category: &models.Category{ID: 2, Name: "dogs"}, Status: "sold",
+		Tags: []*models.Tag{{ID: 1, Name: "big"}, {ID: 2, Name: "friendly"}}, PhotoUrls: []string{"/img/a.png", "/img/b.png"},
+		Breed: "beagle", BirthDate: &models.Date{Time: time.Date(2020, 5, 17, 0, 0, 0, 0, time.UTC)}, Sex: "male",
+		WeightGrams: 9500, Description: "loves, \"quotes\"", Price: 25000, StatusChangedBy: "root", StatusChangedAt: &changedAt}
+
+	var imported []*models.Pet
+	mock := &MockStorage{
+		Export_mock: func(f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error {
+			return fn(exported)
+		},
+		CreateBatch_mock: func(pets []*models.Pet) ([]error, error) {
+			imported = append(imported, pets...)
+			return make([]error, len(pets)), nil
+		},
+	}
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{})
+	controller := NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+
+	for _, format := range []string{"csv", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			imported = nil
+			w := httptest.NewRecorder()
+			controller.PetExport(w, httptest.NewRequest("GET", "/pet/export?format="+format, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("export: expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			req := asUser(httptest.NewRequest("POST", "/pet/import", w.Body), "clerk", models.RoleUser)
+			req.Header.Set("Content-Type", w.Header().Get("Content-Type"))
+			w = httptest.NewRecorder()
+			controller.PetImport(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("import: expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if len(imported) != 1 {
+				t.Fatalf("expected the exported pet to be imported, got %s", w.Body.String())
+			}
+
+			pet := imported[0]
+			if *pet.Name != "rex" || pet.Category.ID != 2 || pet.Status != "sold" || len(pet.Tags) != 2 || pet.Tags[1].Name != "friendly" ||
+				len(pet.PhotoUrls) != 2 || pet.Breed != "beagle" || pet.BirthDate.String() != "2020-05-17" || pet.Sex != "male" ||
+				pet.WeightGrams != 9500 || pet.Description != exported.Description || pet.Price != 25000 {
+				t.Errorf("unexpected imported pet %+v", pet)
+			}
+			if pet.StatusChangedBy != "clerk" || pet.CreatedBy != "clerk" {
+				t.Errorf("expected the importer to own the new pet, got %q %q", pet.StatusChangedBy, pet.CreatedBy)
+			}
+		})
+	}
+}
+
+func TestPetImportHandler(t *testing.T) {
+	newController := func(stored *int) *PetController {
+		mock := &MockStorage{
+			CreateBatch_mock: func(pets []*models.Pet) ([]error, error) {
+				for _, pet := range pets {
+					*stored++
+					pet.ID = int64(*stored)
+				}
+				return make([]error, len(pets)), nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+		return NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		code        int
+		stored      int
+	}{
+		{"csv", "/pet/import", "text/csv", "name,category,photo_urls\nrex,dogs,/img/rex.png\nmax,dogs,/img/max.png\n,dogs,/img/x.png\n", http.StatusOK, 2},
+		{"ndjson by query", "/pet/import?format=ndjson", "application/octet-stream", `{"name": "rex", "category": {"name": "dogs"}, "photoUrls": ["/img/rex.png"]}` + "\n", http.StatusOK, 1},
+		{"dry run", "/pet/import?dry_run=true", "text/csv", "name,category,photo_urls\nrex,dogs,/img/rex.png\n", http.StatusOK, 0},
+		{"bad dry run", "/pet/import?dry_run=maybe", "text/csv", "name,category\nrex,dogs\n", http.StatusBadRequest, 0},
+		{"unknown format", "/pet/import", "application/json", `[]`, http.StatusUnsupportedMediaType, 0},
+		{"bad header", "/pet/import", "text/csv", "name,colour\nrex,black\n", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			stored := 0
+			w := httptest.NewRecorder()
+			newController(&stored).PetImport(w, req)
+
+			if w.Code != tt.code {
+				t.Fatalf("expected status code %d but got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if stored != tt.stored {
+				t.Errorf("expected %d stored pets got %d", tt.stored, stored)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var report models.ImportReport
+			if err := jsoniter.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Created+report.Failed != report.Total || len(report.Rows) != report.Total {
+				t.Errorf("inconsistent report %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPetExportHandler(t *testing.T) {
+	name := "rex"
+	pets := []*models.Pet{
+		{ID: 1, Name: &name, Category: &models.Category{ID: 2, Name: "dogs"}, Status: "available",
+			Tags: []*models.Tag{{ID: 1, Name: "big"}, {ID: 2, Name: "friendly"}}, PhotoUrls: []string{"a.png", "b.png"},
+			Breed: "beagle", BirthDate: &models.Date{Time: time.Date(2020, 5, 17, 0, 0, 0, 0, time.UTC)}, Sex: "male", WeightGrams: 9500, Price: 25000},
+		{ID: 2, Name: &name, Status: "sold"},
+	}
+	mock := &MockStorage{
+		Export_mock: func(f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error {
+			for _, pet := range pets {
+				if err := fn(pet); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{})
+	controller := NewPetController(responder.NewResponder(decoder, logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), nil)
+
+	tests := []struct {
+		name        string
+		target      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"csv", "/pet/export", http.StatusOK, "text/csv;charset=utf-8",
+			"id,name,category_id,category,status,tags,photo_urls,breed,birth_date,sex,weight_grams,description,price,status_changed_by,status_changed_at\n" +
+				"1,rex,2,dogs,available,big|friendly,a.png|b.png,beagle,2020-05-17,male,9500,,25000,,\n" +
+				"2,rex,0,,sold,,,,,,0,,0,,\n"},
+		{"ndjson", "/pet/export?format=ndjson&status=sold", http.StatusOK, "application/x-ndjson", ""},
+		{"bad format", "/pet/export?format=xml", http.StatusBadRequest, "", ""},
+		{"bad sort", "/pet/export?sort=category", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			controller.PetExport(w, httptest.NewRequest("GET", tt.target, nil))
+
+			if w.Code != tt.code {
+				t.Fatalf("expected status code %d but got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("expected content type %q got %q", tt.contentType, got)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("unexpected body:\n%s", w.Body.String())
+			}
+			if tt.body == "" {
+				lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+				if len(lines) != len(pets) {
+					t.Fatalf("expected %d lines got %d", len(pets), len(lines))
+				}
+				var pet models.Pet
+				if err := jsoniter.Unmarshal([]byte(lines[0]), &pet); err != nil || pet.ID != 1 || len(pet.Tags) != 2 {
+					t.Errorf("unexpected line %s: %v", lines[0], err)
+				}
+			}
+		})
+	}
+}
+
+type summariesFunc func(petID int64, now time.Time) (*models.MedicalSummary, error)
+
+func (f summariesFunc) Summary(petID int64, now time.Time) (*models.MedicalSummary, error) {
+	return f(petID, now)
+}
+
+func TestPetGetByIDMedicalSummary(t *testing.T) {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	mock := &MockStorage{
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			name := "CAT"
+			return &models.Pet{ID: id, Name: &name, Status: models.PetStatusAvailable, Version: 3}, nil
+		},
+	}
+	summaries := summariesFunc(func(petID int64, now time.Time) (*models.MedicalSummary, error) {
+		if petID != 1 {
+			t.Errorf("expected a summary of pet 1, got %d", petID)
+		}
+		return &models.MedicalSummary{Records: 2, Vaccinations: []models.MedicalRecord{}}, nil
+	})
+	controller := NewPetController(responder.NewResponder(godecoder.NewDecoder(jsoniter.Config{}), logger), service.NewPetService(mock, &MockEventStorage{}, service.ImageConfig{}), summaries)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("petID", "1")
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("If-None-Match", `"3"`)
+		w := httptest.NewRecorder()
+		controller.PetGetByID(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)))
+		return w
+	}
+
+	w := get("/pet/1?include=medical_summary")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Fatalf("expected 200 without ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	var got models.Pet
+	if err := jsoniter.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.MedicalSummary == nil || got.MedicalSummary.Records != 2 {
+		t.Errorf("expected the medical summary, got %s", w.Body.String())
+	}
+
+	if w := get("/pet/1"); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 without the summary, got %d", w.Code)
+	}
+}
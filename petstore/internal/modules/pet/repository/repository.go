@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 
 	"github.com/lib/pq"
@@ -25,38 +27,115 @@ func NewPetStorage(db *sqlx.DB, logger *zap.Logger) IPetStorage {
 		DB:     db}
 }
 
-func (ps *PetStorage) Create(pet *models.Pet) error {
-	categoryQuery := `INSERT INTO categories (name) VALUES ($1) RETURNING id`
+func (ps *PetStorage) Create(pet *models.Pet, record Recorder) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := ps.DB.QueryRowContext(ctx, categoryQuery, pet.Category.Name).Scan(&pet.Category.ID)
+	tx, err := ps.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			ps.logger.Error("no rows in categories table", zap.Error(err))
-			return ErrEditConflict
-		default:
-			ps.logger.Error(" error on inserting category", zap.Error(err))
-			return err
+		ps.logger.Error("error on starting pet transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	err = ps.insertPet(ctx, tx, pet)
+	if err != nil {
+		return err
+	}
+
+	err = ps.recordEvent(ctx, tx, record, pet)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ps.logger.Error("error on committing pet creation", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// CreateBatch inserts pets in a single transaction. Every pet is written
+// under its own savepoint, so a pet that fails (e.g. because of an unknown
+// category id) is skipped without losing the rest of the batch. The returned
+// slice holds the error of each pet by position, nil for inserted ones. The
+// error result is only set when the batch as a whole failed and nothing was
+// stored. A pet is recorded under its savepoint too.
+func (ps *PetStorage) CreateBatch(pets []*models.Pet, record Recorder) ([]error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := ps.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		ps.logger.Error("error on starting pet batch transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	errs := make([]error, len(pets))
+	for i, pet := range pets {
+		_, err = tx.ExecContext(ctx, `SAVEPOINT pet_row`)
+		if err != nil {
+			ps.logger.Error("error on creating savepoint", zap.Error(err))
+			return nil, err
+		}
+
+		errs[i] = ps.insertPet(ctx, tx, pet)
+		if errs[i] == nil {
+			errs[i] = ps.recordEvent(ctx, tx, record, pet)
+		}
+		if errs[i] != nil {
+			pet.ID = 0
+			_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT pet_row`)
+		} else {
+			_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT pet_row`)
+		}
+		if err != nil {
+			ps.logger.Error("error on closing savepoint", zap.Error(err))
+			return nil, err
 		}
 	}
 
-	query := `INSERT INTO pets (name, category_id, photo_urls, status)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, status, photo_urls`
+	err = tx.Commit()
+	if err != nil {
+		ps.logger.Error("error on committing pet batch", zap.Error(err))
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// insertPet writes pet with its category and tags inside tx.
+func (ps *PetStorage) insertPet(ctx context.Context, tx *sqlx.Tx, pet *models.Pet) error {
+	err := ps.resolveCategory(ctx, tx, pet.Category)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO pets (name, category_id, photo_urls, status, status_changed_by, status_changed_at,
+			breed, birth_date, sex, weight_grams, description, price, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, name, status, photo_urls, version`
 
 	args := []any{
 		pet.Name,
 		pet.Category.ID,
 		pq.Array(pet.PhotoUrls),
 		pet.Status,
+		pet.StatusChangedBy,
+		pet.StatusChangedAt,
+		pet.Breed,
+		pet.BirthDate,
+		pet.Sex,
+		pet.WeightGrams,
+		pet.Description,
+		pet.Price,
+		pet.CreatedBy,
 	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err = ps.DB.QueryRowContext(ctx, query, args...).Scan(&pet.ID, &pet.Name, &pet.Status, pq.Array(&pet.PhotoUrls))
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&pet.ID, &pet.Name, &pet.Status, pq.Array(&pet.PhotoUrls), &pet.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -68,83 +147,169 @@ func (ps *PetStorage) Create(pet *models.Pet) error {
 		}
 	}
 
-	for i := 0; i < len(pet.Tags); i++ {
-		tagQuery := `INSERT INTO tags (name) VALUES ($1) RETURNING id`
-		err = ps.DB.QueryRowContext(ctx, tagQuery, pet.Tags[i].Name).Scan(&pet.Tags[i].ID)
+	return ps.linkTags(ctx, tx, pet)
+}
+
+// resolveCategory points category at an existing categories row. A category
+// with an id must already exist; otherwise it is looked up by name and only
+// inserted when no category with that name exists yet.
+func (ps *PetStorage) resolveCategory(ctx context.Context, tx *sqlx.Tx, category *models.Category) error {
+	if category.ID != 0 {
+		err := tx.QueryRowContext(ctx, `SELECT name FROM categories WHERE id = $1`, category.ID).Scan(&category.Name)
 		if err != nil {
 			switch {
 			case errors.Is(err, sql.ErrNoRows):
-				ps.logger.Error("no rows in tags table", zap.Error(err))
-				return ErrEditConflict
+				return ErrCategoryNotFound
 			default:
-				ps.logger.Error("some error on inserting tag", zap.Error(err))
+				ps.logger.Error("error on getting category", zap.Error(err))
 				return err
 			}
 		}
-		tagPetQuery := `INSERT INTO pet_tags (pet_id, tag_id) VALUES ($1, $2) RETURNING pet_id, tag_id`
-		err = ps.DB.QueryRowContext(ctx, tagPetQuery, pet.ID, pet.Tags[i].ID).Scan(&pet.ID, &pet.Tags[i].ID)
+		return nil
+	}
+
+	categoryQuery := `INSERT INTO categories (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`
+
+	err := tx.QueryRowContext(ctx, categoryQuery, category.Name).Scan(&category.ID)
+	if err != nil {
+		ps.logger.Error("error on resolving category", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// resolveTag points tag at an existing tags row, the same way
+// resolveCategory does for categories.
+func (ps *PetStorage) resolveTag(ctx context.Context, tx *sqlx.Tx, tag *models.Tag) error {
+	if tag.ID != 0 {
+		err := tx.QueryRowContext(ctx, `SELECT name FROM tags WHERE id = $1`, tag.ID).Scan(&tag.Name)
 		if err != nil {
 			switch {
 			case errors.Is(err, sql.ErrNoRows):
-				ps.logger.Error("no rows in pet-tag column", zap.Error(err))
-				return ErrEditConflict
+				return ErrTagNotFound
 			default:
-				ps.logger.Error("error on inserting pet-tag", zap.Error(err))
+				ps.logger.Error("error on getting tag", zap.Error(err))
 				return err
 			}
 		}
+		return nil
+	}
+
+	tagQuery := `INSERT INTO tags (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`
+
+	err := tx.QueryRowContext(ctx, tagQuery, tag.Name).Scan(&tag.ID)
+	if err != nil {
+		ps.logger.Error("error on resolving tag", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// linkTags attaches pet.Tags to the pet inside tx, resolving each of them
+// against the tag catalog first.
+func (ps *PetStorage) linkTags(ctx context.Context, tx *sqlx.Tx, pet *models.Pet) error {
+	for _, tag := range pet.Tags {
+		err := ps.resolveTag(ctx, tx, tag)
+		if err != nil {
+			return err
+		}
+
+		tagPetQuery := `INSERT INTO pet_tags (pet_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		_, err = tx.ExecContext(ctx, tagPetQuery, pet.ID, tag.ID)
+		if err != nil {
+			ps.logger.Error("error on inserting pet-tag", zap.Error(err))
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (ps *PetStorage) Update(pet *models.Pet) error {
+// Update writes name and status only if the pet is still at pet.Version, and
+// bumps pet.Version on success.
+func (ps *PetStorage) Update(pet *models.Pet, record Recorder) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	_, err := ps.DB.ExecContext(ctx, `
+
+	tx, err := ps.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		ps.logger.Error("error on starting pet transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
 		UPDATE pets
-		SET name = $1, status = $2
-		WHERE id = $3
-	`, pet.Name, pet.Status, pet.ID)
+		SET name = $1, status = $2, status_changed_by = $3, status_changed_at = $4, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version
+	`, pet.Name, pet.Status, pet.StatusChangedBy, pet.StatusChangedAt, pet.ID, pet.Version).Scan(&pet.Version)
 	if err != nil {
 		ps.logger.Error("error on partially updating pet", zap.Error(err))
 		return ErrEditConflict
 	}
+
+	err = ps.recordEvent(ctx, tx, record, pet)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ps.logger.Error("error on committing pet update", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
 
-func (ps *PetStorage) Update_put(pet *models.Pet) error {
-	categoryQuery := `UPDATE  categories SET name = $1 WHERE id = $2 RETURNING id`
+func (ps *PetStorage) Update_put(pet *models.Pet, record Recorder) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := ps.DB.QueryRowContext(ctx, categoryQuery, pet.Category.Name, pet.Category.ID).Scan(&pet.Category.ID)
+	tx, err := ps.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			ps.logger.Error("no rows in category table", zap.Error(err))
-			return ErrEditConflict
-		default:
-			ps.logger.Error(" error on updating category", zap.Error(err), zap.Error(ErrEditConflict))
-			return ErrEditConflict
-		}
+		ps.logger.Error("error on starting pet transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	err = ps.resolveCategory(ctx, tx, pet.Category)
+	if err != nil {
+		return err
 	}
 	query := `UPDATE pets 
-	SET name = $1, photo_urls = $2, status = $3 
-	WHERE id = $4 
-	RETURNING id, name, status, photo_urls`
+	SET name = $1, category_id = $2, photo_urls = $3, status = $4,
+		status_changed_by = $5, status_changed_at = $6,
+		breed = $7, birth_date = $8, sex = $9, weight_grams = $10, description = $11, price = $12,
+		version = version + 1
+	WHERE id = $13 AND version = $14
+	RETURNING id, name, status, photo_urls, version`
 
 	args := []any{
 		pet.Name,
+		pet.Category.ID,
 		pq.Array(pet.PhotoUrls),
 		pet.Status,
+		pet.StatusChangedBy,
+		pet.StatusChangedAt,
+		pet.Breed,
+		pet.BirthDate,
+		pet.Sex,
+		pet.WeightGrams,
+		pet.Description,
+		pet.Price,
 		pet.ID,
+		pet.Version,
 	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err = ps.DB.QueryRowContext(ctx, query, args...).Scan(&pet.ID, &pet.Name, &pet.Status, pq.Array(&pet.PhotoUrls))
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&pet.ID, &pet.Name, &pet.Status, pq.Array(&pet.PhotoUrls), &pet.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -156,171 +321,246 @@ func (ps *PetStorage) Update_put(pet *models.Pet) error {
 		}
 	}
 
-	return nil
-}
+	_, err = tx.ExecContext(ctx, `DELETE FROM pet_tags WHERE pet_id = $1`, pet.ID)
+	if err != nil {
+		ps.logger.Error("error on clearing pet-tags", zap.Error(err))
+		return err
+	}
 
-func (ps *PetStorage) Delete(id int64) error {
-	_, err := ps.DB.Exec(`
-		DELETE FROM pets WHERE id = $1
-	`, id)
+	err = ps.linkTags(ctx, tx, pet)
 	if err != nil {
-		ps.logger.Error("error on deleting pet", zap.Error(err))
 		return err
 	}
 
-	_, err = ps.DB.Exec(`
-		DELETE FROM pet_tags WHERE pet_id = $1
-	`, id)
+	err = ps.recordEvent(ctx, tx, record, pet)
 	if err != nil {
-		ps.logger.Error("error on deleting pet-tags", zap.Error(err))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ps.logger.Error("error on committing pet update", zap.Error(err))
 		return err
 	}
 
 	return nil
 }
 
-func (ps *PetStorage) GetByID(id int64) (*models.Pet, error) {
+// AddPhoto appends photo to the pet's photos and its original url to the
+// photo urls in place, so concurrent uploads for the same pet don't
+// overwrite each other. It moves the pet to a new version like any other
+// change.
+func (ps *PetStorage) AddPhoto(id int64, photo models.Photo) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	query := `
-		SELECT pets.*, categories.name
-FROM pets
-INNER JOIN categories ON pets.category_id = categories.id
-WHERE pets.id = $1
-	`
+	result, err := ps.DB.ExecContext(ctx, `
+		UPDATE pets
+		SET photo_urls = array_append(photo_urls, $1), photos = photos || $2::jsonb, version = version + 1
+		WHERE id = $3
+	`, photo.URL, models.Photos{photo}, id)
+	if err != nil {
+		ps.logger.Error("error on adding pet photo", zap.Error(err))
+		return err
+	}
 
-	pet := &models.Pet{
-		Category: &models.Category{},
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ps.logger.Error("error on adding pet photo", zap.Error(err))
+		return err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	if affected == 0 {
+		return ErrPetNotFound
+	}
+
+	return nil
+}
 
+// Delete removes the pet if it is still at version. It returns
+// ErrEditConflict when the pet exists but has been changed since.
+func (ps *PetStorage) Delete(id int64, version int, record Recorder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := ps.DB.QueryRowContext(ctx, query, id).Scan(
-		&pet.ID,
-		&pet.Category.ID,
-		&pet.Name,
-		&pet.Status,
-		pq.Array(&pet.PhotoUrls),
-		&pet.Category.Name,
-	)
+	tx, err := ps.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		ps.logger.Error("error on starting pet transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM pet_tags WHERE pet_id = $1
+	`, id)
+	if err != nil {
+		ps.logger.Error("error on deleting pet-tags", zap.Error(err))
+		return err
+	}
 
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM pets WHERE id = $1 AND version = $2
+	`, id, version)
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			ps.logger.Error("no rows in pets", zap.Error(err))
-			return nil, ErrPetNotFound
-		default:
-			ps.logger.Error("some error on getting pet by id", zap.Error(err))
-			return nil, err
+		ps.logger.Error("error on deleting pet", zap.Error(err))
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ps.logger.Error("error on deleting pet", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pets WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			ps.logger.Error("error on checking pet", zap.Error(err))
+			return err
+		}
+		if exists {
+			return ErrEditConflict
 		}
+		return ErrPetNotFound
+	}
+
+	err = ps.recordEvent(ctx, tx, record, nil)
+	if err != nil {
+		return err
 	}
 
-	tagQuery := `
-	SELECT tags.*
-FROM pet_tags
-INNER JOIN tags ON pet_tags.tag_id = tags.id
-INNER JOIN pets ON pet_tags.pet_id = pets.id
-WHERE pet_tags.pet_id = $1
-	`
-	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	err = tx.Commit()
+	if err != nil {
+		ps.logger.Error("error on committing pet deletion", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
 
+func (ps *PetStorage) GetByID(id int64) (*models.Pet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := ps.DB.QueryContext(ctx, tagQuery, id)
+	pets, err := ps.loadPets(ctx, selectPets+`WHERE pets.id = $1`, id)
 	if err != nil {
-		ps.logger.Error("some error on getting rows from pet tags", zap.Error(err))
+		ps.logger.Error("some error on getting pet by id", zap.Error(err))
 		return nil, err
 	}
+	if len(pets) == 0 {
+		return nil, ErrPetNotFound
+	}
 
-	defer rows.Close()
+	return &pets[0], nil
+}
 
-	for rows.Next() {
-		var tag models.Tag
-		err := rows.Scan(
-			&tag.ID,
-			&tag.Name,
-		)
-		if err != nil {
-			return nil, err
-		}
-		pet.Tags = append(pet.Tags, &tag)
+func (ps *PetStorage) GetByStatus(status string) ([]models.Pet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	pets, err := ps.loadPets(ctx, selectPets+`WHERE pets.status = $1 ORDER BY pets.id`, status)
+	if err != nil {
+		ps.logger.Error("some error on getting pets by status", zap.Error(err))
+		return nil, err
 	}
 
-	return pet, nil
+	return pets, nil
 }
 
-func (ps *PetStorage) GetByStatus(status string) ([]models.Pet, error) {
-	var result []models.Pet
-	query := `
-	SELECT pets.*, categories.name
-FROM pets
-INNER JOIN categories ON pets.category_id = categories.id
-WHERE pets.status = $1
-`
+// GetByTags returns pets carrying any of the given tag names, or all of them
+// when matchAll is set. tags must not contain duplicates.
+func (ps *PetStorage) GetByTags(tags []string, matchAll bool) ([]models.Pet, error) {
+	minMatches := 1
+	if matchAll {
+		minMatches = len(tags)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	query, args, err := sqlx.In(selectPets+`
+WHERE pets.id IN (
+	SELECT pet_tags.pet_id
+	FROM pet_tags
+	INNER JOIN tags ON pet_tags.tag_id = tags.id
+	WHERE tags.name IN (?)
+	GROUP BY pet_tags.pet_id
+	HAVING count(DISTINCT tags.id) >= ?
+)
+ORDER BY pets.id
+`, tags, minMatches)
+	if err != nil {
+		ps.logger.Error("error on building pets by tags query", zap.Error(err))
+		return nil, err
+	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := ps.DB.QueryContext(ctx, query, status)
+	pets, err := ps.loadPets(ctx, ps.DB.Rebind(query), args...)
 	if err != nil {
-		ps.logger.Error("some error on getting rows from pets", zap.Error(err))
+		ps.logger.Error("some error on getting pets by tags", zap.Error(err))
 		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		pet := models.Pet{
-			Category: &models.Category{},
-		}
+	return pets, nil
+}
 
-		err := rows.Scan(
-			&pet.ID,
-			&pet.Category.ID,
-			&pet.Name,
-			&pet.Status,
-			pq.Array(&pet.PhotoUrls),
-			&pet.Category.Name,
-		)
-		if err != nil {
-			ps.logger.Error("some error on getting rows from pets", zap.Error(err))
-			return nil, err
-		}
+// GetAll returns one page of pets matching f, ordered by filters.Sort.
+func (ps *PetStorage) GetAll(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	where, args, err := petFilterClause(f)
+	if err != nil {
+		ps.logger.Error("error on building pet filter", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
 
-		tagQuery := `
-	SELECT tags.*
-FROM pet_tags
-INNER JOIN tags ON pet_tags.tag_id = tags.id
-INNER JOIN pets ON pet_tags.pet_id = pets.id
-WHERE pet_tags.pet_id = $1
-	`
-		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-		defer cancel()
+	if filters.Keyset {
+		return ps.getAfter(ctx, where, args, filters)
+	}
 
-		rows, err := ps.DB.QueryContext(ctx, tagQuery, pet.ID)
-		if err != nil {
-			ps.logger.Error("some error on getting rows from pet tags", zap.Error(err))
-			return nil, err
-		}
+	countQuery := ps.DB.Rebind(`
+	SELECT count(*)
+FROM pets
+INNER JOIN categories ON pets.category_id = categories.id
+` + where)
 
-		defer rows.Close()
+	totalRecords := 0
+	err = ps.DB.QueryRowContext(ctx, countQuery, args...).Scan(&totalRecords)
+	if err != nil {
+		ps.logger.Error("some error on counting pets", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
 
-		for rows.Next() {
-			var tag models.Tag
-			err := rows.Scan(
-				&tag.ID,
-				&tag.Name,
-			)
-			if err != nil {
-				return nil, err
-			}
-			pet.Tags = append(pet.Tags, &tag)
-		}
+	query := ps.DB.Rebind(fmt.Sprintf(`%s%s
+ORDER BY pets.%s %s, pets.id ASC
+LIMIT ? OFFSET ?`, selectPets, where, filters.SortColumn(), filters.SortDirection()))
 
-		result = append(result, pet)
+	pets, err := ps.loadPets(ctx, query, append(args, filters.Limit(), filters.Offset())...)
+	if err != nil {
+		ps.logger.Error("some error on listing pets", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	metadata := filter.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return pets, metadata, nil
+}
 
+// getAfter serves a keyset page of GetAll. Nothing is counted, and the rows
+// before the cursor are skipped by the WHERE clause rather than an OFFSET.
+func (ps *PetStorage) getAfter(ctx context.Context, where string, args []any, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	clause, keysetArgs := filters.KeysetClause("pets")
+
+	query := ps.DB.Rebind(fmt.Sprintf(`%s%s
+AND %s
+ORDER BY pets.%s %s, pets.id ASC
+LIMIT ?`, selectPets, where, clause, filters.SortColumn(), filters.SortDirection()))
+
+	args = append(args, keysetArgs...)
+	pets, err := ps.loadPets(ctx, query, append(args, filters.KeysetLimit())...)
+	if err != nil {
+		ps.logger.Error("some error on listing pets", zap.Error(err))
+		return nil, filter.Metadata{}, err
 	}
 
-	return result, nil
+	pets, metadata := filter.KeysetPage(filters, pets, petKey(filters.SortColumn()))
+	return pets, metadata, nil
 }
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"test/internal/models"
+)
+
+const benchPets = 500
+
+func seedBenchStorage(b *testing.B) *PetStorage {
+	ps := newSQLiteStorage(b)
+	for i := 0; i < benchPets; i++ {
+		pet := testPet(fmt.Sprintf("pet-%d", i), "friendly", fmt.Sprintf("size-%d", i%5), fmt.Sprintf("color-%d", i%7))
+		if err := ps.Create(pet, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return ps
+}
+
+// getByStatusTagPerPet is the loading strategy GetByStatus used before the
+// batched loader: one tag query for every pet in the result.
+func getByStatusTagPerPet(ps *PetStorage, status string) ([]models.Pet, error) {
+	ctx := context.Background()
+
+	rows, err := ps.DB.QueryContext(ctx, selectPets+`WHERE pets.status = $1 ORDER BY pets.id`, status)
+	if err != nil {
+		return nil, err
+	}
+	var pets []models.Pet
+	for rows.Next() {
+		pet, err := scanPet(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		pets = append(pets, pet)
+	}
+	rows.Close()
+
+	for i := range pets {
+		tags := map[int64][]*models.Tag{}
+		err := ps.scanTags(ctx, `
+	SELECT pet_tags.pet_id, tags.id, tags.name
+FROM pet_tags
+INNER JOIN tags ON pet_tags.tag_id = tags.id
+WHERE pet_tags.pet_id = $1`, []any{pets[i].ID}, tags)
+		if err != nil {
+			return nil, err
+		}
+		pets[i].Tags = tags[pets[i].ID]
+	}
+
+	return pets, nil
+}
+
+func BenchmarkGetByStatus(b *testing.B) {
+	ps := seedBenchStorage(b)
+
+	b.Run("batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pets, err := ps.GetByStatus("available")
+			if err != nil || len(pets) != benchPets {
+				b.Fatalf("expected %d pets, got %d (%v)", benchPets, len(pets), err)
+			}
+		}
+	})
+
+	b.Run("tag query per pet", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pets, err := getByStatusTagPerPet(ps, "available")
+			if err != nil || len(pets) != benchPets {
+				b.Fatalf("expected %d pets, got %d (%v)", benchPets, len(pets), err)
+			}
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"test/internal/models"
+)
+
+// The methods below give the in-memory category and tag storages the
+// catalog that PetStorage_map resolves pets against, so that /category and
+// /tag see the same categories and tags as the pets do. Changes are logged
+// like pet writes.
+
+func (ps *PetStorage_map) ListCategories() []*models.Category {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	categories := make([]*models.Category, 0, len(ps.categories.names))
+	for id, name := range ps.categories.names {
+		categories = append(categories, &models.Category{ID: id, Name: name})
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories
+}
+
+func (ps *PetStorage_map) GetCategory(id int64) (*models.Category, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	name, ok := ps.categories.names[id]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	return &models.Category{ID: id, Name: name}, nil
+}
+
+// CreateCategory adds category.Name to the catalog and sets category.ID.
+func (ps *PetStorage_map) CreateCategory(category *models.Category) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.categories.ids[category.Name]; ok {
+		return ErrDuplicateCategory
+	}
+	id := ps.categories.lastID + 1
+	if err := ps.append(mapRecord{Op: opCategory, ID: id, Name: category.Name}); err != nil {
+		return err
+	}
+	ps.renameCategory(id, category.Name)
+	category.ID = id
+	return nil
+}
+
+// RenameCategory renames category.ID, pets in it included, as the categories
+// table joined on read does.
+func (ps *PetStorage_map) RenameCategory(category *models.Category) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.categories.names[category.ID]; !ok {
+		return ErrCategoryNotFound
+	}
+	if id, ok := ps.categories.ids[category.Name]; ok && id != category.ID {
+		return ErrDuplicateCategory
+	}
+	if err := ps.append(mapRecord{Op: opCategory, ID: category.ID, Name: category.Name}); err != nil {
+		return err
+	}
+	ps.renameCategory(category.ID, category.Name)
+	return nil
+}
+
+// DeleteCategory removes a category that no pet is in.
+func (ps *PetStorage_map) DeleteCategory(id int64) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.categories.names[id]; !ok {
+		return ErrCategoryNotFound
+	}
+	for _, pet := range ps.pets {
+		if pet.Category != nil && pet.Category.ID == id {
+			return ErrCategoryInUse
+		}
+	}
+	if err := ps.append(mapRecord{Op: opDropCategory, ID: id}); err != nil {
+		return err
+	}
+	ps.categories.drop(id)
+	return nil
+}
+
+// SearchTags returns up to limit tags whose names start with prefix, ignoring
+// case, by name.
+func (ps *PetStorage_map) SearchTags(prefix string, limit int) []*models.Tag {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	prefix = strings.ToLower(prefix)
+	tags := []*models.Tag{}
+	for id, name := range ps.tags.names {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			tags = append(tags, &models.Tag{ID: id, Name: name})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags
+}
+
+// CreateTag adds tag.Name to the catalog and sets tag.ID.
+func (ps *PetStorage_map) CreateTag(tag *models.Tag) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.tags.ids[tag.Name]; ok {
+		return ErrDuplicateTag
+	}
+	id := ps.tags.lastID + 1
+	if err := ps.append(mapRecord{Op: opTag, ID: id, Name: tag.Name}); err != nil {
+		return err
+	}
+	ps.tags.add(id, tag.Name)
+	tag.ID = id
+	return nil
+}
+
+// DeleteTag removes a tag from the catalog and from every pet that carries
+// it, like the cascade on pet_tags.
+func (ps *PetStorage_map) DeleteTag(id int64) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.tags.names[id]; !ok {
+		return ErrTagNotFound
+	}
+	if err := ps.append(mapRecord{Op: opDropTag, ID: id}); err != nil {
+		return err
+	}
+	ps.dropTag(id)
+	return nil
+}
+
+// renameCategory sets the name of category id, adding it if it is new, and
+// renames it in the pets. Callers must hold the write lock.
+func (ps *PetStorage_map) renameCategory(id int64, name string) {
+	ps.categories.add(id, name)
+	for _, pet := range ps.pets {
+		if pet.Category != nil && pet.Category.ID == id {
+			pet.Category.Name = name
+		}
+	}
+}
+
+// dropTag removes tag id from the catalog and the pets. Callers must hold the
+// write lock.
+func (ps *PetStorage_map) dropTag(id int64) {
+	name := ps.tags.names[id]
+	for petID := range ps.byTag[name] {
+		stored := ps.pets[petID]
+		updated := clonePet(stored)
+		tags := updated.Tags
+		updated.Tags = tags[:0]
+		for _, tag := range tags {
+			if tag.ID != id {
+				updated.Tags = append(updated.Tags, tag)
+			}
+		}
+		ps.remove(stored)
+		ps.put(updated)
+	}
+	ps.tags.drop(id)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"test/internal/models"
+)
+
+type PetEventStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+}
+
+func NewPetEventStorage(db *sqlx.DB, logger *zap.Logger) IPetEventStorage {
+	return &PetEventStorage{
+		logger: logger,
+		DB:     db}
+}
+
+// insertEvent writes event inside tx, the transaction of the pet write it
+// records.
+func insertEvent(ctx context.Context, tx *sqlx.Tx, event *models.PetEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	query := tx.Rebind(`INSERT INTO pet_events (pet_id, action, actor, created_at, changes)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id`)
+
+	return tx.QueryRowContext(ctx, query, event.PetID, event.Action, event.Actor, event.CreatedAt, event.Changes).Scan(&event.ID)
+}
+
+// recordEvent stores the history entry record builds for pet inside tx.
+func (ps *PetStorage) recordEvent(ctx context.Context, tx *sqlx.Tx, record Recorder, pet *models.Pet) error {
+	if record == nil {
+		return nil
+	}
+	event := record(pet)
+	if event == nil {
+		return nil
+	}
+
+	err := insertEvent(ctx, tx, event)
+	if err != nil {
+		ps.logger.Error("error on inserting pet event", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrHistoryNotRecorded, err)
+	}
+
+	return nil
+}
+
+// GetByPet returns the history of a pet, oldest change first.
+func (es *PetEventStorage) GetByPet(petID int64) ([]models.PetEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := es.DB.Rebind(`SELECT id, pet_id, action, actor, created_at, changes
+		FROM pet_events
+		WHERE pet_id = ?
+		ORDER BY created_at, id`)
+
+	rows, err := es.DB.QueryContext(ctx, query, petID)
+	if err != nil {
+		es.logger.Error("error on getting pet events", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.PetEvent{}
+	for rows.Next() {
+		var event models.PetEvent
+		err = rows.Scan(&event.ID, &event.PetID, &event.Action, &event.Actor, &event.CreatedAt, &event.Changes)
+		if err != nil {
+			es.logger.Error("error on scanning pet event", zap.Error(err))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
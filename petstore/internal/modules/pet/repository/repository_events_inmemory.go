@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"time"
+
+	"test/internal/models"
+)
+
+// PetEventStorage_map reads the pet history that PetStorage_map keeps
+// under its lock along with the changes it records.
+type PetEventStorage_map struct {
+	pets *PetStorage_map
+}
+
+func NewPetEventStorage_map(pets *PetStorage_map) *PetEventStorage_map {
+	return &PetEventStorage_map{pets: pets}
+}
+
+// GetByPet returns the history of a pet, oldest change first.
+func (es *PetEventStorage_map) GetByPet(petID int64) ([]models.PetEvent, error) {
+	ps := es.pets
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return append([]models.PetEvent(nil), ps.events[petID]...), nil
+}
+
+// recordEvent adds the history entry record builds for pet. Callers must
+// hold the write lock.
+func (ps *PetStorage_map) recordEvent(record Recorder, pet *models.Pet) {
+	if record == nil {
+		return
+	}
+	event := record(pet)
+	if event == nil {
+		return
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	ps.lastEventID++
+	event.ID = ps.lastEventID
+	ps.events[event.PetID] = append(ps.events[event.PetID], *event)
+}
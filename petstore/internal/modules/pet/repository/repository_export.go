@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+// tagSeparator joins the tags aggregated into an export row. The ASCII unit
+// separator can't be typed into a tag name by accident.
+const tagSeparator = "\x1f"
+
+// exportTags aggregates the tags of the current pet as "id:name" pairs, so
+// that export rows carry their tags without a second query. string_agg is
+// understood by both PostgreSQL and SQLite.
+const exportTags = `,
+	(SELECT string_agg(tags.id || ':' || tags.name, '` + tagSeparator + `' ORDER BY tags.id)
+		FROM pet_tags
+		INNER JOIN tags ON tags.id = pet_tags.tag_id
+		WHERE pet_tags.pet_id = pets.id)
+`
+
+// withTags scans the aggregated tag column after the selectPets columns.
+type withTags struct {
+	rowScanner
+	tags *sql.NullString
+}
+
+func (s withTags) Scan(dest ...any) error {
+	return s.rowScanner.Scan(append(dest, s.tags)...)
+}
+
+// Export calls fn with every pet matching f, in the order given by sort. Rows
+// are read from a single cursor and handed over one by one, so memory use
+// doesn't grow with the number of pets. Returning an error from fn stops the
+// export with that error.
+func (ps *PetStorage) Export(ctx context.Context, f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error {
+	where, args, err := petFilterClause(f)
+	if err != nil {
+		ps.logger.Error("error on building pet filter", zap.Error(err))
+		return err
+	}
+
+	head := strings.Replace(selectPets, "\nFROM pets", exportTags+"FROM pets", 1)
+	query := ps.DB.Rebind(fmt.Sprintf(`%s%s
+ORDER BY pets.%s %s, pets.id ASC`, head, where, sort.SortColumn(), sort.SortDirection()))
+
+	rows, err := ps.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		ps.logger.Error("error on exporting pets", zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tags sql.NullString
+		pet, err := scanPet(withTags{rows, &tags})
+		if err != nil {
+			return err
+		}
+
+		pet.Tags, err = parseExportTags(tags.String)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(&pet); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func parseExportTags(s string) ([]*models.Tag, error) {
+	tags := []*models.Tag{}
+	if s == "" {
+		return tags, nil
+	}
+
+	for _, pair := range strings.Split(s, tagSeparator) {
+		id, name, _ := strings.Cut(pair, ":")
+		tagID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed exported tag %q: %w", pair, err)
+		}
+		tags = append(tags, &models.Tag{ID: tagID, Name: name})
+	}
+	return tags, nil
+}
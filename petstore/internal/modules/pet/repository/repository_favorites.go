@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+type FavoriteStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+	pets   *PetStorage
+}
+
+func NewFavoriteStorage(db *sqlx.DB, logger *zap.Logger) IFavoriteStorage {
+	return &FavoriteStorage{
+		logger: logger,
+		DB:     db,
+		pets:   &PetStorage{logger: logger, DB: db},
+	}
+}
+
+// Add inserts the favorite unless it exists. Nothing inserted means either
+// that or a missing pet, which is told apart by a second lookup.
+func (fs *FavoriteStorage) Add(username string, petID int64, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := fs.DB.ExecContext(ctx, fs.DB.Rebind(`
+	INSERT INTO favorites (username, pet_id, created_at)
+	SELECT ?, pets.id, ? FROM pets WHERE pets.id = ?
+	ON CONFLICT (username, pet_id) DO NOTHING`), username, at, petID)
+	if err != nil {
+		fs.logger.Error("error on adding favorite", zap.Error(err))
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	exists := false
+	err = fs.DB.QueryRowContext(ctx, fs.DB.Rebind(`SELECT EXISTS (SELECT 1 FROM pets WHERE id = ?)`), petID).Scan(&exists)
+	if err != nil {
+		fs.logger.Error("error on looking up favorite pet", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return ErrPetNotFound
+	}
+	return nil
+}
+
+func (fs *FavoriteStorage) Remove(username string, petID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := fs.DB.ExecContext(ctx, fs.DB.Rebind(`
+	DELETE FROM favorites WHERE username = ? AND pet_id = ?`), username, petID)
+	if err != nil {
+		fs.logger.Error("error on removing favorite", zap.Error(err))
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrFavoriteNotFound
+	}
+	return nil
+}
+
+func (fs *FavoriteStorage) GetByUser(username string, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	totalRecords := 0
+	err := fs.DB.QueryRowContext(ctx, fs.DB.Rebind(`SELECT count(*) FROM favorites WHERE username = ?`), username).Scan(&totalRecords)
+	if err != nil {
+		fs.logger.Error("some error on counting favorites", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	query := fs.DB.Rebind(selectPets + `INNER JOIN favorites ON favorites.pet_id = pets.id
+WHERE favorites.username = ?
+ORDER BY favorites.created_at DESC, pets.id DESC
+LIMIT ? OFFSET ?`)
+
+	pets, err := fs.pets.loadPets(ctx, query, username, filters.Limit(), filters.Offset())
+	if err != nil {
+		fs.logger.Error("some error on listing favorites", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	metadata := filter.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return pets, metadata, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+// FavoriteStorage_map keeps favorites inside the PetStorage_map they refer
+// to, under its lock, so that pets carry their favorite count and deleting a
+// pet drops its favorites.
+type FavoriteStorage_map struct {
+	pets *PetStorage_map
+}
+
+func NewFavoriteStorage_map(pets *PetStorage_map) *FavoriteStorage_map {
+	return &FavoriteStorage_map{pets: pets}
+}
+
+func (fs *FavoriteStorage_map) Add(username string, petID int64, at time.Time) error {
+	ps := fs.pets
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.pets[petID]; !ok {
+		return ErrPetNotFound
+	}
+
+	if _, ok := ps.favorites[username][petID]; ok {
+		return nil
+	}
+	if err := ps.append(mapRecord{Op: opFavorite, ID: petID, User: username, At: &at}); err != nil {
+		return err
+	}
+	ps.addFavorite(username, petID, at)
+	return nil
+}
+
+func (fs *FavoriteStorage_map) Remove(username string, petID int64) error {
+	ps := fs.pets
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.favorites[username][petID]; !ok {
+		return ErrFavoriteNotFound
+	}
+	if err := ps.append(mapRecord{Op: opUnfavorite, ID: petID, User: username}); err != nil {
+		return err
+	}
+	ps.removeFavorite(username, petID)
+	return nil
+}
+
+func (fs *FavoriteStorage_map) GetByUser(username string, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	ps := fs.pets
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	favorites := ps.favorites[username]
+	ids := make([]int64, 0, len(favorites))
+	for id := range favorites {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := favorites[ids[i]], favorites[ids[j]]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return ids[i] > ids[j]
+	})
+
+	metadata := filter.CalculateMetadata(len(ids), filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + filters.Limit()
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	pets := make([]models.Pet, 0, end-start)
+	for _, id := range ids[start:end] {
+		pets = append(pets, *ps.read(ps.pets[id]))
+	}
+	return pets, metadata, nil
+}
+
+// addFavorite adds pet id to the favorites of username. Callers must hold the
+// write lock.
+func (ps *PetStorage_map) addFavorite(username string, id int64, at time.Time) {
+	favorites := ps.favorites[username]
+	if favorites == nil {
+		favorites = make(map[int64]time.Time)
+		ps.favorites[username] = favorites
+	}
+	if _, ok := favorites[id]; !ok {
+		favorites[id] = at
+		ps.favoriteCounts[id]++
+	}
+}
+
+// removeFavorite removes pet id from the favorites of username. Callers must
+// hold the write lock.
+func (ps *PetStorage_map) removeFavorite(username string, id int64) {
+	favorites := ps.favorites[username]
+	if _, ok := favorites[id]; !ok {
+		return
+	}
+	delete(favorites, id)
+	if len(favorites) == 0 {
+		delete(ps.favorites, username)
+	}
+	ps.favoriteCounts[id]--
+	if ps.favoriteCounts[id] == 0 {
+		delete(ps.favoriteCounts, id)
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestFavoriteStorage(t *testing.T) {
+	backends := map[string]func(t *testing.T) (IPetStorage, IFavoriteStorage){
+		"sql": func(t *testing.T) (IPetStorage, IFavoriteStorage) {
+			ps := newSQLiteStorage(t)
+			// deleting a pet relies on the cascade, as on PostgreSQL
+			if _, err := ps.DB.Exec(`PRAGMA foreign_keys = ON`); err != nil {
+				t.Fatal(err)
+			}
+			return ps, NewFavoriteStorage(ps.DB, zap.NewNop())
+		},
+		"memory": func(t *testing.T) (IPetStorage, IFavoriteStorage) {
+			ps := NewPetStorage_map(zap.NewNop())
+			return ps, NewFavoriteStorage_map(ps)
+		},
+	}
+	page := filter.Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}}
+	at := time.Date(2024, 10, 12, 12, 0, 0, 0, time.UTC)
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			pets, favorites := newStorage(t)
+
+			rex, tom := testPet("rex", "friendly"), testPet("tom")
+			for _, pet := range []*models.Pet{rex, tom} {
+				if err := pets.Create(pet, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, f := range []struct {
+				user string
+				pet  int64
+				at   time.Time
+			}{
+				{"alice", rex.ID, at},
+				{"alice", tom.ID, at.Add(time.Minute)},
+				{"bob", rex.ID, at},
+				{"alice", rex.ID, at.Add(time.Hour)}, // again, keeps its place
+			} {
+				if err := favorites.Add(f.user, f.pet, f.at); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := favorites.Add("alice", 99, at); !errors.Is(err, ErrPetNotFound) {
+				t.Errorf("expected ErrPetNotFound, got %v", err)
+			}
+
+			got, err := pets.GetByID(rex.ID)
+			if err != nil || got.FavoriteCount != 2 {
+				t.Errorf("expected rex to be favorited twice, got %+v: %v", got, err)
+			}
+
+			list, meta, err := favorites.GetByUser("alice", page)
+			if err != nil || len(list) != 2 || list[0].ID != tom.ID || list[1].ID != rex.ID || meta.TotalRecords != 2 {
+				t.Fatalf("expected tom then rex, got %+v %+v: %v", list, meta, err)
+			}
+			if len(list[1].Tags) != 1 || list[1].FavoriteCount != 2 {
+				t.Errorf("expected a fully loaded rex, got %+v", list[1])
+			}
+
+			if err := favorites.Remove("alice", rex.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := favorites.Remove("alice", rex.ID); !errors.Is(err, ErrFavoriteNotFound) {
+				t.Errorf("expected ErrFavoriteNotFound, got %v", err)
+			}
+			if got, _ := pets.GetByID(rex.ID); got.FavoriteCount != 1 {
+				t.Errorf("expected one favorite left, got %d", got.FavoriteCount)
+			}
+
+			rex.Status = "sold"
+			if err := pets.Update(rex, nil); err != nil {
+				t.Fatal(err)
+			}
+			list, _, err = favorites.GetByUser("bob", page)
+			if err != nil || len(list) != 1 || list[0].Status != "sold" {
+				t.Errorf("expected bob's favorite sold, got %+v: %v", list, err)
+			}
+
+			if err := pets.Delete(rex.ID, rex.Version, nil); err != nil {
+				t.Fatal(err)
+			}
+			list, meta, err = favorites.GetByUser("bob", page)
+			if err != nil || len(list) != 0 || meta.TotalRecords != 0 {
+				t.Errorf("expected bob's favorite gone with the pet, got %+v %+v: %v", list, meta, err)
+			}
+		})
+	}
+}
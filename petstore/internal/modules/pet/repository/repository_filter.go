@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"test/internal/models"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// petFilterClause turns f into a WHERE clause over selectPets. The clause
+// uses ? placeholders, so callers have to Rebind the final query.
+func petFilterClause(f models.PetFilter) (string, []any, error) {
+	conditions := []string{"1 = 1"}
+	args := []any{}
+
+	if f.Name != "" {
+		conditions = append(conditions, `lower(pets.name) LIKE lower(?) ESCAPE '\'`)
+		args = append(args, likeEscaper.Replace(f.Name)+"%")
+	}
+
+	if f.CategoryID != 0 {
+		conditions = append(conditions, `pets.category_id = ?`)
+		args = append(args, f.CategoryID)
+	}
+
+	if f.Category != "" {
+		conditions = append(conditions, `categories.name = ?`)
+		args = append(args, f.Category)
+	}
+
+	if f.Tag != "" {
+		conditions = append(conditions, `EXISTS (
+	SELECT 1 FROM pet_tags
+	INNER JOIN tags ON pet_tags.tag_id = tags.id
+	WHERE pet_tags.pet_id = pets.id AND tags.name = ?
+)`)
+		args = append(args, f.Tag)
+	}
+
+	if len(f.Statuses) > 0 {
+		clause, statusArgs, err := sqlx.In(`pets.status IN (?)`, f.Statuses)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, clause)
+		args = append(args, statusArgs...)
+	}
+
+	if f.CreatedBy != "" {
+		conditions = append(conditions, `pets.created_by = ?`)
+		args = append(args, f.CreatedBy)
+	}
+
+	if f.Breed != "" {
+		conditions = append(conditions, `lower(pets.breed) = lower(?)`)
+		args = append(args, f.Breed)
+	}
+
+	if f.MinPrice != nil {
+		conditions = append(conditions, `pets.price >= ?`)
+		args = append(args, *f.MinPrice)
+	}
+
+	if f.MaxPrice != nil {
+		conditions = append(conditions, `pets.price <= ?`)
+		args = append(args, *f.MaxPrice)
+	}
+
+	bornOnOrBefore, bornAfter := birthDateBounds(f, time.Now())
+	if bornOnOrBefore != nil {
+		conditions = append(conditions, `pets.birth_date <= ?`)
+		args = append(args, *bornOnOrBefore)
+	}
+	if bornAfter != nil {
+		conditions = append(conditions, `pets.birth_date > ?`)
+		args = append(args, *bornAfter)
+	}
+
+	return "WHERE " + strings.Join(conditions, "\nAND "), args, nil
+}
+
+// birthDateBounds turns the age range of f into dates of birth as of now: a
+// pet is at least MinAge years old when born on or before the first bound,
+// and at most MaxAge years old when born after the second. Unset ends of the
+// range give nil.
+func birthDateBounds(f models.PetFilter, now time.Time) (bornOnOrBefore, bornAfter *models.Date) {
+	today := models.NewDate(now)
+	if f.MinAge != nil {
+		d := models.Date{Time: today.AddDate(-*f.MinAge, 0, 0)}
+		bornOnOrBefore = &d
+	}
+	if f.MaxAge != nil {
+		d := models.Date{Time: today.AddDate(-*f.MaxAge-1, 0, 0)}
+		bornAfter = &d
+	}
+	return bornOnOrBefore, bornAfter
+}
+
+// petKey returns the value of a pet in the sort column and its id, for
+// cursors.
+func petKey(column string) func(models.Pet) (string, int64) {
+	return func(pet models.Pet) (string, int64) {
+		switch column {
+		case "name":
+			return petName(&pet), pet.ID
+		case "status":
+			return pet.Status, pet.ID
+		case "price":
+			return strconv.FormatInt(pet.Price, 10), pet.ID
+		default:
+			return "", pet.ID
+		}
+	}
+}
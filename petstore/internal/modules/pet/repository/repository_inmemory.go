@@ -1,128 +1,754 @@
 package repository
 
 import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/infrastructure/wal"
 	"test/internal/models"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// PetStorage_map keeps pets in memory. Every instance has its own data, and
+// pets never leave the store by reference: writes store a copy of the pet
+// and reads hand out copies, the same way rows come out of the database.
+// Categories and tags are resolved by name against a per-instance catalog
+// like the SQL store does against its tables.
 type PetStorage_map struct {
-	logger             *zap.Logger
-	data               []*models.Pet
-	primaryKeyIDx      map[int64]*models.Pet
-	autoIncrementCount int
-	sync.Mutex
-}
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	pets   map[int64]*models.Pet
+	lastID int64
+
+	// secondary indexes from status and tag name to pet ids
+	byStatus map[string]map[int64]struct{}
+	byTag    map[string]map[int64]struct{}
+
+	categories catalog
+	tags       catalog
 
-func NewPetStorage_map(logger *zap.Logger) IPetStorage {
-	models.Pets = make([]*models.Pet, 0, 10000)
-	models.PrimaryKeyIDx = make(map[int64]*models.Pet, 10000)
+	// favorite pets by username with the time they were added, and the
+	// number of favorites of every pet
+	favorites      map[string]map[int64]time.Time
+	favoriteCounts map[int64]int
+
+	// history by pet id, written by the changes it records
+	events      map[int64][]models.PetEvent
+	lastEventID int64
+
+	// persistence, only set up by OpenPetStorage_map
+	log          *wal.Log
+	snapshotPath string
+	seq          uint64
+	pending      int
+	stop, done   chan struct{}
+}
 
+func NewPetStorage_map(logger *zap.Logger) *PetStorage_map {
 	return &PetStorage_map{
-		logger:             logger,
-		primaryKeyIDx:      models.PrimaryKeyIDx,
-		autoIncrementCount: 0,
-		data:               models.Pets,
+		logger:         logger,
+		pets:           make(map[int64]*models.Pet),
+		byStatus:       make(map[string]map[int64]struct{}),
+		byTag:          make(map[string]map[int64]struct{}),
+		categories:     newCatalog(),
+		tags:           newCatalog(),
+		favorites:      make(map[string]map[int64]time.Time),
+		favoriteCounts: make(map[int64]int),
+		events:         make(map[int64][]models.PetEvent),
 	}
 }
 
-func (ps *PetStorage_map) Create(pet *models.Pet) error {
-	ps.Lock()
-	defer ps.Unlock()
-	pet.ID = int64(ps.autoIncrementCount)
-	ps.primaryKeyIDx[pet.ID] = pet
-	ps.autoIncrementCount++
-	ps.data = append(ps.data, pet)
+func (ps *PetStorage_map) Create(pet *models.Pet, record Recorder) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.insert(pet); err != nil {
+		return err
+	}
+	ps.recordEvent(record, pet)
 	return nil
+}
 
+// CreateBatch stores the pets under one lock. Like the SQL store, a pet that
+// fails doesn't stop the others.
+func (ps *PetStorage_map) CreateBatch(pets []*models.Pet, record Recorder) ([]error, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	errs := make([]error, len(pets))
+	for i, pet := range pets {
+		errs[i] = ps.insert(pet)
+		if errs[i] == nil {
+			ps.recordEvent(record, pet)
+		}
+	}
+	return errs, nil
 }
 
-func (ps *PetStorage_map) Update(pet *models.Pet) error {
-	ps.Lock()
-	defer ps.Unlock()
+func (ps *PetStorage_map) insert(pet *models.Pet) error {
+	if err := ps.resolve(pet); err != nil {
+		return err
+	}
 
-	if v, ok := ps.primaryKeyIDx[pet.ID]; ok {
-		*v = *pet
-		return nil
+	ps.lastID++
+	pet.ID = ps.lastID
+	pet.Version = 1
+
+	stored := clonePet(pet)
+	if err := ps.logPut(stored); err != nil {
+		return err
 	}
+	ps.put(stored)
+	return nil
+}
 
-	for i := 0; i < len(ps.data); i++ {
-		if ps.data[i].ID == pet.ID {
-			ps.data[i] = pet
-			return nil
-		}
+// Update writes name and status only if the pet is still at pet.Version, and
+// bumps pet.Version on success.
+func (ps *PetStorage_map) Update(pet *models.Pet, record Recorder) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	stored, ok := ps.pets[pet.ID]
+	if !ok || stored.Version != pet.Version {
+		return ErrEditConflict
+	}
+
+	updated := clonePet(stored)
+	updated.Name = cloneString(pet.Name)
+	updated.Status = pet.Status
+	updated.StatusChangedBy = pet.StatusChangedBy
+	updated.StatusChangedAt = pet.StatusChangedAt
+	updated.Version++
+	if err := ps.logPut(updated); err != nil {
+		return err
 	}
+	ps.remove(stored)
+	ps.put(updated)
 
-	return ErrEditConflict
+	pet.Version = updated.Version
+	ps.recordEvent(record, pet)
+	return nil
 }
 
-func (ps *PetStorage_map) Update_put(pet *models.Pet) error {
-	ps.Lock()
-	defer ps.Unlock()
+// Update_put replaces the pet if it is still at pet.Version. Uploaded photos
+// are kept, as they are only ever added through AddPhoto, and so is the
+// owner, which is set once on create.
+func (ps *PetStorage_map) Update_put(pet *models.Pet, record Recorder) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
 
-	if v, ok := ps.primaryKeyIDx[pet.ID]; ok {
-		*v = *pet
-		return nil
+	stored, ok := ps.pets[pet.ID]
+	if !ok || stored.Version != pet.Version {
+		return ErrEditConflict
+	}
+	if err := ps.resolve(pet); err != nil {
+		return err
 	}
 
-	for i := 0; i < len(ps.data); i++ {
-		if ps.data[i].ID == pet.ID {
-			ps.data[i] = pet
-			return nil
-		}
+	updated := clonePet(pet)
+	updated.Photos = stored.Photos
+	updated.CreatedBy = stored.CreatedBy
+	updated.Version++
+	if err := ps.logPut(updated); err != nil {
+		return err
 	}
+	ps.remove(stored)
+	ps.put(updated)
 
-	return ErrEditConflict
+	pet.Version = updated.Version
+	ps.recordEvent(record, pet)
+	return nil
 }
 
-func (ps *PetStorage_map) Delete(id int64) error {
-	ps.Lock()
-	defer ps.Unlock()
+func (ps *PetStorage_map) AddPhoto(id int64, photo models.Photo) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
 
-	if _, ok := ps.primaryKeyIDx[id]; ok {
-		delete(ps.primaryKeyIDx, id)
+	stored, ok := ps.pets[id]
+	if !ok {
+		return ErrPetNotFound
 	}
 
-	for i := 0; i < len(ps.data); i++ {
-		if ps.data[i].ID == id {
-			ps.data = append(ps.data[:i], ps.data[i+1:]...)
-			return nil
-		}
+	updated := clonePet(stored)
+	updated.PhotoUrls = append(updated.PhotoUrls, photo.URL)
+	updated.Photos = append(updated.Photos, photo)
+	updated.Version++
+	if err := ps.logPut(updated); err != nil {
+		return err
 	}
+	ps.pets[id] = updated
+	return nil
+}
+
+// Delete removes the pet if it is still at version. It returns
+// ErrEditConflict when the pet exists but has been changed since.
+func (ps *PetStorage_map) Delete(id int64, version int, record Recorder) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
 
-	return ErrEditConflict
+	stored, ok := ps.pets[id]
+	if !ok {
+		return ErrPetNotFound
+	}
+	if stored.Version != version {
+		return ErrEditConflict
+	}
+	if err := ps.logDelete(id); err != nil {
+		return err
+	}
 
+	ps.remove(stored)
+	ps.dropFavorites(id)
+	ps.recordEvent(record, nil)
+	return nil
 }
 
 func (ps *PetStorage_map) GetByID(id int64) (*models.Pet, error) {
-	ps.Lock()
-	defer ps.Unlock()
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	stored, ok := ps.pets[id]
+	if !ok {
+		return nil, ErrPetNotFound
+	}
 
-	if v, ok := ps.primaryKeyIDx[id]; ok {
-		return v, nil
+	return ps.read(stored), nil
+}
+
+// GetByTags returns pets carrying any of the given tag names, or all of them
+// when matchAll is set. tags must not contain duplicates.
+func (ps *PetStorage_map) GetByTags(tags []string, matchAll bool) ([]models.Pet, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	minMatches := 1
+	if matchAll {
+		minMatches = len(tags)
 	}
 
-	for i := 0; i < len(ps.data); i++ {
-		if ps.data[i].ID == id {
-			return ps.data[i], nil
+	matches := make(map[int64]int)
+	for _, name := range tags {
+		for id := range ps.byTag[name] {
+			matches[id]++
 		}
 	}
 
-	return nil, ErrPetNotFound
+	ids := make([]int64, 0, len(matches))
+	for id, n := range matches {
+		if n >= minMatches {
+			ids = append(ids, id)
+		}
+	}
 
+	return ps.collect(ids), nil
 }
 
 func (ps *PetStorage_map) GetByStatus(status string) ([]models.Pet, error) {
-	ps.Lock()
-	defer ps.Unlock()
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	ids := make([]int64, 0, len(ps.byStatus[status]))
+	for id := range ps.byStatus[status] {
+		ids = append(ids, id)
+	}
+
+	return ps.collect(ids), nil
+}
+
+// CountByStatus returns the number of pets in every status, read off the
+// status index.
+func (ps *PetStorage_map) CountByStatus() map[string]int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	counts := make(map[string]int, len(ps.byStatus))
+	for status, ids := range ps.byStatus {
+		counts[status] = len(ids)
+	}
+	return counts
+}
+
+func (ps *PetStorage_map) GetAll(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	pets := ps.matching(f, filters)
+	if filters.Keyset {
+		start := sort.Search(len(pets), func(i int) bool { return afterCursor(&pets[i], filters) })
+		end := start + filters.KeysetLimit()
+		if end > len(pets) {
+			end = len(pets)
+		}
+		pets, metadata := filter.KeysetPage(filters, pets[start:end], petKey(filters.SortColumn()))
+		return pets, metadata, nil
+	}
+	metadata := filter.CalculateMetadata(len(pets), filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > len(pets) {
+		start = len(pets)
+	}
+	end := start + filters.Limit()
+	if end > len(pets) {
+		end = len(pets)
+	}
+
+	return pets[start:end], metadata, nil
+}
+
+// Export hands copies of the matching pets to fn after releasing the lock,
+// so a slow consumer doesn't block writers.
+func (ps *PetStorage_map) Export(ctx context.Context, f models.PetFilter, order filter.Filters, fn func(*models.Pet) error) error {
+	ps.mu.RLock()
+	pets := ps.matching(f, order)
+	ps.mu.RUnlock()
+
+	for i := range pets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&pets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSimilar scores the available pets off the status index with the same
+// weights as the SQL store.
+func (ps *PetStorage_map) GetSimilar(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	tags := make(map[string]struct{}, len(pet.Tags))
+	for _, tag := range pet.Tags {
+		tags[tag.Name] = struct{}{}
+	}
+	from, to := similarBirthBounds(pet)
+
+	type scoredPet struct {
+		pet   *models.Pet
+		score int
+	}
+	var scored []scoredPet
+	for id := range ps.byStatus[models.PetStatusAvailable] {
+		other := ps.pets[id]
+		if id == pet.ID {
+			continue
+		}
+
+		score := 0
+		if pet.Category != nil && other.Category != nil && other.Category.ID == pet.Category.ID {
+			score += similarCategoryScore
+		}
+		for _, tag := range other.Tags {
+			if _, ok := tags[tag.Name]; ok {
+				score += similarTagScore
+			}
+		}
+		if pet.Breed != "" && strings.EqualFold(other.Breed, pet.Breed) {
+			score += similarBreedScore
+		}
+		if from != nil && other.BirthDate != nil && !other.BirthDate.Before(from.Time) && !other.BirthDate.After(to.Time) {
+			score += similarAgeScore
+		}
+
+		if score > 0 {
+			scored = append(scored, scoredPet{other, score})
+		}
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return scored[i].pet.ID < scored[j].pet.ID
+	})
+	metadata := filter.CalculateMetadata(len(scored), filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > len(scored) {
+		start = len(scored)
+	}
+	end := start + filters.Limit()
+	if end > len(scored) {
+		end = len(scored)
+	}
+
+	pets := make([]models.Pet, 0, end-start)
+	for _, s := range scored[start:end] {
+		pets = append(pets, *ps.read(s.pet))
+	}
+	return pets, metadata, nil
+}
+
+// resolve points the category and tags of pet at catalog entries, failing
+// for ids the catalog never handed out.
+func (ps *PetStorage_map) resolve(pet *models.Pet) error {
+	if pet.Category != nil {
+		if !ps.categories.resolve(&pet.Category.ID, &pet.Category.Name) {
+			return ErrCategoryNotFound
+		}
+	}
+	for _, tag := range pet.Tags {
+		if !ps.tags.resolve(&tag.ID, &tag.Name) {
+			return ErrTagNotFound
+		}
+	}
+	return nil
+}
+
+// put stores pet and adds it to the indexes. Callers must hold the write lock.
+func (ps *PetStorage_map) put(pet *models.Pet) {
+	ps.pets[pet.ID] = pet
+	addToIndex(ps.byStatus, pet.Status, pet.ID)
+	for _, tag := range pet.Tags {
+		addToIndex(ps.byTag, tag.Name, pet.ID)
+	}
+}
+
+// remove drops pet and its index entries. Callers must hold the write lock.
+func (ps *PetStorage_map) remove(pet *models.Pet) {
+	delete(ps.pets, pet.ID)
+	removeFromIndex(ps.byStatus, pet.Status, pet.ID)
+	for _, tag := range pet.Tags {
+		removeFromIndex(ps.byTag, tag.Name, pet.ID)
+	}
+}
+
+// collect returns copies of the pets with the given ids, ordered by id.
+// Callers must hold the lock.
+func (ps *PetStorage_map) collect(ids []int64) []models.Pet {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	pets := make([]models.Pet, 0, len(ids))
+	for _, id := range ids {
+		pets = append(pets, *ps.read(ps.pets[id]))
+	}
+	return pets
+}
+
+// candidates narrows the pets to scan for f using the indexes, falling back
+// to every pet when f filters on neither status nor tag. Callers must hold
+// the lock.
+func (ps *PetStorage_map) candidates(f models.PetFilter) []*models.Pet {
+	var ids map[int64]struct{}
+	switch {
+	case f.Tag != "":
+		ids = ps.byTag[f.Tag]
+	case len(f.Statuses) > 0:
+		ids = make(map[int64]struct{})
+		for _, status := range f.Statuses {
+			for id := range ps.byStatus[status] {
+				ids[id] = struct{}{}
+			}
+		}
+	default:
+		pets := make([]*models.Pet, 0, len(ps.pets))
+		for _, pet := range ps.pets {
+			pets = append(pets, pet)
+		}
+		return pets
+	}
+
+	pets := make([]*models.Pet, 0, len(ids))
+	for id := range ids {
+		pets = append(pets, ps.pets[id])
+	}
+	return pets
+}
+
+// matching returns copies of the pets matching f in the order asked for by
+// filters. Callers must hold the lock.
+func (ps *PetStorage_map) matching(f models.PetFilter, filters filter.Filters) []models.Pet {
+	bornOnOrBefore, bornAfter := birthDateBounds(f, time.Now())
 
 	var pets []models.Pet
-	for _, pet := range ps.data {
-		if pet.Status == status {
-			pets = append(pets, *pet)
+	for _, pet := range ps.candidates(f) {
+		if matchesPetFilter(pet, f) && bornWithin(pet, bornOnOrBefore, bornAfter) {
+			pets = append(pets, *ps.read(pet))
+		}
+	}
+
+	column, desc := filters.SortColumn(), filters.SortDirection() == "DESC"
+	sort.SliceStable(pets, func(i, j int) bool {
+		a, b := pets[i], pets[j]
+		var less, equal bool
+		switch column {
+		case "name":
+			less, equal = petName(&a) < petName(&b), petName(&a) == petName(&b)
+		case "status":
+			less, equal = a.Status < b.Status, a.Status == b.Status
+		case "price":
+			less, equal = a.Price < b.Price, a.Price == b.Price
+		default:
+			less, equal = a.ID < b.ID, a.ID == b.ID
 		}
+		if equal {
+			return a.ID < b.ID
+		}
+		return less != desc
+	})
+
+	return pets
+}
+
+// afterCursor tells whether pet comes after filters.After in the order of
+// matching.
+func afterCursor(pet *models.Pet, filters filter.Filters) bool {
+	after := filters.After
+	if after == nil {
+		return true
 	}
-	return pets, nil
 
+	cmp := 0
+	switch filters.SortColumn() {
+	case "name":
+		cmp = strings.Compare(petName(pet), after.Value)
+	case "status":
+		cmp = strings.Compare(pet.Status, after.Value)
+	case "price":
+		price, _ := strconv.ParseInt(after.Value, 10, 64)
+		cmp = compareInt64(pet.Price, price)
+	default:
+		cmp = compareInt64(pet.ID, after.ID)
+	}
+	if filters.SortDirection() == "DESC" {
+		cmp = -cmp
+	}
+	if cmp != 0 {
+		return cmp > 0
+	}
+	return pet.ID > after.ID
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func addToIndex(index map[string]map[int64]struct{}, key string, id int64) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[int64]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removeFromIndex(index map[string]map[int64]struct{}, key string, id int64) {
+	delete(index[key], id)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// catalog hands out ids for category and tag names, standing in for the
+// categories and tags tables.
+type catalog struct {
+	ids    map[string]int64
+	names  map[int64]string
+	lastID int64
+}
+
+func newCatalog() catalog {
+	return catalog{ids: make(map[string]int64), names: make(map[int64]string)}
+}
+
+// resolve fills in the name for a known id, or the id for a name, adding the
+// name when it is new. It reports false for an unknown id.
+func (c *catalog) resolve(id *int64, name *string) bool {
+	if *id != 0 {
+		known, ok := c.names[*id]
+		if ok {
+			*name = known
+		}
+		return ok
+	}
+
+	known, ok := c.ids[*name]
+	if !ok {
+		c.lastID++
+		known = c.lastID
+		c.ids[*name] = known
+		c.names[known] = *name
+	}
+	*id = known
+	return true
+}
+
+// add registers a known id and name, as read back from disk, replacing the
+// previous name of id.
+func (c *catalog) add(id int64, name string) {
+	if old, ok := c.names[id]; ok && c.ids[old] == id {
+		delete(c.ids, old)
+	}
+	c.ids[name] = id
+	c.names[id] = name
+	if id > c.lastID {
+		c.lastID = id
+	}
+}
+
+// drop removes id and its name. lastID is kept so the id is not handed out
+// again.
+func (c *catalog) drop(id int64) {
+	if name, ok := c.names[id]; ok && c.ids[name] == id {
+		delete(c.ids, name)
+	}
+	delete(c.names, id)
+}
+
+// read returns the copy of a stored pet handed out by reads, with its
+// favorite count. Callers must hold the lock.
+func (ps *PetStorage_map) read(pet *models.Pet) *models.Pet {
+	c := clonePet(pet)
+	c.FavoriteCount = ps.favoriteCounts[pet.ID]
+	return c
+}
+
+// dropFavorites removes pet id from every user's favorites. Callers must hold
+// the write lock.
+func (ps *PetStorage_map) dropFavorites(id int64) {
+	if ps.favoriteCounts[id] == 0 {
+		return
+	}
+	for username, pets := range ps.favorites {
+		delete(pets, id)
+		if len(pets) == 0 {
+			delete(ps.favorites, username)
+		}
+	}
+	delete(ps.favoriteCounts, id)
+}
+
+// clonePet copies pet deeply enough that neither copy can change the other.
+func clonePet(pet *models.Pet) *models.Pet {
+	c := *pet
+	c.Name = cloneString(pet.Name)
+	if pet.Category != nil {
+		category := *pet.Category
+		c.Category = &category
+	}
+	if pet.PhotoUrls != nil {
+		c.PhotoUrls = append([]string(nil), pet.PhotoUrls...)
+	}
+	if pet.Photos != nil {
+		c.Photos = make(models.Photos, len(pet.Photos))
+		for i, photo := range pet.Photos {
+			c.Photos[i] = photo
+			c.Photos[i].Variants = append([]models.PhotoVariant(nil), photo.Variants...)
+		}
+	}
+	if pet.StatusChangedAt != nil {
+		at := *pet.StatusChangedAt
+		c.StatusChangedAt = &at
+	}
+	if pet.BirthDate != nil {
+		born := *pet.BirthDate
+		c.BirthDate = &born
+	}
+	c.Age = nil
+	c.FavoriteCount = 0
+	if pet.Tags != nil {
+		c.Tags = make([]*models.Tag, len(pet.Tags))
+		for i, tag := range pet.Tags {
+			t := *tag
+			c.Tags[i] = &t
+		}
+	}
+	return &c
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func petName(pet *models.Pet) string {
+	if pet.Name == nil {
+		return ""
+	}
+	return *pet.Name
+}
+
+func matchesPetFilter(pet *models.Pet, f models.PetFilter) bool {
+	if f.Name != "" && !strings.HasPrefix(strings.ToLower(petName(pet)), strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.CategoryID != 0 && (pet.Category == nil || pet.Category.ID != f.CategoryID) {
+		return false
+	}
+	if f.Category != "" && (pet.Category == nil || pet.Category.Name != f.Category) {
+		return false
+	}
+	if f.Tag != "" {
+		found := false
+		for _, tag := range pet.Tags {
+			if tag.Name == f.Tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.CreatedBy != "" && pet.CreatedBy != f.CreatedBy {
+		return false
+	}
+	if f.Breed != "" && !strings.EqualFold(pet.Breed, f.Breed) {
+		return false
+	}
+	if f.MinPrice != nil && pet.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && pet.Price > *f.MaxPrice {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, status := range f.Statuses {
+			if pet.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// bornWithin applies the bounds of birthDateBounds to pet.
+func bornWithin(pet *models.Pet, bornOnOrBefore, bornAfter *models.Date) bool {
+	if bornOnOrBefore == nil && bornAfter == nil {
+		return true
+	}
+	if pet.BirthDate == nil {
+		return false
+	}
+	if bornOnOrBefore != nil && pet.BirthDate.After(bornOnOrBefore.Time) {
+		return false
+	}
+	if bornAfter != nil && !pet.BirthDate.After(bornAfter.Time) {
+		return false
+	}
+	return true
 }
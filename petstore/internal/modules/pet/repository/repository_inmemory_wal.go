@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"test/internal/infrastructure/wal"
+	"test/internal/models"
+)
+
+const (
+	mapSnapshotFile = "pets.snapshot"
+	mapLogFile      = "pets.wal"
+)
+
+// MapPersistence says where and how a PetStorage_map keeps its data across
+// restarts: pets, their categories and tags, and favorites. The other
+// in-memory storages, pet history included, are not persisted and start
+// empty.
+type MapPersistence struct {
+	// directory for the snapshot and the log
+	Dir string
+
+	// when log records are fsynced, see wal.SyncPolicy
+	Fsync         wal.SyncPolicy
+	FsyncInterval time.Duration
+
+	// how often the log is compacted into a snapshot, 0 for only on Close
+	SnapshotInterval time.Duration
+}
+
+// mapRecord is one change in the log: the pet as stored after a write, the id
+// of a deleted pet, a category or tag added, renamed or dropped, or a
+// favorite added or removed. Records
+// are numbered so that replay can skip the ones a snapshot already holds.
+type mapRecord struct {
+	Seq  uint64     `json:"seq"`
+	Op   string     `json:"op"`
+	Pet  *storedPet `json:"pet,omitempty"`
+	ID   int64      `json:"id,omitempty"`
+	Name string     `json:"name,omitempty"`
+	User string     `json:"user,omitempty"`
+	At   *time.Time `json:"at,omitempty"`
+}
+
+const (
+	opPut          = "put"
+	opDelete       = "delete"
+	opCategory     = "category"
+	opDropCategory = "drop_category"
+	opTag          = "tag"
+	opDropTag      = "drop_tag"
+	opFavorite     = "favorite"
+	opUnfavorite   = "unfavorite"
+)
+
+// storedPet is a pet on disk, with the version that the API leaves out.
+type storedPet struct {
+	*models.Pet
+	Version int `json:"version"`
+}
+
+type mapSnapshot struct {
+	Seq        uint64                         `json:"seq"`
+	LastID     int64                          `json:"last_id"`
+	Pets       []storedPet                    `json:"pets"`
+	Categories map[int64]string               `json:"categories"`
+	Tags       map[int64]string               `json:"tags"`
+	Favorites  map[string]map[int64]time.Time `json:"favorites,omitempty"`
+
+	// the last ids handed out, which dropped categories and tags may leave
+	// higher than any still in the catalog
+	LastCategoryID int64 `json:"last_category_id,omitempty"`
+	LastTagID      int64 `json:"last_tag_id,omitempty"`
+}
+
+// OpenPetStorage_map returns a PetStorage_map that logs every change under
+// p.Dir before applying it. The last snapshot and the log written after it
+// are loaded first, so the storage starts where the previous process
+// stopped. Close must be called to stop the snapshot loop and close the log.
+func OpenPetStorage_map(p MapPersistence, logger *zap.Logger) (*PetStorage_map, error) {
+	ps := NewPetStorage_map(logger)
+	ps.snapshotPath = filepath.Join(p.Dir, mapSnapshotFile)
+
+	if err := ps.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	log, err := wal.Open(filepath.Join(p.Dir, mapLogFile), p.Fsync, p.FsyncInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := 0
+	err = log.Replay(func(payload []byte) error {
+		var rec mapRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return err
+		}
+		if rec.Seq <= ps.seq {
+			return nil
+		}
+		ps.apply(rec)
+		ps.seq = rec.Seq
+		ps.pending++
+		replayed++
+		return nil
+	})
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+	ps.log = log
+
+	logger.Info("pet storage restored",
+		zap.String("dir", p.Dir),
+		zap.Int("pets", len(ps.pets)),
+		zap.Int("replayed", replayed))
+
+	if p.SnapshotInterval > 0 {
+		ps.stop, ps.done = make(chan struct{}), make(chan struct{})
+		go ps.snapshotEvery(p.SnapshotInterval)
+	}
+	return ps, nil
+}
+
+func (ps *PetStorage_map) loadSnapshot() error {
+	data, err := os.ReadFile(ps.snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap mapSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	for id, name := range snap.Categories {
+		ps.categories.add(id, name)
+	}
+	for id, name := range snap.Tags {
+		ps.tags.add(id, name)
+	}
+	if snap.LastCategoryID > ps.categories.lastID {
+		ps.categories.lastID = snap.LastCategoryID
+	}
+	if snap.LastTagID > ps.tags.lastID {
+		ps.tags.lastID = snap.LastTagID
+	}
+	for i := range snap.Pets {
+		ps.apply(mapRecord{Op: opPut, Pet: &snap.Pets[i]})
+	}
+	for username, favorites := range snap.Favorites {
+		for id, at := range favorites {
+			ps.addFavorite(username, id, at)
+		}
+	}
+	ps.seq = snap.Seq
+	if snap.LastID > ps.lastID {
+		ps.lastID = snap.LastID
+	}
+	return nil
+}
+
+// apply replays rec onto the storage. Callers must hold the write lock.
+func (ps *PetStorage_map) apply(rec mapRecord) {
+	switch rec.Op {
+	case opPut:
+		pet := rec.Pet.Pet
+		pet.Version = rec.Pet.Version
+		if stored, ok := ps.pets[pet.ID]; ok {
+			ps.remove(stored)
+		}
+		if pet.Category != nil {
+			ps.categories.add(pet.Category.ID, pet.Category.Name)
+		}
+		for _, tag := range pet.Tags {
+			ps.tags.add(tag.ID, tag.Name)
+		}
+		ps.put(pet)
+		if pet.ID > ps.lastID {
+			ps.lastID = pet.ID
+		}
+	case opDelete:
+		if stored, ok := ps.pets[rec.ID]; ok {
+			ps.remove(stored)
+		}
+		ps.dropFavorites(rec.ID)
+		if rec.ID > ps.lastID {
+			ps.lastID = rec.ID
+		}
+	case opCategory:
+		ps.renameCategory(rec.ID, rec.Name)
+	case opDropCategory:
+		ps.categories.drop(rec.ID)
+	case opTag:
+		ps.tags.add(rec.ID, rec.Name)
+	case opDropTag:
+		ps.dropTag(rec.ID)
+	case opFavorite:
+		ps.addFavorite(rec.User, rec.ID, *rec.At)
+	case opUnfavorite:
+		ps.removeFavorite(rec.User, rec.ID)
+	}
+}
+
+// logPut records pet as stored before it is applied. Callers must hold the
+// write lock, which keeps the log in the order changes are applied.
+func (ps *PetStorage_map) logPut(pet *models.Pet) error {
+	return ps.append(mapRecord{Op: opPut, Pet: &storedPet{Pet: pet, Version: pet.Version}})
+}
+
+// logDelete records the deletion of the pet with id, like logPut.
+func (ps *PetStorage_map) logDelete(id int64) error {
+	return ps.append(mapRecord{Op: opDelete, ID: id})
+}
+
+func (ps *PetStorage_map) append(rec mapRecord) error {
+	if ps.log == nil {
+		return nil
+	}
+
+	rec.Seq = ps.seq + 1
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := ps.log.Append(payload); err != nil {
+		ps.logger.Error("error on writing pet log", zap.Error(err))
+		return err
+	}
+	ps.seq = rec.Seq
+	ps.pending++
+	return nil
+}
+
+// Snapshot writes the whole storage to the snapshot file and empties the
+// log. Writes wait while it runs.
+func (ps *PetStorage_map) Snapshot() error {
+	if ps.log == nil {
+		return nil
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	snap := mapSnapshot{
+		Seq:        ps.seq,
+		LastID:     ps.lastID,
+		Pets:       make([]storedPet, 0, len(ps.pets)),
+		Categories: ps.categories.names,
+		Tags:       ps.tags.names,
+		Favorites:  ps.favorites,
+
+		LastCategoryID: ps.categories.lastID,
+		LastTagID:      ps.tags.lastID,
+	}
+	for _, pet := range ps.pets {
+		snap.Pets = append(snap.Pets, storedPet{Pet: pet, Version: pet.Version})
+	}
+	sort.Slice(snap.Pets, func(i, j int) bool { return snap.Pets[i].ID < snap.Pets[j].ID })
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := wal.WriteFile(ps.snapshotPath, data); err != nil {
+		ps.logger.Error("error on writing pet snapshot", zap.Error(err))
+		return err
+	}
+
+	// A crash before the reset leaves records the snapshot already holds;
+	// replay skips them by their sequence number.
+	if err := ps.log.Reset(); err != nil {
+		ps.logger.Error("error on resetting pet log", zap.Error(err))
+		return err
+	}
+	ps.pending = 0
+	return nil
+}
+
+func (ps *PetStorage_map) snapshotEvery(interval time.Duration) {
+	defer close(ps.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ps.mu.RLock()
+			pending := ps.pending
+			ps.mu.RUnlock()
+			if pending > 0 {
+				ps.Snapshot()
+			}
+		case <-ps.stop:
+			return
+		}
+	}
+}
+
+// Close takes a last snapshot and closes the log. It does nothing for a
+// storage that isn't persisted.
+func (ps *PetStorage_map) Close() error {
+	if ps.log == nil {
+		return nil
+	}
+
+	if ps.stop != nil {
+		close(ps.stop)
+		<-ps.done
+		ps.stop = nil
+	}
+
+	err := ps.Snapshot()
+	if cerr := ps.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -6,8 +6,11 @@ import (
 
 	//"fmt"
 
+	"context"
 	"errors"
+	"time"
 
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 )
 
@@ -15,14 +18,52 @@ var (
 	ErrPetNotFound       = errors.New("pet not found")
 	ErrEditConflict      = errors.New("edit conflict")
 	ErrDuplicateCategory = errors.New("duplicate pet category")
+	ErrCategoryNotFound  = errors.New("pet category not found")
+	ErrCategoryInUse     = errors.New("pet category in use")
+	ErrTagNotFound       = errors.New("pet tag not found")
 	ErrDuplicateTag      = errors.New("duplicate pet tag")
+	ErrFavoriteNotFound  = errors.New("favorite not found")
+
+	// ErrHistoryNotRecorded fails a write whose history entry couldn't be
+	// stored. The write is rolled back with it.
+	ErrHistoryNotRecorded = errors.New("pet history not recorded")
 )
 
+// Recorder builds the history entry of a pet write from the pet as written,
+// or nil for a deletion. It is called inside the write, so that the change
+// and its history are stored together or not at all. A nil Recorder, or a
+// nil entry, records nothing.
+type Recorder func(pet *models.Pet) *models.PetEvent
+
 type IPetStorage interface {
-	Create(pet *models.Pet) error
-	Update(pet *models.Pet) error
-	Update_put(pet *models.Pet) error
-	Delete(id int64) error
+	Create(pet *models.Pet, record Recorder) error
+	CreateBatch(pets []*models.Pet, record Recorder) ([]error, error)
+	Update(pet *models.Pet, record Recorder) error
+	Update_put(pet *models.Pet, record Recorder) error
+	AddPhoto(id int64, photo models.Photo) error
+	Delete(id int64, version int, record Recorder) error
 	GetByID(id int64) (*models.Pet, error)
 	GetByStatus(status string) ([]models.Pet, error)
+	GetByTags(tags []string, matchAll bool) ([]models.Pet, error)
+	GetAll(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	GetSimilar(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	Export(ctx context.Context, f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error
+}
+
+// IPetEventStorage reads the change history of pets, which the pet storage
+// writes along with every change.
+type IPetEventStorage interface {
+	GetByPet(petID int64) ([]models.PetEvent, error)
+}
+
+// IFavoriteStorage keeps the pets users have bookmarked. Favorites go with
+// their pet when it is deleted.
+type IFavoriteStorage interface {
+	// Add bookmarks petID for username at the given time. Adding a favorite
+	// again keeps the first time.
+	Add(username string, petID int64, at time.Time) error
+	Remove(username string, petID int64) error
+	// GetByUser returns one page of the favorite pets of username, most
+	// recently added first.
+	GetByUser(username string, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
 }
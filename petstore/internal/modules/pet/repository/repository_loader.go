@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"test/internal/models"
+)
+
+// selectPets is the common head of every pet read. Queries passed to loadPets
+// extend it with their own WHERE/ORDER BY clauses so that scanPet stays the
+// single place that knows the column order. The favorite count is looked up
+// on the pet_id index of favorites.
+const selectPets = `
+	SELECT pets.id, pets.category_id, pets.name, pets.status, pets.photo_urls, pets.photos,
+		pets.status_changed_by, pets.status_changed_at, pets.version,
+		pets.breed, pets.birth_date, pets.sex, pets.weight_grams, pets.description, pets.price,
+		pets.created_by,
+		(SELECT count(*) FROM favorites WHERE favorites.pet_id = pets.id),
+		categories.name
+FROM pets
+INNER JOIN categories ON pets.category_id = categories.id
+`
+
+// tagBatchSize caps the number of pet ids sent in one tag query, keeping it
+// well below the bind parameter limits of the drivers we support.
+const tagBatchSize = 1000
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPet(row rowScanner) (models.Pet, error) {
+	pet := models.Pet{
+		Category: &models.Category{},
+	}
+
+	err := row.Scan(
+		&pet.ID,
+		&pet.Category.ID,
+		&pet.Name,
+		&pet.Status,
+		pq.Array(&pet.PhotoUrls),
+		&pet.Photos,
+		&pet.StatusChangedBy,
+		&pet.StatusChangedAt,
+		&pet.Version,
+		&pet.Breed,
+		&pet.BirthDate,
+		&pet.Sex,
+		&pet.WeightGrams,
+		&pet.Description,
+		&pet.Price,
+		&pet.CreatedBy,
+		&pet.FavoriteCount,
+		&pet.Category.Name,
+	)
+
+	return pet, err
+}
+
+// loadPets runs a selectPets query and attaches tags to every returned pet.
+// Tags are fetched in batches by pet id, so a listing costs one query for the
+// pets plus one per tagBatchSize pets rather than one per pet.
+func (ps *PetStorage) loadPets(ctx context.Context, query string, args ...any) ([]models.Pet, error) {
+	rows, err := ps.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pets := []models.Pet{}
+	for rows.Next() {
+		pet, err := scanPet(rows)
+		if err != nil {
+			return nil, err
+		}
+		pets = append(pets, pet)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	ids := make([]int64, len(pets))
+	for i := range pets {
+		ids[i] = pets[i].ID
+	}
+
+	tags, err := ps.loadTags(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pets {
+		pets[i].Tags = tags[pets[i].ID]
+	}
+
+	return pets, nil
+}
+
+// loadTags returns the tags of the given pets keyed by pet id.
+func (ps *PetStorage) loadTags(ctx context.Context, ids []int64) (map[int64][]*models.Tag, error) {
+	tags := make(map[int64][]*models.Tag, len(ids))
+
+	for start := 0; start < len(ids); start += tagBatchSize {
+		end := start + tagBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		query, args, err := sqlx.In(`
+	SELECT pet_tags.pet_id, tags.id, tags.name
+FROM pet_tags
+INNER JOIN tags ON pet_tags.tag_id = tags.id
+WHERE pet_tags.pet_id IN (?)
+ORDER BY pet_tags.pet_id, tags.id
+`, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		err = ps.scanTags(ctx, ps.DB.Rebind(query), args, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+func (ps *PetStorage) scanTags(ctx context.Context, query string, args []any, tags map[int64][]*models.Tag) error {
+	rows, err := ps.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var petID int64
+		var tag models.Tag
+		err := rows.Scan(&petID, &tag.ID, &tag.Name)
+		if err != nil {
+			return err
+		}
+		tags[petID] = append(tags[petID], &tag)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+// Weights of the similarity score. A pet counts as similar when it scores
+// above zero, i.e. has at least one of these in common with the other pet.
+const (
+	similarCategoryScore = 3
+	similarTagScore      = 2 // per shared tag
+	similarBreedScore    = 2
+	similarAgeScore      = 1
+)
+
+// similarAgeYears is how far apart two dates of birth may be for the pets to
+// count as the same age.
+const similarAgeYears = 1
+
+// similarBirthBounds returns the dates of birth that count as the same age as
+// pet, or nil when pet has no date of birth.
+func similarBirthBounds(pet *models.Pet) (from, to *models.Date) {
+	if pet.BirthDate == nil {
+		return nil, nil
+	}
+	from = &models.Date{Time: pet.BirthDate.AddDate(-similarAgeYears, 0, 0)}
+	to = &models.Date{Time: pet.BirthDate.AddDate(similarAgeYears, 0, 0)}
+	return from, to
+}
+
+// similarScore builds the score of the current row of pets against pet. Every
+// term is a plain comparison or an index lookup on pet_tags, so the query
+// reads each available pet once. The expression uses ? placeholders.
+func similarScore(pet *models.Pet) (string, []any, error) {
+	terms := []string{}
+	args := []any{}
+
+	if pet.Category != nil {
+		terms = append(terms, `CASE WHEN pets.category_id = ? THEN ? ELSE 0 END`)
+		args = append(args, pet.Category.ID, similarCategoryScore)
+	}
+
+	if len(pet.Tags) > 0 {
+		ids := make([]int64, len(pet.Tags))
+		for i, tag := range pet.Tags {
+			ids[i] = tag.ID
+		}
+		term, tagArgs, err := sqlx.In(`? * (SELECT count(*) FROM pet_tags
+		WHERE pet_tags.pet_id = pets.id AND pet_tags.tag_id IN (?))`, similarTagScore, ids)
+		if err != nil {
+			return "", nil, err
+		}
+		terms = append(terms, term)
+		args = append(args, tagArgs...)
+	}
+
+	if pet.Breed != "" {
+		terms = append(terms, `CASE WHEN lower(pets.breed) = lower(?) THEN ? ELSE 0 END`)
+		args = append(args, pet.Breed, similarBreedScore)
+	}
+
+	if from, to := similarBirthBounds(pet); from != nil {
+		terms = append(terms, `CASE WHEN pets.birth_date BETWEEN ? AND ? THEN ? ELSE 0 END`)
+		args = append(args, *from, *to, similarAgeScore)
+	}
+
+	if len(terms) == 0 {
+		return "0", args, nil
+	}
+	return strings.Join(terms, "\n\t\t+ "), args, nil
+}
+
+// GetSimilar returns one page of the available pets other than pet, most
+// similar first and by id among equals. Pets with nothing in common with pet
+// are left out.
+func (ps *PetStorage) GetSimilar(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	score, args, err := similarScore(pet)
+	if err != nil {
+		ps.logger.Error("error on building similarity score", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	scored := `WITH scored AS (
+	SELECT pets.id, ` + score + ` AS score
+	FROM pets
+	WHERE pets.status = ? AND pets.id <> ?
+)
+`
+	args = append(args, models.PetStatusAvailable, pet.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	totalRecords := 0
+	err = ps.DB.QueryRowContext(ctx, ps.DB.Rebind(scored+`SELECT count(*) FROM scored WHERE score > 0`), args...).Scan(&totalRecords)
+	if err != nil {
+		ps.logger.Error("some error on counting similar pets", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	query := ps.DB.Rebind(scored + selectPets + `INNER JOIN scored ON scored.id = pets.id
+WHERE scored.score > 0
+ORDER BY scored.score DESC, pets.id ASC
+LIMIT ? OFFSET ?`)
+
+	pets, err := ps.loadPets(ctx, query, append(args, filters.Limit(), filters.Offset())...)
+	if err != nil {
+		ps.logger.Error("some error on listing similar pets", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	metadata := filter.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return pets, metadata, nil
+}
@@ -1,8 +1,19 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"test/internal/infrastructure/dbtest"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/infrastructure/wal"
 	"test/internal/models"
 	"testing"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 func NewMockStorage() *MockStorage {
@@ -17,6 +28,9 @@ func NewMockStorage() *MockStorage {
 		Update_put_mock: func(pet *models.Pet) error {
 			return nil
 		},
+		AddPhoto_mock: func(id int64, photo models.Photo) error {
+			return nil
+		},
 		Delete_mock: func(id int64) error {
 			return nil
 		},
@@ -26,32 +40,52 @@ func NewMockStorage() *MockStorage {
 		GetByStatus_mock: func(status string) ([]models.Pet, error) {
 			return []models.Pet{}, nil
 		},
+		GetByTags_mock: func(tags []string, matchAll bool) ([]models.Pet, error) {
+			return []models.Pet{}, nil
+		},
+		GetAll_mock: func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+			return []models.Pet{}, filter.Metadata{}, nil
+		},
 	}
 
 }
 
 type MockStorage struct {
 	Create_mock      func(pet *models.Pet) error
+	CreateBatch_mock func(pets []*models.Pet) ([]error, error)
 	Update_mock      func(pet *models.Pet) error
 	Update_put_mock  func(pet *models.Pet) error
+	AddPhoto_mock    func(id int64, photo models.Photo) error
 	Delete_mock      func(id int64) error
 	GetByID_mock     func(id int64) (*models.Pet, error)
 	GetByStatus_mock func(status string) ([]models.Pet, error)
+	GetByTags_mock   func(tags []string, matchAll bool) ([]models.Pet, error)
+	GetAll_mock      func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	GetSimilar_mock  func(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	Export_mock      func(f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error
 }
 
-func (m *MockStorage) Create(pet *models.Pet) error {
+func (m *MockStorage) Create(pet *models.Pet, record Recorder) error {
 	return m.Create_mock(pet)
 }
 
-func (m *MockStorage) Update(pet *models.Pet) error {
+func (m *MockStorage) CreateBatch(pets []*models.Pet, record Recorder) ([]error, error) {
+	return m.CreateBatch_mock(pets)
+}
+
+func (m *MockStorage) Update(pet *models.Pet, record Recorder) error {
 	return m.Update_mock(pet)
 }
 
-func (m *MockStorage) Update_put(pet *models.Pet) error {
+func (m *MockStorage) Update_put(pet *models.Pet, record Recorder) error {
 	return m.Update_put_mock(pet)
 }
 
-func (m *MockStorage) Delete(id int64) error {
+func (m *MockStorage) AddPhoto(id int64, photo models.Photo) error {
+	return m.AddPhoto_mock(id, photo)
+}
+
+func (m *MockStorage) Delete(id int64, version int, record Recorder) error {
 	return m.Delete_mock(id)
 }
 
@@ -63,25 +97,41 @@ func (m *MockStorage) GetByStatus(status string) ([]models.Pet, error) {
 	return m.GetByStatus_mock(status)
 }
 
+func (m *MockStorage) GetByTags(tags []string, matchAll bool) ([]models.Pet, error) {
+	return m.GetByTags_mock(tags, matchAll)
+}
+
+func (m *MockStorage) GetAll(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	return m.GetAll_mock(f, filters)
+}
+
+func (m *MockStorage) GetSimilar(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	return m.GetSimilar_mock(pet, filters)
+}
+
+func (m *MockStorage) Export(ctx context.Context, f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error {
+	return m.Export_mock(f, sort, fn)
+}
+
 func TestRepo(t *testing.T) {
 	petRepository := NewMockStorage()
 
 	t.Run("Create", func(t *testing.T) {
-		resp := petRepository.Create(&models.Pet{})
+		resp := petRepository.Create(&models.Pet{}, nil)
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
 	})
 
 	t.Run("Update", func(t *testing.T) {
-		resp := petRepository.Update(&models.Pet{})
+		resp := petRepository.Update(&models.Pet{}, nil)
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
 	})
 
 	t.Run("Delete", func(t *testing.T) {
-		resp := petRepository.Delete(0)
+		resp := petRepository.Delete(0, 0, nil)
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
@@ -101,3 +151,1105 @@ func TestRepo(t *testing.T) {
 		}
 	})
 }
+
+const sqliteSchema = `
+CREATE TABLE categories (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE);
+CREATE TABLE pets (
+	id INTEGER PRIMARY KEY,
+	category_id INTEGER REFERENCES categories(id),
+	name TEXT NOT NULL,
+	status TEXT NOT NULL,
+	photo_urls TEXT,
+	photos TEXT NOT NULL DEFAULT '[]',
+	status_changed_by TEXT NOT NULL DEFAULT '',
+	status_changed_at TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	breed TEXT NOT NULL DEFAULT '',
+	birth_date DATE,
+	sex TEXT NOT NULL DEFAULT '',
+	weight_grams INTEGER NOT NULL DEFAULT 0,
+	description TEXT NOT NULL DEFAULT '',
+	price INTEGER NOT NULL DEFAULT 0,
+	created_by TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE pet_tags (
+	pet_id INTEGER REFERENCES pets(id) ON DELETE CASCADE,
+	tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (pet_id, tag_id)
+);
+CREATE TABLE favorites (
+	username TEXT NOT NULL,
+	pet_id INTEGER NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (username, pet_id)
+);
+CREATE TABLE pet_events (
+	id INTEGER PRIMARY KEY,
+	pet_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	changes TEXT NOT NULL DEFAULT '{}'
+);
+`
+
+func newSQLiteStorage(tb testing.TB) *PetStorage {
+	tb.Helper()
+
+	db := dbtest.OpenSQLite(tb, sqliteSchema)
+	return NewPetStorage(db, zap.NewNop()).(*PetStorage)
+}
+
+func testPet(name string, tags ...string) *models.Pet {
+	pet := &models.Pet{
+		Name:      &name,
+		Category:  &models.Category{Name: "dogs"},
+		PhotoUrls: []string{"http://example.com/" + name + ".png"},
+		Status:    "available",
+	}
+	for _, tag := range tags {
+		pet.Tags = append(pet.Tags, &models.Tag{Name: tag})
+	}
+	return pet
+}
+
+func countRows(t *testing.T, ps *PetStorage, table string) int {
+	t.Helper()
+	var n int
+	if err := ps.DB.Get(&n, "SELECT count(*) FROM "+table); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPetStorageTransactions(t *testing.T) {
+	t.Run("Create rolls back on failure", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		if _, err := ps.DB.Exec("DROP TABLE pet_tags"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ps.Create(testPet("rex", "friendly"), nil); err == nil {
+			t.Fatal("expected error got nil")
+		}
+
+		for _, table := range []string{"categories", "pets", "tags"} {
+			if n := countRows(t, ps, table); n != 0 {
+				t.Errorf("expected no rows in %s, got %d", table, n)
+			}
+		}
+	})
+
+	t.Run("Update_put replaces tags", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		pet := testPet("rex", "friendly", "small")
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		pet.Tags = []*models.Tag{{Name: "loud"}}
+		if err := ps.Update_put(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ps.GetByID(pet.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Tags) != 1 || got.Tags[0].Name != "loud" {
+			t.Errorf("expected tags [loud], got %v", got.Tags)
+		}
+	})
+
+	t.Run("Update records status change", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		pet := testPet("rex")
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		changedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		pet.Status = models.PetStatusSold
+		pet.StatusChangedBy = "clerk"
+		pet.StatusChangedAt = &changedAt
+		if err := ps.Update(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ps.GetByID(pet.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != models.PetStatusSold || got.StatusChangedBy != "clerk" || got.StatusChangedAt == nil || !got.StatusChangedAt.Equal(changedAt) {
+			t.Errorf("expected sold by clerk at %v, got %s by %q at %v", changedAt, got.Status, got.StatusChangedBy, got.StatusChangedAt)
+		}
+	})
+
+	t.Run("stale versions are rejected", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		pet := testPet("rex", "friendly")
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+		if pet.Version != 1 {
+			t.Fatalf("expected version 1 got %d", pet.Version)
+		}
+
+		stale := *pet
+		if err := ps.Update(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+		if pet.Version != 2 {
+			t.Errorf("expected version 2 got %d", pet.Version)
+		}
+
+		if err := ps.Update(&stale, nil); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected %v for Update got %v", ErrEditConflict, err)
+		}
+		if err := ps.Update_put(&stale, nil); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected %v for Update_put got %v", ErrEditConflict, err)
+		}
+		if err := ps.Delete(stale.ID, stale.Version, nil); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected %v for Delete got %v", ErrEditConflict, err)
+		}
+
+		got, err := ps.GetByID(pet.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != 2 || len(got.Tags) != 1 {
+			t.Errorf("expected untouched pet at version 2, got version %d with %d tags", got.Version, len(got.Tags))
+		}
+	})
+
+	t.Run("CreateBatch skips failing pets", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		bad := testPet("ghost", "friendly")
+		bad.Category = &models.Category{ID: 42}
+		pets := []*models.Pet{testPet("rex", "friendly"), bad, testPet("max", "small")}
+
+		errs, err := ps.CreateBatch(pets, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs[0] != nil || !errors.Is(errs[1], ErrCategoryNotFound) || errs[2] != nil {
+			t.Fatalf("unexpected errors %v", errs)
+		}
+		if pets[0].ID == 0 || pets[1].ID != 0 || pets[2].ID == 0 {
+			t.Errorf("expected ids only for stored pets, got %d %d %d", pets[0].ID, pets[1].ID, pets[2].ID)
+		}
+		if n := countRows(t, ps, "pets"); n != 2 {
+			t.Errorf("expected 2 pets got %d", n)
+		}
+		if n := countRows(t, ps, "pet_tags"); n != 2 {
+			t.Errorf("expected 2 pet-tags got %d", n)
+		}
+	})
+
+	t.Run("Create reuses categories", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		first, second := testPet("rex"), testPet("max")
+		if err := ps.Create(first, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := ps.Create(second, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Category.ID != second.Category.ID {
+			t.Errorf("expected shared category, got %d and %d", first.Category.ID, second.Category.ID)
+		}
+		if n := countRows(t, ps, "categories"); n != 1 {
+			t.Errorf("expected 1 category, got %d", n)
+		}
+
+		missing := testPet("tom")
+		missing.Category = &models.Category{ID: 42}
+		if err := ps.Create(missing, nil); !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("expected %v got %v", ErrCategoryNotFound, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		ps := newSQLiteStorage(t)
+		pet := testPet("rex", "friendly")
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ps.Delete(pet.ID, pet.Version, nil); err != nil {
+			t.Fatal(err)
+		}
+		if n := countRows(t, ps, "pet_tags"); n != 0 {
+			t.Errorf("expected no pet-tags, got %d", n)
+		}
+		if err := ps.Delete(pet.ID, pet.Version, nil); !errors.Is(err, ErrPetNotFound) {
+			t.Errorf("expected %v got %v", ErrPetNotFound, err)
+		}
+	})
+}
+
+func TestPetStorageGetByTags(t *testing.T) {
+	ps := newSQLiteStorage(t)
+	for _, pet := range []*models.Pet{
+		testPet("rex", "friendly", "small"),
+		testPet("max", "friendly"),
+		testPet("tom", "loud"),
+	} {
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := countRows(t, ps, "tags"); n != 3 {
+		t.Errorf("expected tags to be shared, got %d rows", n)
+	}
+
+	names := func(pets []models.Pet) []string {
+		var result []string
+		for _, pet := range pets {
+			result = append(result, *pet.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		tags     []string
+		matchAll bool
+		want     []string
+	}{
+		{"any", []string{"small", "loud"}, false, []string{"rex", "tom"}},
+		{"all", []string{"friendly", "small"}, true, []string{"rex"}},
+		{"none", []string{"unknown"}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pets, err := ps.GetByTags(tt.tags, tt.matchAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(pets); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPetStorageGetByStatus(t *testing.T) {
+	ps := newSQLiteStorage(t)
+	rex, tom := testPet("rex", "friendly", "small"), testPet("tom")
+	tom.Status = "sold"
+	for _, pet := range []*models.Pet{rex, tom, testPet("max", "loud")} {
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pets, err := ps.GetByStatus("available")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pets) != 2 {
+		t.Fatalf("expected 2 pets got %d", len(pets))
+	}
+	if len(pets[0].Tags) != 2 || len(pets[1].Tags) != 1 || pets[1].Tags[0].Name != "loud" {
+		t.Errorf("tags attached to the wrong pets: %v, %v", pets[0].Tags, pets[1].Tags)
+	}
+	if pets[0].Category == nil || pets[0].Category.Name != "dogs" {
+		t.Errorf("expected category dogs got %v", pets[0].Category)
+	}
+}
+
+func TestPetStorageGetAll(t *testing.T) {
+	ps := newSQLiteStorage(t)
+	rex, tom, rexa := testPet("Rex", "friendly"), testPet("tom"), testPet("rexa_2", "friendly")
+	tom.Status = "sold"
+	tom.Category = &models.Category{Name: "cats"}
+	for _, pet := range []*models.Pet{rex, tom, rexa, testPet("max")} {
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page := filter.Filters{Page: 1, PageSize: 2, Sort: "-name", SortSafelist: []string{"id", "-name"}}
+
+	tests := []struct {
+		name  string
+		f     models.PetFilter
+		want  []string
+		total int
+	}{
+		{"all pets paginated", models.PetFilter{}, []string{"tom", "rexa_2"}, 4},
+		{"name prefix", models.PetFilter{Name: "rex"}, []string{"rexa_2", "Rex"}, 2},
+		{"escaped prefix", models.PetFilter{Name: "rexa_"}, []string{"rexa_2"}, 1},
+		{"category and status", models.PetFilter{Category: "cats", Statuses: []string{"sold", "pending"}}, []string{"tom"}, 1},
+		{"tag", models.PetFilter{Tag: "friendly", CategoryID: rex.Category.ID}, []string{"rexa_2", "Rex"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pets, meta, err := ps.GetAll(tt.f, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, pet := range pets {
+				got = append(got, *pet.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v got %v", tt.want, got)
+			}
+			if meta.TotalRecords != tt.total {
+				t.Errorf("expected %d records got %d", tt.total, meta.TotalRecords)
+			}
+		})
+	}
+}
+
+func TestPetStorageProfileFilters(t *testing.T) {
+	backends := map[string]func(t *testing.T) IPetStorage{
+		"sql":    func(t *testing.T) IPetStorage { return newSQLiteStorage(t) },
+		"memory": func(t *testing.T) IPetStorage { return NewPetStorage_map(zap.NewNop()) },
+	}
+	today := models.NewDate(time.Now())
+	born := func(years, days int) *models.Date {
+		return &models.Date{Time: today.AddDate(-years, 0, days)}
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			ps := newStorage(t)
+
+			puppy, adult, senior, unknown := testPet("puppy"), testPet("adult"), testPet("senior"), testPet("unknown")
+			puppy.Breed, puppy.BirthDate, puppy.Price = "Beagle", born(0, -100), 50000
+			adult.Breed, adult.BirthDate, adult.Price = "beagle", born(3, 0), 30000
+			senior.Breed, senior.BirthDate, senior.Price = "poodle", born(9, 1), 10000
+			unknown.Sex, unknown.WeightGrams, unknown.Description = models.PetSexFemale, 4200, "shy"
+			puppy.CreatedBy, unknown.CreatedBy = "alice", "alice"
+			for _, pet := range []*models.Pet{puppy, adult, senior, unknown} {
+				if err := ps.Create(pet, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := ps.GetByID(adult.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Breed != "beagle" || got.BirthDate == nil || !got.BirthDate.Equal(adult.BirthDate.Time) || got.Price != 30000 {
+				t.Errorf("profile not stored: %+v", got)
+			}
+			got, err = ps.GetByID(unknown.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.BirthDate != nil || got.Sex != models.PetSexFemale || got.WeightGrams != 4200 || got.Description != "shy" {
+				t.Errorf("profile not stored: %+v", got)
+			}
+
+			got.CreatedBy = "bob"
+			if err := ps.Update_put(got, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got, err = ps.GetByID(unknown.ID); err != nil || got.CreatedBy != "alice" {
+				t.Errorf("expected the owner to survive a full update, got %+v: %v", got, err)
+			}
+
+			intp := func(n int) *int { return &n }
+			int64p := func(n int64) *int64 { return &n }
+			page := filter.Filters{Page: 1, PageSize: 10, Sort: "-price", SortSafelist: []string{"-price"}}
+
+			tests := []struct {
+				name string
+				f    models.PetFilter
+				want []string
+			}{
+				{"breed", models.PetFilter{Breed: "BEAGLE"}, []string{"puppy", "adult"}},
+				{"owner", models.PetFilter{CreatedBy: "alice"}, []string{"puppy", "unknown"}},
+				{"price range", models.PetFilter{MinPrice: int64p(10000), MaxPrice: int64p(30000)}, []string{"adult", "senior"}},
+				{"min age", models.PetFilter{MinAge: intp(3)}, []string{"adult", "senior"}},
+				{"max age", models.PetFilter{MaxAge: intp(3)}, []string{"puppy", "adult"}},
+				{"age below one", models.PetFilter{MaxAge: intp(0)}, []string{"puppy"}},
+				{"eight turns nine tomorrow", models.PetFilter{MinAge: intp(9)}, nil},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					pets, _, err := ps.GetAll(tt.f, page)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var got []string
+					for _, pet := range pets {
+						got = append(got, *pet.Name)
+					}
+					if !slices.Equal(got, tt.want) {
+						t.Errorf("expected %v got %v", tt.want, got)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestPetStorageExport(t *testing.T) {
+	ps := newSQLiteStorage(t)
+	rex, tom, max := testPet("rex", "friendly", "big"), testPet("tom"), testPet("max", "friendly")
+	tom.Status = "sold"
+	for _, pet := range []*models.Pet{rex, tom, max} {
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sort := filter.Filters{Sort: "name", SortSafelist: []string{"name"}}
+
+	var got []string
+	var tags [][]*models.Tag
+	err := ps.Export(context.Background(), models.PetFilter{Statuses: []string{"available"}}, sort, func(pet *models.Pet) error {
+		got = append(got, *pet.Name)
+		tags = append(tags, pet.Tags)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"max", "rex"}) {
+		t.Fatalf("expected [max rex] got %v", got)
+	}
+	if len(tags[1]) != 2 || tags[1][0].Name != "friendly" || tags[1][1].Name != "big" || tags[1][0].ID == 0 {
+		t.Errorf("unexpected tags %+v", tags[1])
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = ps.Export(context.Background(), models.PetFilter{}, sort, func(pet *models.Pet) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected callback error after one row, got %v after %d", err, calls)
+	}
+}
+
+func TestPetEventStorage(t *testing.T) {
+	ps := newSQLiteStorage(t)
+	es := NewPetEventStorage(ps.DB, zap.NewNop())
+
+	sold := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := func(event models.PetEvent) Recorder {
+		return func(pet *models.Pet) *models.PetEvent {
+			event.PetID = pet.ID
+			return &event
+		}
+	}
+	rex, tom := testPet("rex"), testPet("tom")
+	if err := ps.Create(rex, record(models.PetEvent{Action: models.PetEventCreated, Actor: "clerk", CreatedAt: sold.Add(-time.Hour),
+		Changes: models.PetChanges{"status": {After: models.PetStatusAvailable}}})); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Create(tom, record(models.PetEvent{Action: models.PetEventCreated, Actor: "clerk", CreatedAt: sold})); err != nil {
+		t.Fatal(err)
+	}
+	rex.Status = models.PetStatusSold
+	if err := ps.Update(rex, record(models.PetEvent{Action: models.PetEventStatusChanged, Actor: "alice", CreatedAt: sold,
+		Changes: models.PetChanges{"status": {Before: models.PetStatusAvailable, After: models.PetStatusSold}}})); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Update(tom, func(*models.Pet) *models.PetEvent { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := es.GetByPet(rex.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Action != models.PetEventCreated || got[1].Action != models.PetEventStatusChanged {
+		t.Fatalf("expected rex's events in order, got %+v", got)
+	}
+	last := got[1]
+	if last.Actor != "alice" || !last.CreatedAt.Equal(sold) || last.Changes["status"].After != models.PetStatusSold {
+		t.Errorf("unexpected event %+v", last)
+	}
+	if got, _ := es.GetByPet(tom.ID); len(got) != 1 {
+		t.Errorf("expected a nil event not to be recorded, got %+v", got)
+	}
+
+	t.Run("rolled back with the write", func(t *testing.T) {
+		if _, err := ps.DB.Exec("DROP TABLE pet_events"); err != nil {
+			t.Fatal(err)
+		}
+		created := record(models.PetEvent{Action: models.PetEventCreated})
+
+		if err := ps.Create(testPet("max"), created); !errors.Is(err, ErrHistoryNotRecorded) {
+			t.Fatalf("expected ErrHistoryNotRecorded, got %v", err)
+		}
+		if n := countRows(t, ps, "pets"); n != 2 {
+			t.Errorf("expected the pet not to be stored, got %d pets", n)
+		}
+
+		errs, err := ps.CreateBatch([]*models.Pet{testPet("max")}, created)
+		if err != nil || !errors.Is(errs[0], ErrHistoryNotRecorded) {
+			t.Fatalf("expected the row to fail, got %v %v", errs, err)
+		}
+
+		version := rex.Version
+		rex.Status = models.PetStatusAvailable
+		if err := ps.Update(rex, created); !errors.Is(err, ErrHistoryNotRecorded) {
+			t.Fatalf("expected ErrHistoryNotRecorded, got %v", err)
+		}
+		deleted := func(*models.Pet) *models.PetEvent {
+			return &models.PetEvent{PetID: tom.ID, Action: models.PetEventDeleted}
+		}
+		if err := ps.Delete(tom.ID, tom.Version, deleted); !errors.Is(err, ErrHistoryNotRecorded) {
+			t.Fatalf("expected ErrHistoryNotRecorded, got %v", err)
+		}
+		stored, err := ps.GetByID(rex.ID)
+		if err != nil || stored.Status != models.PetStatusSold || stored.Version != version {
+			t.Errorf("expected rex unchanged, got %+v %v", stored, err)
+		}
+		if n := countRows(t, ps, "pets"); n != 2 {
+			t.Errorf("expected tom to be kept, got %d pets", n)
+		}
+	})
+}
+
+func TestPetEventStorageMap(t *testing.T) {
+	ps := NewPetStorage_map(zap.NewNop())
+	es := NewPetEventStorage_map(ps)
+	record := func(action string) Recorder {
+		return func(pet *models.Pet) *models.PetEvent {
+			return &models.PetEvent{PetID: pet.ID, Action: action}
+		}
+	}
+
+	rex := testPet("rex")
+	if err := ps.Create(rex, record(models.PetEventCreated)); err != nil {
+		t.Fatal(err)
+	}
+	stale := *rex
+	if err := ps.Update(rex, record(models.PetEventStatusChanged)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Update(&stale, record(models.PetEventUpdated)); !errors.Is(err, ErrEditConflict) {
+		t.Fatalf("expected ErrEditConflict, got %v", err)
+	}
+	id := rex.ID
+	if err := ps.Delete(id, rex.Version, func(*models.Pet) *models.PetEvent {
+		return &models.PetEvent{PetID: id, Action: models.PetEventDeleted}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := es.GetByPet(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Action != models.PetEventCreated || got[1].Action != models.PetEventStatusChanged ||
+		got[2].Action != models.PetEventDeleted || got[2].ID != 3 || got[2].CreatedAt.IsZero() {
+		t.Errorf("expected the created, status_changed and deleted events, got %+v", got)
+	}
+}
+
+// TestPetStorageBackends runs the same steps against the SQL and in-memory
+// storages, which must not be told apart by their callers.
+func TestPetStorageBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) IPetStorage{
+		"sql":    func(t *testing.T) IPetStorage { return newSQLiteStorage(t) },
+		"memory": func(t *testing.T) IPetStorage { return NewPetStorage_map(zap.NewNop()) },
+	}
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			ps := newStorage(t)
+
+			rex, tom := testPet("rex", "friendly", "big"), testPet("tom", "friendly")
+			tom.Category = &models.Category{Name: "cats"}
+			for _, pet := range []*models.Pet{rex, tom} {
+				if err := ps.Create(pet, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if rex.ID != 1 || tom.ID != 2 || rex.Version != 1 {
+				t.Fatalf("unexpected ids %d %d or version %d", rex.ID, tom.ID, rex.Version)
+			}
+			if rex.Category.ID == 0 || rex.Category.ID == tom.Category.ID || rex.Tags[0].ID != tom.Tags[0].ID {
+				t.Errorf("categories and tags not resolved: %+v %+v", rex.Category, tom.Tags[0])
+			}
+
+			stale := *rex
+			name := "rexy"
+			rex.Name, rex.Status = &name, "sold"
+			if err := ps.Update(rex, nil); err != nil {
+				t.Fatal(err)
+			}
+			if rex.Version != 2 {
+				t.Errorf("expected version 2 got %d", rex.Version)
+			}
+			if err := ps.Update(&stale, nil); !errors.Is(err, ErrEditConflict) {
+				t.Errorf("expected ErrEditConflict for stale update got %v", err)
+			}
+
+			got, err := ps.GetByID(rex.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got.Name != "rexy" || got.Status != "sold" || len(got.Tags) != 2 || got.Category.Name != "dogs" {
+				t.Errorf("unexpected pet %+v", got)
+			}
+
+			sold, err := ps.GetByStatus("sold")
+			if err != nil || len(sold) != 1 || sold[0].ID != rex.ID {
+				t.Errorf("unexpected sold pets %v: %v", sold, err)
+			}
+			available, err := ps.GetByStatus("available")
+			if err != nil || len(available) != 1 || available[0].ID != tom.ID {
+				t.Errorf("unexpected available pets %v: %v", available, err)
+			}
+
+			anyTag, err := ps.GetByTags([]string{"friendly", "big"}, false)
+			if err != nil || len(anyTag) != 2 || anyTag[0].ID != rex.ID {
+				t.Errorf("unexpected pets by any tag %v: %v", anyTag, err)
+			}
+			all, err := ps.GetByTags([]string{"friendly", "big"}, true)
+			if err != nil || len(all) != 1 || all[0].ID != rex.ID {
+				t.Errorf("unexpected pets by all tags %v: %v", all, err)
+			}
+
+			tom.Tags = []*models.Tag{{Name: "small"}}
+			tom.Status = "pending"
+			if err := ps.Update_put(tom, nil); err != nil {
+				t.Fatal(err)
+			}
+			if pets, _ := ps.GetByTags([]string{"friendly"}, false); len(pets) != 1 {
+				t.Errorf("expected tag index to drop tom, got %v", pets)
+			}
+			pets, meta, err := ps.GetAll(models.PetFilter{Tag: "small", Statuses: []string{"pending"}}, filter.Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}})
+			if err != nil || len(pets) != 1 || pets[0].ID != tom.ID || meta.TotalRecords != 1 {
+				t.Errorf("unexpected filtered pets %v %+v: %v", pets, meta, err)
+			}
+
+			bad := testPet("max")
+			bad.Category = &models.Category{ID: 99}
+			if err := ps.Create(bad, nil); !errors.Is(err, ErrCategoryNotFound) {
+				t.Errorf("expected ErrCategoryNotFound got %v", err)
+			}
+
+			if err := ps.Delete(rex.ID, 1, nil); !errors.Is(err, ErrEditConflict) {
+				t.Errorf("expected ErrEditConflict got %v", err)
+			}
+			if err := ps.Delete(rex.ID, rex.Version, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ps.GetByID(rex.ID); !errors.Is(err, ErrPetNotFound) {
+				t.Errorf("expected ErrPetNotFound got %v", err)
+			}
+			if err := ps.Delete(rex.ID, rex.Version, nil); !errors.Is(err, ErrPetNotFound) {
+				t.Errorf("expected ErrPetNotFound got %v", err)
+			}
+			if sold, _ := ps.GetByStatus("sold"); len(sold) != 0 {
+				t.Errorf("expected status index to drop rex, got %v", sold)
+			}
+		})
+	}
+}
+
+func TestPetStorageMapIsolation(t *testing.T) {
+	a, b := NewPetStorage_map(zap.NewNop()), NewPetStorage_map(zap.NewNop())
+
+	pet := testPet("rex", "friendly")
+	if err := a.Create(pet, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.GetByID(pet.ID); !errors.Is(err, ErrPetNotFound) {
+		t.Errorf("expected instances not to share pets, got %v", err)
+	}
+
+	// Neither the pet handed to Create nor the one handed out by GetByID
+	// may reach into the store.
+	*pet.Name = "changed"
+	pet.Tags[0].Name = "changed"
+	got, err := a.GetByID(pet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Status = "sold"
+	got.PhotoUrls[0] = "changed"
+
+	again, err := a.GetByID(pet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *again.Name != "rex" || again.Tags[0].Name != "friendly" || again.Status != "available" || again.PhotoUrls[0] == "changed" {
+		t.Errorf("stored pet was changed through a reference: %+v", again)
+	}
+	if counts := a.CountByStatus(); counts["available"] != 1 || counts["sold"] != 0 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestPetStorageMapPersistence(t *testing.T) {
+	p := MapPersistence{Dir: t.TempDir(), Fsync: wal.SyncAlways}
+
+	ps, err := OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rex, tom, max := testPet("rex", "friendly"), testPet("tom"), testPet("max")
+	for _, pet := range []*models.Pet{rex, tom, max} {
+		if err := ps.Create(pet, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rex.Status = "sold"
+	if err := ps.Update(rex, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.AddPhoto(tom.ID, models.Photo{URL: "/media/tom.png"}); err != nil {
+		t.Fatal(err)
+	}
+	favorites := NewFavoriteStorage_map(ps)
+	for _, id := range []int64{rex.ID, tom.ID, max.ID} {
+		if err := favorites.Add("alice", id, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := favorites.Remove("alice", tom.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Delete(max.ID, max.Version, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	lastID := max.ID
+	check := func(t *testing.T, ps *PetStorage_map) {
+		t.Helper()
+		got, err := ps.GetByID(rex.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != "sold" || got.Version != 2 || len(got.Tags) != 1 || got.Tags[0].ID == 0 || got.FavoriteCount != 1 {
+			t.Errorf("unexpected rex %+v", got)
+		}
+		if got, _ := ps.GetByID(tom.ID); got == nil || len(got.Photos) != 1 || got.Version != 2 || got.FavoriteCount != 0 {
+			t.Errorf("expected tom's photo to survive, got %+v", got)
+		}
+		if liked, _, _ := NewFavoriteStorage_map(ps).GetByUser("alice", filter.Filters{Page: 1, PageSize: 10}); len(liked) != 1 || liked[0].ID != rex.ID {
+			t.Errorf("expected alice's favorites to survive, got %v", liked)
+		}
+		if _, err := ps.GetByID(max.ID); !errors.Is(err, ErrPetNotFound) {
+			t.Errorf("expected max to stay deleted, got %v", err)
+		}
+		if sold, _ := ps.GetByStatus("sold"); len(sold) != 1 {
+			t.Errorf("expected the status index to be rebuilt, got %v", sold)
+		}
+
+		// Ids are not handed out twice, even the one of the deleted pet.
+		next := testPet("bella", "friendly")
+		if err := ps.Create(next, nil); err != nil {
+			t.Fatal(err)
+		}
+		if next.ID != lastID+1 || next.Tags[0].ID != rex.Tags[0].ID {
+			t.Errorf("unexpected id %d or tag %+v", next.ID, next.Tags[0])
+		}
+		lastID = next.ID
+		if err := ps.Delete(next.ID, next.Version, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A crash: the log is all there is.
+	ps.log.Close()
+	ps, err = OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("replayed from the log", func(t *testing.T) { check(t, ps) })
+
+	// A clean shutdown: everything goes into the snapshot.
+	if err := ps.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(p.Dir, mapLogFile)); err != nil || info.Size() != 0 {
+		t.Fatalf("expected an empty log after the snapshot, got %v %v", info, err)
+	}
+	ps, err = OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+	t.Run("loaded from the snapshot", func(t *testing.T) { check(t, ps) })
+}
+
+func TestPetStorageMapCatalog(t *testing.T) {
+	p := MapPersistence{Dir: t.TempDir(), Fsync: wal.SyncAlways}
+
+	ps, err := OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rex := testPet("rex", "friendly", "calm")
+	if err := ps.Create(rex, nil); err != nil {
+		t.Fatal(err)
+	}
+	dogs := rex.Category.ID
+
+	cats := &models.Category{Name: "cats"}
+	if err := ps.CreateCategory(cats); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.CreateCategory(&models.Category{Name: "cats"}); !errors.Is(err, ErrDuplicateCategory) {
+		t.Errorf("expected ErrDuplicateCategory, got %v", err)
+	}
+	if err := ps.RenameCategory(&models.Category{ID: dogs, Name: "cats"}); !errors.Is(err, ErrDuplicateCategory) {
+		t.Errorf("expected ErrDuplicateCategory, got %v", err)
+	}
+	if err := ps.RenameCategory(&models.Category{ID: dogs, Name: "hounds"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.DeleteCategory(dogs); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("expected ErrCategoryInUse, got %v", err)
+	}
+	if err := ps.DeleteCategory(cats.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.DeleteCategory(cats.ID); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("expected ErrCategoryNotFound, got %v", err)
+	}
+
+	if err := ps.CreateTag(&models.Tag{Name: "calm"}); !errors.Is(err, ErrDuplicateTag) {
+		t.Errorf("expected ErrDuplicateTag, got %v", err)
+	}
+	if err := ps.CreateTag(&models.Tag{Name: "Cuddly"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.DeleteTag(rex.Tags[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(t *testing.T, ps *PetStorage_map) {
+		t.Helper()
+		got, err := ps.GetByID(rex.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Category.Name != "hounds" || len(got.Tags) != 1 || got.Tags[0].Name != "friendly" {
+			t.Errorf("unexpected rex %+v", got)
+		}
+		if calm, _ := ps.GetByTags([]string{"calm"}, false); len(calm) != 0 {
+			t.Errorf("expected the tag index to drop calm, got %v", calm)
+		}
+		if categories := ps.ListCategories(); len(categories) != 1 || *categories[0] != (models.Category{ID: dogs, Name: "hounds"}) {
+			t.Errorf("unexpected categories %+v", categories)
+		}
+		if tags := ps.SearchTags("c", 10); len(tags) != 1 || tags[0].Name != "Cuddly" {
+			t.Errorf("unexpected tags %+v", tags)
+		}
+
+		// A pet in the renamed category finds it by its new name.
+		bella := testPet("bella")
+		bella.Category.Name = "hounds"
+		if err := ps.Create(bella, nil); err != nil {
+			t.Fatal(err)
+		}
+		if bella.Category.ID != dogs {
+			t.Errorf("expected category %d, got %+v", dogs, bella.Category)
+		}
+		if err := ps.Delete(bella.ID, bella.Version, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// The id of the deleted category is not handed out again.
+		next := &models.Category{Name: "birds"}
+		if err := ps.CreateCategory(next); err != nil {
+			t.Fatal(err)
+		}
+		if next.ID <= cats.ID {
+			t.Errorf("expected an id after %d, got %d", cats.ID, next.ID)
+		}
+		if err := ps.DeleteCategory(next.ID); err != nil {
+			t.Fatal(err)
+		}
+		cats.ID = next.ID
+	}
+
+	ps.log.Close()
+	ps, err = OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("replayed from the log", func(t *testing.T) { check(t, ps) })
+
+	if err := ps.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ps, err = OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+	t.Run("loaded from the snapshot", func(t *testing.T) { check(t, ps) })
+}
+
+func TestPetStorageMapSnapshotLoop(t *testing.T) {
+	p := MapPersistence{Dir: t.TempDir(), Fsync: wal.SyncNever, SnapshotInterval: 10 * time.Millisecond}
+
+	ps, err := OpenPetStorage_map(p, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	if err := ps.Create(testPet("rex"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(p.Dir, mapSnapshotFile)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot was taken")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPetStorageGetSimilar(t *testing.T) {
+	backends := map[string]func(t *testing.T) IPetStorage{
+		"sql":    func(t *testing.T) IPetStorage { return newSQLiteStorage(t) },
+		"memory": func(t *testing.T) IPetStorage { return NewPetStorage_map(zap.NewNop()) },
+	}
+	born := func(s string) *models.Date {
+		d, err := models.ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			ps := newStorage(t)
+
+			rex := testPet("rex", "friendly", "big")
+			rex.Breed, rex.BirthDate = "Beagle", born("2020-05-01")
+			twin := testPet("twin", "friendly", "big") // category and both tags
+			twin.Breed = "beagle"
+			pup := testPet("pup", "friendly") // category and one tag
+			pup.BirthDate = born("2021-03-01")
+			old := testPet("old") // category only, too old
+			old.BirthDate = born("2010-01-01")
+			cat := testPet("cat", "big") // one tag, other category
+			cat.Category = &models.Category{Name: "cats"}
+			fish := testPet("fish", "wet") // nothing in common
+			fish.Category = &models.Category{Name: "fish"}
+			sold := testPet("sold", "friendly", "big")
+			sold.Status = "sold"
+
+			for _, pet := range []*models.Pet{rex, twin, pup, old, cat, fish, sold} {
+				if err := ps.Create(pet, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			page := filter.Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}}
+			pets, meta, err := ps.GetSimilar(rex, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []int64{twin.ID, pup.ID, old.ID, cat.ID}
+			if len(pets) != len(want) || meta.TotalRecords != len(want) {
+				t.Fatalf("expected %d similar pets, got %+v %+v", len(want), pets, meta)
+			}
+			for i, id := range want {
+				if pets[i].ID != id {
+					t.Errorf("expected pet %d at %d, got %d", id, i, pets[i].ID)
+				}
+			}
+			if len(pets[0].Tags) != 2 || pets[0].Category.Name != "dogs" {
+				t.Errorf("expected similar pets to be fully loaded, got %+v", pets[0])
+			}
+
+			page.Page, page.PageSize = 2, 3
+			pets, meta, err = ps.GetSimilar(rex, page)
+			if err != nil || len(pets) != 1 || pets[0].ID != cat.ID || meta.LastPage != 2 {
+				t.Errorf("unexpected second page %+v %+v: %v", pets, meta, err)
+			}
+
+			pets, _, err = ps.GetSimilar(fish, filter.Filters{Page: 1, PageSize: 10})
+			if err != nil || len(pets) != 0 {
+				t.Errorf("expected nothing similar to fish, got %+v: %v", pets, err)
+			}
+		})
+	}
+}
+
+// TestPetStorageKeyset walks every listing order by cursor and expects the
+// same pets as a single page by number, duplicate prices and names included.
+func TestPetStorageKeyset(t *testing.T) {
+	backends := map[string]func(t *testing.T) IPetStorage{
+		"sql":    func(t *testing.T) IPetStorage { return newSQLiteStorage(t) },
+		"memory": func(t *testing.T) IPetStorage { return NewPetStorage_map(zap.NewNop()) },
+	}
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			ps := newStorage(t)
+
+			for i, name := range []string{"rex", "tom", "ace", "rex", "max", "bob", "ace"} {
+				pet := testPet(name)
+				pet.Price = int64(i%3) * 1000
+				if i == 5 {
+					pet.Status = "sold"
+				}
+				if err := ps.Create(pet, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, sort := range petSortSafelistForTest {
+				t.Run(sort, func(t *testing.T) {
+					all, _, err := ps.GetAll(models.PetFilter{}, filter.Filters{Page: 1, PageSize: 100, Sort: sort, SortSafelist: petSortSafelistForTest})
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					var walked []int64
+					page := filter.Filters{Page: 1, PageSize: 2, Sort: sort, SortSafelist: petSortSafelistForTest, Keyset: true}
+					for i := 0; i <= len(all); i++ {
+						pets, meta, err := ps.GetAll(models.PetFilter{}, page)
+						if err != nil {
+							t.Fatal(err)
+						}
+						for _, pet := range pets {
+							walked = append(walked, pet.ID)
+						}
+						if meta.NextCursor == "" {
+							break
+						}
+						if page.After, err = filter.DecodeCursor(meta.NextCursor); err != nil {
+							t.Fatal(err)
+						}
+					}
+
+					if len(walked) != len(all) {
+						t.Fatalf("expected %d pets, walked %v", len(all), walked)
+					}
+					for i := range all {
+						if walked[i] != all[i].ID {
+							t.Fatalf("expected order %v, walked %v", all, walked)
+						}
+					}
+				})
+			}
+
+			page := filter.Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}, Keyset: true}
+			pets, meta, err := ps.GetAll(models.PetFilter{Statuses: []string{"sold"}}, page)
+			if err != nil || len(pets) != 1 || meta.NextCursor != "" || meta.TotalRecords != 0 {
+				t.Errorf("unexpected filtered keyset page %+v %+v: %v", pets, meta, err)
+			}
+		})
+	}
+}
+
+var petSortSafelistForTest = []string{"id", "name", "status", "price", "-id", "-name", "-status", "-price"}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"test/internal/infrastructure/filters"
+	"test/internal/models"
+	"test/internal/modules/pet/repository"
+)
+
+var (
+	ErrFavoriteNotFound = errors.New("pet is not among your favorites")
+	ErrNoUser           = errors.New("favorites belong to a signed-in user")
+)
+
+// r.Get("/user/me/favorites", ctrl.FavoriteHandler.FavoriteList)
+// r.Post("/user/me/favorites/{petID}", ctrl.FavoriteHandler.FavoriteAdd)
+// r.Delete("/user/me/favorites/{petID}", ctrl.FavoriteHandler.FavoriteRemove)
+
+// IFavoriteService keeps the favorite pets of the signed-in user.
+type IFavoriteService interface {
+	Add(actor models.Actor, petID int64) error
+	Remove(actor models.Actor, petID int64) error
+	List(actor models.Actor, filters filters.Filters) ([]models.Pet, filters.Metadata, error)
+}
+
+type FavoriteService struct {
+	favorites repository.IFavoriteStorage
+}
+
+func NewFavoriteService(favorites repository.IFavoriteStorage) *FavoriteService {
+	return &FavoriteService{favorites: favorites}
+}
+
+// Add bookmarks a pet whatever its status. Adding it twice is not an error.
+func (s *FavoriteService) Add(actor models.Actor, petID int64) error {
+	if actor.Username == "" {
+		return ErrNoUser
+	}
+	err := s.favorites.Add(actor.Username, petID, time.Now().UTC().Truncate(time.Second))
+	if errors.Is(err, repository.ErrPetNotFound) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+func (s *FavoriteService) Remove(actor models.Actor, petID int64) error {
+	if actor.Username == "" {
+		return ErrNoUser
+	}
+	err := s.favorites.Remove(actor.Username, petID)
+	if errors.Is(err, repository.ErrFavoriteNotFound) {
+		return ErrFavoriteNotFound
+	}
+	return err
+}
+
+// List pages through the favorites of actor as they are now: pets that have
+// been sold since keep their place with their new status, and deleted pets
+// are gone.
+func (s *FavoriteService) List(actor models.Actor, page filters.Filters) ([]models.Pet, filters.Metadata, error) {
+	if actor.Username == "" {
+		return nil, filters.Metadata{}, ErrNoUser
+	}
+	pets, meta, err := s.favorites.GetByUser(actor.Username, page)
+	if err != nil {
+		return nil, meta, err
+	}
+	setAges(pets, time.Now())
+	return pets, meta, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+
+	"test/internal/models"
+	"test/internal/modules/pet/repository"
+)
+
+// petSnapshot flattens the fields tracked by the pet history into plain
+// values, so that snapshots taken before and after a write can be compared
+// and stored as json.
+func petSnapshot(pet *models.Pet) map[string]any {
+	name := ""
+	if pet.Name != nil {
+		name = *pet.Name
+	}
+
+	category := ""
+	if pet.Category != nil {
+		category = pet.Category.Name
+	}
+
+	tags := make([]string, 0, len(pet.Tags))
+	for _, tag := range pet.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+
+	birthDate := ""
+	if pet.BirthDate != nil {
+		birthDate = pet.BirthDate.String()
+	}
+
+	return map[string]any{
+		"name":        name,
+		"category":    category,
+		"status":      pet.Status,
+		"photoUrls":   append([]string{}, pet.PhotoUrls...),
+		"tags":        tags,
+		"breed":       pet.Breed,
+		"birthDate":   birthDate,
+		"sex":         pet.Sex,
+		"weightGrams": pet.WeightGrams,
+		"description": pet.Description,
+		"price":       pet.Price,
+	}
+}
+
+// diffSnapshots returns the fields that differ between two snapshots. A nil
+// snapshot stands for a pet that doesn't exist on that side of the change.
+func diffSnapshots(before, after map[string]any) models.PetChanges {
+	changes := models.PetChanges{}
+	for field := range before {
+		if after == nil || !reflect.DeepEqual(before[field], after[field]) {
+			changes[field] = models.FieldChange{Before: before[field], After: after[field]}
+		}
+	}
+	for field := range after {
+		if before == nil {
+			changes[field] = models.FieldChange{After: after[field]}
+		}
+	}
+	return changes
+}
+
+// recordChange returns the Recorder of a pet created or updated by actor,
+// given the tracked fields before the write.
+func recordChange(actor models.Actor, action string, before map[string]any) repository.Recorder {
+	return func(pet *models.Pet) *models.PetEvent {
+		return newEvent(actor, action, pet.ID, before, petSnapshot(pet))
+	}
+}
+
+// recordDelete returns the Recorder of the deletion of pet by actor.
+func recordDelete(actor models.Actor, pet *models.Pet) repository.Recorder {
+	before := petSnapshot(pet)
+	return func(*models.Pet) *models.PetEvent {
+		return newEvent(actor, models.PetEventDeleted, pet.ID, before, nil)
+	}
+}
+
+// newEvent builds the history entry of a change to petID. Updates that
+// didn't change any tracked field are not recorded, and updates that changed
+// the status are recorded as status changes.
+func newEvent(actor models.Actor, action string, petID int64, before, after map[string]any) *models.PetEvent {
+	changes := diffSnapshots(before, after)
+	if action == models.PetEventUpdated {
+		if len(changes) == 0 {
+			return nil
+		}
+		if _, ok := changes["status"]; ok {
+			action = models.PetEventStatusChanged
+		}
+	}
+
+	return &models.PetEvent{
+		PetID:   petID,
+		Action:  action,
+		Actor:   actor.Username,
+		Changes: changes,
+	}
+}
+
+// History returns every recorded change of a pet, oldest first. It keeps
+// working after the pet has been deleted.
+func (s *PetService) History(petID int64) ([]models.PetEvent, error) {
+	events, err := s.events.GetByPet(petID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		if _, err := s.storage.GetByID(petID); err != nil {
+			return nil, ErrRecordNotFound
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"test/internal/infrastructure/blobstore"
+	"test/internal/infrastructure/imaging"
+	"test/internal/models"
+	"test/internal/modules/pet/repository"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type")
+	ErrImageTooLarge    = errors.New("image is too large")
+)
+
+// maxImagePixels guards thumbnail generation against images that are small
+// on the wire but decode into huge bitmaps.
+const maxImagePixels = 50_000_000
+
+// imageExtensions lists the accepted upload types, keyed by the content type
+// sniffed from the upload itself rather than the one claimed by the client.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageConfig controls where pet images are stored and how they are served.
+// Uploads get one thumbnail per entry in ThumbnailSizes, fitted into a
+// square of that many pixels.
+type ImageConfig struct {
+	Store          blobstore.BlobStore
+	MaxSize        int64
+	URLPrefix      string
+	ThumbnailSizes []int
+}
+
+// UploadImage stores the image read from r together with its thumbnails and
+// adds it to the pet's photos. Like any other change, only the owner and
+// staff may upload.
+func (s *PetService) UploadImage(actor models.Actor, petID int64, r io.Reader) (*models.Pet, error) {
+	pet, err := s.storage.GetByID(petID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	err = checkOwner(actor, pet)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	key, err := imageKey(petID, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &io.LimitedReader{R: io.MultiReader(bytes.NewReader(head), r), N: s.images.MaxSize + 1}
+	err = s.images.Store.Put(key, body)
+	if err != nil {
+		return nil, err
+	}
+	if body.N == 0 {
+		s.images.Store.Delete(key)
+		return nil, ErrImageTooLarge
+	}
+
+	photo := models.Photo{URL: s.images.URLPrefix + key}
+	photo.Variants, err = s.storeThumbnails(key, contentType)
+	if err != nil {
+		s.deletePhoto(key, photo)
+		return nil, err
+	}
+
+	err = s.storage.AddPhoto(petID, photo)
+	if err != nil {
+		s.deletePhoto(key, photo)
+		if errors.Is(err, repository.ErrPetNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return s.GetByID(petID)
+}
+
+// storeThumbnails decodes the stored original and writes one resized copy
+// per configured size. JPEGs stay JPEGs, everything else becomes PNG so that
+// transparency survives. Formats the standard library can't decode (webp)
+// are kept without variants.
+func (s *PetService) storeThumbnails(key, contentType string) ([]models.PhotoVariant, error) {
+	if contentType == "image/webp" || len(s.images.ThumbnailSizes) == 0 {
+		return []models.PhotoVariant{}, nil
+	}
+
+	src, err := s.decodeStored(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := append([]int(nil), s.images.ThumbnailSizes...)
+	sort.Ints(sizes)
+
+	base, ext := strings.TrimSuffix(key, imageExtensions[contentType]), ".png"
+	if contentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+
+	variants := make([]models.PhotoVariant, 0, len(sizes))
+	for _, size := range sizes {
+		thumb := imaging.Thumbnail(src, size)
+
+		buf := &bytes.Buffer{}
+		if ext == ".jpg" {
+			err = jpeg.Encode(buf, thumb, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(buf, thumb)
+		}
+		if err != nil {
+			return variants, err
+		}
+
+		variantKey := fmt.Sprintf("%s_%d%s", base, size, ext)
+		err = s.images.Store.Put(variantKey, buf)
+		if err != nil {
+			return variants, err
+		}
+
+		variants = append(variants, models.PhotoVariant{
+			Size:   size,
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+			URL:    s.images.URLPrefix + variantKey,
+		})
+	}
+
+	return variants, nil
+}
+
+func (s *PetService) decodeStored(key string) (image.Image, error) {
+	file, err := s.images.Store.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrUnsupportedImage
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	return src, nil
+}
+
+func (s *PetService) deletePhoto(key string, photo models.Photo) {
+	s.images.Store.Delete(key)
+	for _, variant := range photo.Variants {
+		s.images.Store.Delete(strings.TrimPrefix(variant.URL, s.images.URLPrefix))
+	}
+}
+
+// OpenImage opens a stored image by the key it was served under.
+func (s *PetService) OpenImage(key string) (blobstore.File, error) {
+	file, err := s.images.Store.Open(key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blobstore.ErrNotFound), errors.Is(err, blobstore.ErrInvalidKey):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func imageKey(petID int64, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pets/%d/%s%s", petID, hex.EncodeToString(b), ext), nil
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/pet/repository"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportFormat is the encoding of an import body.
+type ImportFormat int
+
+const (
+	// ImportCSV is a csv file with a header row naming the columns listed in
+	// importColumns, and maybe those of exportOnlyColumns. Tags and photo
+	// urls hold several values separated by |.
+	ImportCSV ImportFormat = iota
+	// ImportNDJSON is one pet per line in the same shape POST /pet accepts.
+	ImportNDJSON
+)
+
+const (
+	// importBatchSize is the number of rows written per transaction.
+	importBatchSize = 100
+	// maxImportLine bounds a single NDJSON line.
+	maxImportLine = 1 << 20
+)
+
+var importColumns = map[string]bool{
+	"name":         true,
+	"category":     true,
+	"category_id":  true,
+	"status":       true,
+	"tags":         true,
+	"photo_urls":   true,
+	"breed":        true,
+	"birth_date":   true,
+	"sex":          true,
+	"weight_grams": true,
+	"description":  true,
+	"price":        true,
+}
+
+// exportOnlyColumns are written by GET /pet/export but set by the service on
+// a new pet. Import accepts and ignores them, so that an export can be
+// imported again as it is.
+var exportOnlyColumns = map[string]bool{
+	"id":                true,
+	"status_changed_by": true,
+	"status_changed_at": true,
+}
+
+// importRow is a parsed row waiting to be validated and stored.
+type importRow struct {
+	pet    *models.Pet
+	errors map[string]string
+}
+
+// Import reads pets from r and creates them in batches of importBatchSize,
+// each batch in one transaction. Rows that fail to parse or validate are
+// reported and skipped; the others are still imported. With dryRun the rows
+// are only validated. Batches stored before an error stay stored: the error
+// is then returned in the report, whose rows tell which pets exist, and the
+// rows still waiting for their batch are reported as not stored.
+func (s *PetService) Import(actor models.Actor, format ImportFormat, r io.Reader, dryRun bool) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: dryRun, Rows: []models.ImportRow{}}
+	batch := make([]*models.Pet, 0, importBatchSize)
+	batchRows := make([]int, 0, importBatchSize)
+	stored := false
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		errs, err := s.storage.CreateBatch(batch, recordChange(actor, models.PetEventCreated, nil))
+		if err != nil {
+			return err
+		}
+		for i, pet := range batch {
+			row := &report.Rows[batchRows[i]]
+			if errs[i] != nil {
+				row.Errors = importStorageError(errs[i])
+				continue
+			}
+			row.ID = pet.ID
+			stored = true
+		}
+		batch, batchRows = batch[:0], batchRows[:0]
+		return nil
+	}
+
+	err := readImport(format, r, func(row importRow) error {
+		report.Total++
+		report.Rows = append(report.Rows, models.ImportRow{Row: report.Total, Errors: row.errors})
+		if row.errors != nil {
+			return nil
+		}
+
+		v := validator.New()
+		if ValidatePet(v, row.pet); !v.Valid() {
+			report.Rows[len(report.Rows)-1].Errors = v.Errors
+			return nil
+		}
+		if dryRun {
+			return nil
+		}
+
+		prepareNew(actor, row.pet)
+		batch = append(batch, row.pet)
+		batchRows = append(batchRows, len(report.Rows)-1)
+		if len(batch) == importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		if !stored {
+			return nil, err
+		}
+		for _, i := range batchRows {
+			if report.Rows[i].ID == 0 {
+				report.Rows[i].Errors = map[string]string{"row": "not stored, the import stopped"}
+			}
+		}
+		report.Error = err.Error()
+	}
+
+	for _, row := range report.Rows {
+		if row.Errors != nil {
+			report.Failed++
+		}
+	}
+	report.Created = report.Total - report.Failed
+	return report, nil
+}
+
+func importStorageError(err error) map[string]string {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return map[string]string{"category": "doesn't exist"}
+	case errors.Is(err, repository.ErrTagNotFound):
+		return map[string]string{"tags": "doesn't exist"}
+	default:
+		return map[string]string{"row": "could not be stored"}
+	}
+}
+
+// readImport calls fn for every row of r in order. Malformed rows are passed
+// on with their errors set; only a broken body stops the import.
+func readImport(format ImportFormat, r io.Reader, fn func(importRow) error) error {
+	if format == ImportNDJSON {
+		return readNDJSON(r, fn)
+	}
+	return readCSV(r, fn)
+}
+
+func readNDJSON(r io.Reader, fn func(importRow) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var pet models.Pet
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		row := importRow{pet: &pet}
+		if err := dec.Decode(&pet); err != nil {
+			row.errors = map[string]string{"row": err.Error()}
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, fn func(importRow) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: reading csv header: %v", ErrInvalidImport, err)
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !importColumns[header[i]] && !exportOnlyColumns[header[i]] {
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidImport, column)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var row importRow
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			row.errors = map[string]string{"row": parseErr.Err.Error()}
+		case err != nil:
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		case len(record) != len(header):
+			row.errors = map[string]string{"row": fmt.Sprintf("has %d fields, expected %d", len(record), len(header))}
+		default:
+			row = csvPet(header, record)
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+func csvPet(header, record []string) importRow {
+	pet := &models.Pet{Category: &models.Category{}, PhotoUrls: []string{}, Tags: []*models.Tag{}}
+	row := importRow{pet: pet}
+
+	for i, column := range header {
+		value := strings.TrimSpace(record[i])
+		switch column {
+		case "name":
+			pet.Name = &value
+		case "category":
+			pet.Category.Name = value
+		case "category_id":
+			if value == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				row.errors = map[string]string{"category_id": "must be an integer value"}
+				return row
+			}
+			pet.Category.ID = id
+		case "status":
+			pet.Status = value
+		case "tags":
+			for _, name := range splitList(value) {
+				pet.Tags = append(pet.Tags, &models.Tag{Name: name})
+			}
+		case "photo_urls":
+			pet.PhotoUrls = splitList(value)
+		case "breed":
+			pet.Breed = value
+		case "birth_date":
+			if value == "" {
+				continue
+			}
+			date, err := models.ParseDate(value)
+			if err != nil {
+				row.errors = map[string]string{"birth_date": "must be a date like 2006-01-02"}
+				return row
+			}
+			pet.BirthDate = &date
+		case "sex":
+			pet.Sex = value
+		case "description":
+			pet.Description = value
+		case "weight_grams", "price":
+			if value == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				row.errors = map[string]string{column: "must be an integer value"}
+				return row
+			}
+			if column == "price" {
+				pet.Price = n
+			} else {
+				pet.WeightGrams = n
+			}
+		}
+	}
+
+	return row
+}
+
+func splitList(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
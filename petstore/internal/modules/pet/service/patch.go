@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"test/internal/infrastructure/jsonpatch"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+)
+
+// PatchFormat tells Patch how to read the patch document.
+type PatchFormat int
+
+const (
+	// MergePatch is an RFC 7396 JSON Merge Patch.
+	MergePatch PatchFormat = iota
+	// JSONPatch is an RFC 6902 JSON Patch.
+	JSONPatch
+)
+
+// Patch applies patch to the JSON document of the pet, validates the result
+// and stores it through Update_put, so status transitions, history and
+// versioning behave exactly as for a full update. Errors from the jsonpatch
+// package are returned as they are.
+func (s *PetService) Patch(actor models.Actor, id int64, version int, format PatchFormat, patch []byte) (*models.Pet, error) {
+	current, err := s.storage.GetByID(id)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	err = checkOwner(actor, current)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersion(current, version)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case JSONPatch:
+		doc, err = jsonpatch.Apply(doc, patch)
+	default:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patched models.Pet
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+
+	unpinRenamed(current, &patched)
+
+	v := validator.New()
+	v.Check(patched.ID == id, "id", "can't be changed")
+	v.Check(patched.CreatedBy == current.CreatedBy, "createdBy", "can't be changed")
+	if ValidatePet(v, &patched); !v.Valid() {
+		return nil, invalid(v)
+	}
+
+	err = s.Update_put(actor, &patched, current.Version)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// unpinRenamed drops the id that the category or a tag of patched kept from
+// current when the patch gave it another name, so that the storage looks it
+// up by its new name instead of keeping the old one by id. A patch that
+// changes the id as well picks a category or tag by id as before.
+func unpinRenamed(current, patched *models.Pet) {
+	if c := patched.Category; c != nil && current.Category != nil &&
+		c.ID == current.Category.ID && c.Name != "" && c.Name != current.Category.Name {
+		c.ID = 0
+	}
+
+	names := make(map[int64]string, len(current.Tags))
+	for _, tag := range current.Tags {
+		names[tag.ID] = tag.Name
+	}
+	for _, tag := range patched.Tags {
+		if name, ok := names[tag.ID]; ok && tag.ID != 0 && tag.Name != "" && tag.Name != name {
+			tag.ID = 0
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"io"
+	"strings"
+	"test/internal/infrastructure/blobstore"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/validator"
 	"test/internal/modules/pet/repository"
+	"time"
 
 	"test/internal/models"
 )
@@ -11,79 +18,236 @@ var (
 	ErrRecordNotFound  = errors.New("record not found")
 	ErrEditConflict    = errors.New("edit conflict")
 	ErrDuplicateRecord = errors.New("duplicate record")
+	ErrNoCategory      = errors.New("category doesn't exist")
+	ErrNoTag           = errors.New("tag doesn't exist")
+	ErrVersionMismatch = errors.New("pet has been modified")
+	ErrForbidden       = errors.New("only the owner or staff can change this pet")
 )
 
+// r.Get("/pet", ctrl.petController.PetList)
 // r.Post("/pet", ctrl.petController.PetCreate)
 // r.Post("/pet/{petID}", ctrl.petController.PetUpdate)
 // r.Get("/pet/{petID}", ctrl.petController.PetGetByID)
 // r.Get("/pet/findByStatus", ctrl.petController.PetGetByStatus)
+// r.Get("/pet/findByTags", ctrl.petController.PetGetByTags)
+// r.Post("/pet/import", ctrl.petController.PetImport)
+// r.Get("/pet/export", ctrl.petController.PetExport)
+// r.Post("/pet/{petID}/uploadImage", ctrl.petController.PetUploadImage)
+// r.Get("/pet/{petID}/history", ctrl.petController.PetHistory)
+// r.Get("/pet/{petID}/similar", ctrl.petController.PetSimilar)
+// r.Get("/user/{username}/pets", ctrl.petController.UserPets)
+// r.Get("/media/*", ctrl.petController.MediaGet)
 // r.Put("/pet/{petID}", ctrl.petController.PetUpdate)
+// r.Patch("/pet/{petID}", ctrl.petController.PetPatch)
 // r.Delete("/pet/{petID}", ctrl.petController.PetDelete)
 
 type IPetstoreService interface {
-	Create(pet *models.Pet) error
-	Update(name, status *string, ID int64) error
-	Update_put(pet *models.Pet) error
-	Delete(id int64) error
+	Create(actor models.Actor, pet *models.Pet) error
+	Update(actor models.Actor, name, status *string, ID int64, version int) error
+	Update_put(actor models.Actor, pet *models.Pet, version int) error
+	Delete(actor models.Actor, id int64, version int) error
 	GetByID(id int64) (*models.Pet, error)
 	GetByStatus(status string) ([]models.Pet, error)
+	GetByTags(tags []string, matchAll bool) ([]models.Pet, error)
+	List(f models.PetFilter, filters filters.Filters) ([]models.Pet, filters.Metadata, error)
+	Similar(id int64, filters filters.Filters) ([]models.Pet, filters.Metadata, error)
+	UploadImage(actor models.Actor, petID int64, r io.Reader) (*models.Pet, error)
+	OpenImage(key string) (blobstore.File, error)
+	History(petID int64) ([]models.PetEvent, error)
+	Patch(actor models.Actor, id int64, version int, format PatchFormat, patch []byte) (*models.Pet, error)
+	Import(actor models.Actor, format ImportFormat, r io.Reader, dryRun bool) (*models.ImportReport, error)
+	Export(ctx context.Context, f models.PetFilter, sort filters.Filters, fn func(*models.Pet) error) error
+	Transition(actor models.Actor, id int64, from, to string) error
 }
 
 type PetService struct {
 	storage repository.IPetStorage
+	events  repository.IPetEventStorage
+	images  ImageConfig
 }
 
-func NewPetService(repo repository.IPetStorage) *PetService {
-	return &PetService{storage: repo}
+func NewPetService(repo repository.IPetStorage, events repository.IPetEventStorage, images ImageConfig) *PetService {
+	return &PetService{storage: repo, events: events, images: images}
 }
 
-func (s *PetService) Create(pet *models.Pet) error {
-	err := s.storage.Create(pet)
+// Create stores a new pet owned by actor. Pets start out available unless the
+// request names another status, which is then recorded as set by actor.
+func (s *PetService) Create(actor models.Actor, pet *models.Pet) error {
+	v := validator.New()
+	if ValidatePet(v, pet); !v.Valid() {
+		return invalid(v)
+	}
+	prepareNew(actor, pet)
+
+	err := s.storage.Create(pet, recordChange(actor, models.PetEventCreated, nil))
 	if err != nil {
-		return ErrDuplicateRecord
+		switch {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			return ErrNoCategory
+		case errors.Is(err, repository.ErrTagNotFound):
+			return ErrNoTag
+		case errors.Is(err, repository.ErrHistoryNotRecorded):
+			return err
+		default:
+			return ErrDuplicateRecord
+		}
 	}
+	setAge(pet, time.Now())
 	return nil
 }
 
-func (s *PetService) Update(name, status *string, ID int64) error {
+// prepareNew fills in what the service owns on a new pet: the owner, the
+// default status and who set it. Photos only ever come from uploads.
+func prepareNew(actor models.Actor, pet *models.Pet) {
+	pet.CreatedBy = actor.Username
+
+	if pet.Status == "" {
+		pet.Status = models.PetStatusAvailable
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	pet.StatusChangedBy = actor.Username
+	pet.StatusChangedAt = &now
+	pet.Photos = nil
+	pet.Age = nil
+}
+
+// Update changes a pet's name and status. A non-zero version makes the
+// update conditional on the pet still being at that version, see
+// checkVersion.
+func (s *PetService) Update(actor models.Actor, name, status *string, ID int64, version int) error {
+	v := validator.New()
+	if validateUpdate(v, name, status); !v.Valid() {
+		return invalid(v)
+	}
 
 	updated, err := s.storage.GetByID(ID)
 	if err != nil {
 		return ErrRecordNotFound
 	}
+	err = checkOwner(actor, updated)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(updated, version)
+	if err != nil {
+		return err
+	}
+	before := petSnapshot(updated)
+	err = changeStatus(updated, *status, actor)
+	if err != nil {
+		return err
+	}
 	updated.Name = name
-	updated.Status = *status
-	err = s.storage.Update(updated)
+	err = s.storage.Update(updated, recordChange(actor, models.PetEventUpdated, before))
 	if err != nil {
+		if errors.Is(err, repository.ErrHistoryNotRecorded) {
+			return err
+		}
 		return ErrEditConflict
 	}
 	return nil
 }
 
-func (s *PetService) Update_put(pet *models.Pet) error {
+func (s *PetService) Update_put(actor models.Actor, pet *models.Pet, version int) error {
+	v := validator.New()
+	if ValidatePet(v, pet); !v.Valid() {
+		return invalid(v)
+	}
 
 	updated, err := s.storage.GetByID(int64(pet.ID))
 	if err != nil {
 		return ErrRecordNotFound
 	}
+	err = checkOwner(actor, updated)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(updated, version)
+	if err != nil {
+		return err
+	}
+	before := petSnapshot(updated)
+	if pet.Status != "" {
+		err = changeStatus(updated, pet.Status, actor)
+		if err != nil {
+			return err
+		}
+	}
 	updated.Name = pet.Name
 	updated.Category.ID = pet.Category.ID
 	updated.Category.Name = pet.Category.Name
-	updated.Status = pet.Status
 	updated.PhotoUrls = pet.PhotoUrls
 	updated.Tags = pet.Tags
+	updated.Breed = pet.Breed
+	updated.BirthDate = pet.BirthDate
+	updated.Sex = pet.Sex
+	updated.WeightGrams = pet.WeightGrams
+	updated.Description = pet.Description
+	updated.Price = pet.Price
 
-	err = s.storage.Update_put(updated)
+	err = s.storage.Update_put(updated, recordChange(actor, models.PetEventUpdated, before))
 	if err != nil {
-		return ErrEditConflict
+		switch {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			return ErrNoCategory
+		case errors.Is(err, repository.ErrTagNotFound):
+			return ErrNoTag
+		case errors.Is(err, repository.ErrHistoryNotRecorded):
+			return err
+		default:
+			return ErrEditConflict
+		}
 	}
+	pet.Version = updated.Version
+	setAge(pet, time.Now())
 	return nil
 }
-func (s *PetService) Delete(id int64) error {
-	err := s.storage.Delete(id)
+
+func (s *PetService) Delete(actor models.Actor, id int64, version int) error {
+	deleted, err := s.storage.GetByID(id)
 	if err != nil {
 		return ErrRecordNotFound
 	}
+	err = checkOwner(actor, deleted)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(deleted, version)
+	if err != nil {
+		return err
+	}
+	err = s.storage.Delete(id, deleted.Version, recordDelete(actor, deleted))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			return ErrEditConflict
+		case errors.Is(err, repository.ErrHistoryNotRecorded):
+			return err
+		default:
+			return ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// checkOwner lets the owner of a pet and staff change it. Pets created
+// before owners were recorded have none and can only be changed by staff.
+func checkOwner(actor models.Actor, pet *models.Pet) error {
+	if actor.IsStaff() || (pet.CreatedBy != "" && pet.CreatedBy == actor.Username) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// checkVersion compares the version a client last saw, usually taken from an
+// If-Match header, with the stored one. Zero means the client didn't ask for
+// a conditional write. The storage re-checks the loaded version when writing,
+// so a change slipping in between is reported as ErrEditConflict.
+func checkVersion(pet *models.Pet, version int) error {
+	if version != 0 && pet.Version != version {
+		return ErrVersionMismatch
+	}
 	return nil
 }
 
@@ -92,6 +256,7 @@ func (s *PetService) GetByID(id int64) (*models.Pet, error) {
 	if err != nil {
 		return nil, ErrRecordNotFound
 	}
+	setAge(pet, time.Now())
 	return pet, nil
 }
 
@@ -100,5 +265,79 @@ func (s *PetService) GetByStatus(status string) ([]models.Pet, error) {
 	if err != nil {
 		return nil, ErrRecordNotFound
 	}
+	setAges(pet, time.Now())
 	return pet, nil
 }
+
+// GetByTags looks pets up by tag name. Names are trimmed and de-duplicated so
+// that matchAll compares against the number of distinct tags asked for.
+func (s *PetService) GetByTags(tags []string, matchAll bool) ([]models.Pet, error) {
+	seen := make(map[string]bool, len(tags))
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		names = append(names, tag)
+	}
+	if len(names) == 0 {
+		return []models.Pet{}, nil
+	}
+
+	pets, err := s.storage.GetByTags(names, matchAll)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	setAges(pets, time.Now())
+	return pets, nil
+}
+
+func (s *PetService) List(f models.PetFilter, filters filters.Filters) ([]models.Pet, filters.Metadata, error) {
+	pets, meta, err := s.storage.GetAll(f, filters)
+	if err != nil {
+		return nil, meta, err
+	}
+	setAges(pets, time.Now())
+	return pets, meta, nil
+}
+
+// Similar lists the available pets sharing the most with pet id: its
+// category, tags, breed and age. See IPetStorage.GetSimilar.
+func (s *PetService) Similar(id int64, page filters.Filters) ([]models.Pet, filters.Metadata, error) {
+	pet, err := s.storage.GetByID(id)
+	if err != nil {
+		return nil, filters.Metadata{}, ErrRecordNotFound
+	}
+	pets, meta, err := s.storage.GetSimilar(pet, page)
+	if err != nil {
+		return nil, meta, err
+	}
+	setAges(pets, time.Now())
+	return pets, meta, nil
+}
+
+// Export streams every pet matching f to fn, see IPetStorage.Export.
+func (s *PetService) Export(ctx context.Context, f models.PetFilter, sort filters.Filters, fn func(*models.Pet) error) error {
+	now := time.Now()
+	return s.storage.Export(ctx, f, sort, func(pet *models.Pet) error {
+		setAge(pet, now)
+		return fn(pet)
+	})
+}
+
+// setAge fills in the age of a pet as of now. Ages are never stored, so a
+// pet without a date of birth has none.
+func setAge(pet *models.Pet, now time.Time) {
+	pet.Age = nil
+	if pet.BirthDate != nil {
+		pet.Age = pet.BirthDate.AgeAt(now)
+	}
+}
+
+func setAges(pets []models.Pet, now time.Time) {
+	for i := range pets {
+		setAge(&pets[i], now)
+	}
+}
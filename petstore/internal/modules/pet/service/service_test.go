@@ -1,9 +1,16 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/infrastructure/validator"
 	"test/internal/models"
+	"test/internal/modules/pet/repository"
 	"testing"
+	"time"
 )
 
 // Create(pet *models.Pet) error
@@ -15,11 +22,20 @@ import (
 
 type MockStorage struct {
 	Create_mock      func(pet *models.Pet) error
+	CreateBatch_mock func(pets []*models.Pet) ([]error, error)
 	Update_mock      func(pet *models.Pet) error
 	Update_put_mock  func(pet *models.Pet) error
+	AddPhoto_mock    func(id int64, photo models.Photo) error
 	Delete_mock      func(id int64) error
 	GetByID_mock     func(id int64) (*models.Pet, error)
 	GetByStatus_mock func(status string) ([]models.Pet, error)
+	GetByTags_mock   func(tags []string, matchAll bool) ([]models.Pet, error)
+	GetAll_mock      func(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	GetSimilar_mock  func(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error)
+	Export_mock      func(f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error
+
+	// History, when set, stores the events of the writes.
+	History *MockEventStorage
 }
 
 func testpetctor() *models.Pet {
@@ -28,25 +44,70 @@ func testpetctor() *models.Pet {
 		ID:        1,
 		Category:  &models.Category{ID: 1, Name: "test"},
 		Name:      &name,
-		PhotoUrls: make([]string, 0),
+		PhotoUrls: []string{"http://example.com/test.png"},
 		Status:    "available",
 		Tags:      make([]*models.Tag, 0),
 	}
 }
-func (m *MockStorage) Create(pet *models.Pet) error {
-	return m.Create_mock(pet)
+func (m *MockStorage) Create(pet *models.Pet, record repository.Recorder) error {
+	if err := m.Create_mock(pet); err != nil {
+		return err
+	}
+	return m.record(record, pet)
 }
 
-func (m *MockStorage) Update(pet *models.Pet) error {
-	return m.Update_mock(pet)
+func (m *MockStorage) CreateBatch(pets []*models.Pet, record repository.Recorder) ([]error, error) {
+	errs, err := m.CreateBatch_mock(pets)
+	if err != nil {
+		return nil, err
+	}
+	for i, pet := range pets {
+		if errs[i] == nil {
+			errs[i] = m.record(record, pet)
+		}
+	}
+	return errs, nil
 }
 
-func (m *MockStorage) Update_put(pet *models.Pet) error {
-	return m.Update_put_mock(pet)
+func (m *MockStorage) Update(pet *models.Pet, record repository.Recorder) error {
+	if err := m.Update_mock(pet); err != nil {
+		return err
+	}
+	return m.record(record, pet)
 }
 
-func (m *MockStorage) Delete(id int64) error {
-	return m.Delete_mock(id)
+func (m *MockStorage) Update_put(pet *models.Pet, record repository.Recorder) error {
+	if err := m.Update_put_mock(pet); err != nil {
+		return err
+	}
+	return m.record(record, pet)
+}
+
+func (m *MockStorage) AddPhoto(id int64, photo models.Photo) error {
+	return m.AddPhoto_mock(id, photo)
+}
+
+func (m *MockStorage) Delete(id int64, version int, record repository.Recorder) error {
+	if err := m.Delete_mock(id); err != nil {
+		return err
+	}
+	return m.record(record, nil)
+}
+
+// record adds the event of a write to m.History, failing the write like the
+// storages do when the event can't be stored.
+func (m *MockStorage) record(record repository.Recorder, pet *models.Pet) error {
+	if m.History == nil || record == nil {
+		return nil
+	}
+	event := record(pet)
+	if event == nil {
+		return nil
+	}
+	if err := m.History.Add(event); err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrHistoryNotRecorded, err)
+	}
+	return nil
 }
 
 func (m *MockStorage) GetByID(id int64) (*models.Pet, error) {
@@ -57,6 +118,47 @@ func (m *MockStorage) GetByStatus(status string) ([]models.Pet, error) {
 	return m.GetByStatus_mock(status)
 }
 
+func (m *MockStorage) GetByTags(tags []string, matchAll bool) ([]models.Pet, error) {
+	return m.GetByTags_mock(tags, matchAll)
+}
+
+func (m *MockStorage) GetAll(f models.PetFilter, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	return m.GetAll_mock(f, filters)
+}
+
+func (m *MockStorage) GetSimilar(pet *models.Pet, filters filter.Filters) ([]models.Pet, filter.Metadata, error) {
+	return m.GetSimilar_mock(pet, filters)
+}
+
+func (m *MockStorage) Export(ctx context.Context, f models.PetFilter, sort filter.Filters, fn func(*models.Pet) error) error {
+	return m.Export_mock(f, sort, fn)
+}
+
+// MockEventStorage keeps recorded events in memory, or fails with Err.
+type MockEventStorage struct {
+	Events []models.PetEvent
+	Err    error
+}
+
+func (m *MockEventStorage) Add(event *models.PetEvent) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	event.ID = int64(len(m.Events) + 1)
+	m.Events = append(m.Events, *event)
+	return nil
+}
+
+func (m *MockEventStorage) GetByPet(petID int64) ([]models.PetEvent, error) {
+	events := []models.PetEvent{}
+	for _, event := range m.Events {
+		if event.PetID == petID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
 func TestMockService(t *testing.T) {
 	mockStorage := MockStorage{}
 
@@ -65,7 +167,9 @@ func TestMockService(t *testing.T) {
 	}
 
 	mockStorage.GetByID_mock = func(id int64) (*models.Pet, error) {
-		return testpetctor(), nil
+		pet := testpetctor()
+		pet.CreatedBy = "test"
+		return pet, nil
 	}
 	mockStorage.GetByStatus_mock = func(status string) ([]models.Pet, error) {
 		return []models.Pet{}, nil
@@ -80,9 +184,9 @@ func TestMockService(t *testing.T) {
 		return nil
 	}
 
-	storeService := NewPetService(&mockStorage)
+	storeService := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{})
 	t.Run("Create", func(t *testing.T) {
-		resp := storeService.Create(&models.Pet{})
+		resp := storeService.Create(models.Actor{Username: "test"}, &models.Pet{})
 		fmt.Println(resp)
 	})
 	t.Run("Get by ID", func(t *testing.T) {
@@ -103,9 +207,9 @@ func TestMockService(t *testing.T) {
 
 	t.Run("Update", func(t *testing.T) {
 		name := "test"
-		status := "test"
+		status := "pending"
 		ID := 0
-		resp := storeService.Update(&name, &status, int64(ID))
+		resp := storeService.Update(models.Actor{Username: "test"}, &name, &status, int64(ID), 0)
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
@@ -113,7 +217,7 @@ func TestMockService(t *testing.T) {
 	})
 
 	t.Run("Update_put", func(t *testing.T) {
-		resp := storeService.Update_put(testpetctor())
+		resp := storeService.Update_put(models.Actor{Username: "test"}, testpetctor(), 0)
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
@@ -121,7 +225,7 @@ func TestMockService(t *testing.T) {
 	})
 
 	t.Run("Delete", func(t *testing.T) {
-		resp := storeService.Delete(0)
+		resp := storeService.Delete(models.Actor{Username: "test"}, 0, 0)
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
@@ -129,3 +233,576 @@ func TestMockService(t *testing.T) {
 	})
 
 }
+
+func TestStatusTransitions(t *testing.T) {
+	user := models.Actor{Username: "alice", Role: models.RoleUser}
+	admin := models.Actor{Username: "root", Role: models.RoleAdmin}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		actor   models.Actor
+		wantErr bool
+	}{
+		{"reserve", models.PetStatusAvailable, models.PetStatusPending, user, false},
+		{"sell", models.PetStatusPending, models.PetStatusSold, user, false},
+		{"release", models.PetStatusPending, models.PetStatusAvailable, user, false},
+		{"same status", models.PetStatusSold, models.PetStatusSold, user, false},
+		{"unsell", models.PetStatusSold, models.PetStatusAvailable, user, true},
+		{"admin override", models.PetStatusSold, models.PetStatusAvailable, admin, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *models.Pet
+			mockStorage := MockStorage{
+				GetByID_mock: func(id int64) (*models.Pet, error) {
+					pet := testpetctor()
+					pet.Status = tt.from
+					pet.CreatedBy = user.Username
+					return pet, nil
+				},
+				Update_mock: func(pet *models.Pet) error {
+					stored = pet
+					return nil
+				},
+			}
+			service := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{})
+
+			name := "test"
+			err := service.Update(tt.actor, &name, &tt.to, 1, 0)
+
+			var transitionErr *StatusTransitionError
+			if tt.wantErr {
+				if !errors.As(err, &transitionErr) || transitionErr.From != tt.from || transitionErr.To != tt.to {
+					t.Fatalf("expected transition error got %v", err)
+				}
+				if stored != nil {
+					t.Errorf("expected rejected transition not to be stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if stored.Status != tt.to {
+				t.Errorf("expected status %s got %s", tt.to, stored.Status)
+			}
+			if tt.from != tt.to && (stored.StatusChangedBy != tt.actor.Username || stored.StatusChangedAt == nil) {
+				t.Errorf("expected transition to be recorded, got %q at %v", stored.StatusChangedBy, stored.StatusChangedAt)
+			}
+		})
+	}
+
+	t.Run("unknown status", func(t *testing.T) {
+		mockStorage := MockStorage{
+			GetByID_mock: func(id int64) (*models.Pet, error) { return testpetctor(), nil },
+		}
+		name, status := "test", "lost"
+		err := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{}).Update(admin, &name, &status, 1, 0)
+		if !errors.Is(err, ErrInvalidStatus) {
+			t.Errorf("expected ErrInvalidStatus got %v", err)
+		}
+	})
+}
+
+func TestTransition(t *testing.T) {
+	var stored *models.Pet
+	mockStorage := MockStorage{
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			pet := testpetctor()
+			pet.Status = models.PetStatusAvailable
+			pet.CreatedBy = "clerk"
+			return pet, nil
+		},
+		Update_mock: func(pet *models.Pet) error {
+			stored = pet
+			return nil
+		},
+	}
+	events := &MockEventStorage{}
+	mockStorage.History = events
+	service := NewPetService(&mockStorage, events, ImageConfig{})
+	alice := models.Actor{Username: "alice", Role: models.RoleUser}
+
+	err := service.Transition(alice, 1, models.PetStatusPending, models.PetStatusSold)
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Fatalf("expected ErrUnexpectedStatus got %v", err)
+	}
+
+	err = service.Transition(alice, 1, models.PetStatusAvailable, models.PetStatusPending)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if stored.Status != models.PetStatusPending || stored.StatusChangedBy != "alice" {
+		t.Errorf("expected pending pet changed by alice, got %q by %q", stored.Status, stored.StatusChangedBy)
+	}
+	if len(events.Events) != 1 || events.Events[0].Actor != "alice" {
+		t.Errorf("expected the transition in the history, got %+v", events.Events)
+	}
+}
+
+func TestPetHistory(t *testing.T) {
+	var current *models.Pet
+	mockStorage := MockStorage{
+		Create_mock: func(pet *models.Pet) error {
+			pet.ID = 7
+			current = pet
+			return nil
+		},
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			if current == nil {
+				return nil, errors.New("not found")
+			}
+			pet := *current
+			return &pet, nil
+		},
+		Update_mock: func(pet *models.Pet) error {
+			current = pet
+			return nil
+		},
+		Delete_mock: func(id int64) error {
+			current = nil
+			return nil
+		},
+	}
+	events := &MockEventStorage{}
+	mockStorage.History = events
+	service := NewPetService(&mockStorage, events, ImageConfig{})
+	clerk := models.Actor{Username: "clerk", Role: models.RoleUser}
+
+	if _, err := service.History(7); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound for unknown pet, got %v", err)
+	}
+
+	if err := service.Create(clerk, testpetctor()); err != nil {
+		t.Fatal(err)
+	}
+	name, status := "test", models.PetStatusSold
+	if err := service.Update(clerk, &name, &status, 7, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Update(clerk, &name, &status, 7, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Delete(models.Actor{Username: "root", Role: models.RoleAdmin}, 7, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := service.History(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []string{models.PetEventCreated, models.PetEventStatusChanged, models.PetEventDeleted}
+	if len(history) != len(actions) {
+		t.Fatalf("expected %d events got %d: %+v", len(actions), len(history), history)
+	}
+	for i, event := range history {
+		if event.Action != actions[i] {
+			t.Errorf("event %d: expected %s got %s", i, actions[i], event.Action)
+		}
+	}
+
+	sold := history[1]
+	if sold.Actor != "clerk" || len(sold.Changes) != 1 {
+		t.Fatalf("expected a single change by clerk, got %+v", sold)
+	}
+	if change := sold.Changes["status"]; change.Before != models.PetStatusAvailable || change.After != models.PetStatusSold {
+		t.Errorf("unexpected status change %+v", change)
+	}
+
+	deleted := history[2]
+	if deleted.Actor != "root" || deleted.Changes["name"].Before != "test" || deleted.Changes["name"].After != nil {
+		t.Errorf("expected deletion to keep previous values, got %+v", deleted)
+	}
+}
+
+func TestPetHistoryFailure(t *testing.T) {
+	var current *models.Pet
+	mockStorage := MockStorage{
+		Create_mock: func(pet *models.Pet) error {
+			pet.ID = 7
+			current = pet
+			return nil
+		},
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			pet := *current
+			return &pet, nil
+		},
+		Update_mock: func(pet *models.Pet) error {
+			current = pet
+			return nil
+		},
+		Delete_mock: func(id int64) error {
+			return nil
+		},
+	}
+	events := &MockEventStorage{Err: errors.New("pet_events is down")}
+	mockStorage.History = events
+	service := NewPetService(&mockStorage, events, ImageConfig{})
+	clerk := models.Actor{Username: "clerk", Role: models.RoleUser}
+
+	// The storage rolls the write back with its history, so the client is
+	// told it failed.
+	if err := service.Create(clerk, testpetctor()); !errors.Is(err, repository.ErrHistoryNotRecorded) {
+		t.Fatalf("expected the create to fail without its history, got %v", err)
+	}
+	name, status := "test", models.PetStatusSold
+	if err := service.Update(clerk, &name, &status, 7, 0); !errors.Is(err, repository.ErrHistoryNotRecorded) {
+		t.Errorf("expected the update to fail without its history, got %v", err)
+	}
+	if err := service.Delete(clerk, 7, 0); !errors.Is(err, repository.ErrHistoryNotRecorded) {
+		t.Errorf("expected the delete to fail without its history, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	newService := func(batches *[][]*models.Pet) (*PetService, *MockEventStorage) {
+		var nextID int64
+		mockStorage := MockStorage{
+			CreateBatch_mock: func(pets []*models.Pet) ([]error, error) {
+				*batches = append(*batches, append([]*models.Pet(nil), pets...))
+				errs := make([]error, len(pets))
+				for i, pet := range pets {
+					if pet.Category.ID == 42 {
+						errs[i] = repository.ErrCategoryNotFound
+						continue
+					}
+					nextID++
+					pet.ID = nextID
+				}
+				return errs, nil
+			},
+		}
+		events := &MockEventStorage{}
+		mockStorage.History = events
+		return NewPetService(&mockStorage, events, ImageConfig{}), events
+	}
+	clerk := models.Actor{Username: "clerk"}
+
+	t.Run("csv in batches", func(t *testing.T) {
+		body := &strings.Builder{}
+		body.WriteString("name,category,status,tags,photo_urls\n")
+		for i := 0; i < 2*importBatchSize+10; i++ {
+			fmt.Fprintf(body, "pet%d,dogs,available,friendly|small,/img/a.png|/img/b.png\n", i)
+		}
+		body.WriteString(",dogs,available,,\n")
+		body.WriteString("rex,dogs,lost,,\n")
+		body.WriteString("rex,dogs\n")
+
+		var batches [][]*models.Pet
+		service, events := newService(&batches)
+		report, err := service.Import(clerk, ImportCSV, strings.NewReader(body.String()), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(batches) != 3 || len(batches[0]) != importBatchSize || len(batches[2]) != 10 {
+			t.Errorf("expected batches of %d, %d and 10", importBatchSize, importBatchSize)
+		}
+		if report.Total != 2*importBatchSize+13 || report.Created != 2*importBatchSize+10 || report.Failed != 3 {
+			t.Errorf("unexpected totals %d/%d/%d", report.Total, report.Created, report.Failed)
+		}
+		first := batches[0][0]
+		if *first.Name != "pet0" || first.Category.Name != "dogs" || len(first.Tags) != 2 || len(first.PhotoUrls) != 2 || first.StatusChangedBy != "clerk" {
+			t.Errorf("unexpected pet %+v", first)
+		}
+		if report.Rows[0].ID != 1 || report.Rows[0].Errors != nil {
+			t.Errorf("unexpected first row %+v", report.Rows[0])
+		}
+		failed := report.Rows[len(report.Rows)-3:]
+		for i, field := range []string{"name", "status", "row"} {
+			if failed[i].ID != 0 || failed[i].Errors[field] == "" {
+				t.Errorf("expected row %d to fail on %s, got %+v", failed[i].Row, field, failed[i])
+			}
+		}
+		if len(events.Events) != report.Created {
+			t.Errorf("expected %d history events got %d", report.Created, len(events.Events))
+		}
+	})
+
+	t.Run("ndjson with storage errors", func(t *testing.T) {
+		body := `{"name": "rex", "category": {"name": "dogs"}, "tags": [{"name": "friendly"}], "photoUrls": ["/img/rex.png"]}
+
+{"name": "ghost", "category": {"id": 42}, "photoUrls": ["/img/ghost.png"]}
+{"name": "max", "colour": "black", "category": {"name": "dogs"}}
+{"name": `
+
+		var batches [][]*models.Pet
+		service, _ := newService(&batches)
+		report, err := service.Import(clerk, ImportNDJSON, strings.NewReader(body), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Total != 4 || report.Created != 1 || report.Failed != 3 {
+			t.Fatalf("unexpected totals %d/%d/%d: %+v", report.Total, report.Created, report.Failed, report.Rows)
+		}
+		if report.Rows[1].Errors["category"] == "" || report.Rows[2].Errors["row"] == "" || report.Rows[3].Errors["row"] == "" {
+			t.Errorf("unexpected rows %+v", report.Rows)
+		}
+	})
+
+	t.Run("storage failure", func(t *testing.T) {
+		body := &strings.Builder{}
+		for i := 0; i < importBatchSize+10; i++ {
+			fmt.Fprintf(body, "{\"name\": \"pet%d\", \"category\": {\"name\": \"dogs\"}, \"photoUrls\": [\"/img/a.png\"]}\n", i)
+		}
+
+		var nextID int64
+		mockStorage := MockStorage{
+			CreateBatch_mock: func(pets []*models.Pet) ([]error, error) {
+				if nextID > 0 {
+					return nil, errors.New("connection reset")
+				}
+				for _, pet := range pets {
+					nextID++
+					pet.ID = nextID
+				}
+				return make([]error, len(pets)), nil
+			},
+		}
+		service := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{})
+
+		// The first batch is stored: the client needs the report to know
+		// which rows not to send again.
+		report, err := service.Import(clerk, ImportNDJSON, strings.NewReader(body.String()), false)
+		if err != nil {
+			t.Fatalf("expected the partial report, got %v", err)
+		}
+		if report.Error == "" || report.Total != importBatchSize+10 || report.Created != importBatchSize || report.Failed != 10 {
+			t.Errorf("unexpected report %d/%d/%d %q", report.Total, report.Created, report.Failed, report.Error)
+		}
+		if last := report.Rows[len(report.Rows)-1]; last.ID != 0 || last.Errors["row"] == "" {
+			t.Errorf("expected the last row not to be stored, got %+v", last)
+		}
+
+		// Nothing stored, nothing to report.
+		_, err = service.Import(clerk, ImportNDJSON, strings.NewReader(body.String()), false)
+		if err == nil {
+			t.Error("expected the storage error when no row was stored")
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		var batches [][]*models.Pet
+		service, _ := newService(&batches)
+		report, err := service.Import(clerk, ImportCSV, strings.NewReader("name,category,photo_urls\nrex,dogs,/img/rex.png\n,dogs,/img/rex.png\n"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(batches) != 0 {
+			t.Errorf("expected nothing stored on a dry run")
+		}
+		if !report.DryRun || report.Total != 2 || report.Created != 1 || report.Failed != 1 || report.Rows[0].ID != 0 {
+			t.Errorf("unexpected report %+v", report)
+		}
+	})
+
+	t.Run("bad header", func(t *testing.T) {
+		var batches [][]*models.Pet
+		service, _ := newService(&batches)
+		_, err := service.Import(clerk, ImportCSV, strings.NewReader("name,colour\nrex,black\n"), false)
+		if !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("expected ErrInvalidImport got %v", err)
+		}
+	})
+}
+
+func TestValidatePet(t *testing.T) {
+	long := strings.Repeat("x", 256)
+	tests := []struct {
+		name   string
+		modify func(pet *models.Pet)
+		fields []string
+	}{
+		{"valid", func(pet *models.Pet) {}, nil},
+		{"no status", func(pet *models.Pet) { pet.Status = "" }, nil},
+		{"absolute path", func(pet *models.Pet) { pet.PhotoUrls = []string{"/media/pets/1/a.png"} }, nil},
+		{"missing name", func(pet *models.Pet) { pet.Name = nil }, []string{"name"}},
+		{"blank name", func(pet *models.Pet) { name := "  "; pet.Name = &name }, []string{"name"}},
+		{"long name", func(pet *models.Pet) { pet.Name = &long }, []string{"name"}},
+		{"missing category", func(pet *models.Pet) { pet.Category = nil }, []string{"category"}},
+		{"empty category", func(pet *models.Pet) { pet.Category = &models.Category{} }, []string{"category"}},
+		{"bogus status", func(pet *models.Pet) { pet.Status = "lost" }, []string{"status"}},
+		{"no photos", func(pet *models.Pet) { pet.PhotoUrls = nil }, []string{"photoUrls"}},
+		{"relative url", func(pet *models.Pet) { pet.PhotoUrls = []string{"a.png"} }, []string{"photoUrls"}},
+		{"ftp url", func(pet *models.Pet) { pet.PhotoUrls = []string{"ftp://example.com/a.png"} }, []string{"photoUrls"}},
+		{"empty tag", func(pet *models.Pet) { pet.Tags = []*models.Tag{{}} }, []string{"tags"}},
+		{"long tag", func(pet *models.Pet) { pet.Tags = []*models.Tag{{Name: long}} }, []string{"tags"}},
+		{"duplicate tags", func(pet *models.Pet) { pet.Tags = []*models.Tag{{Name: "a"}, {Name: "a"}} }, []string{"tags"}},
+		{"several", func(pet *models.Pet) { pet.Name, pet.Category, pet.PhotoUrls = nil, nil, nil }, []string{"name", "category", "photoUrls"}},
+		{"profile", func(pet *models.Pet) {
+			pet.Breed, pet.Sex, pet.WeightGrams, pet.Price = "beagle", models.PetSexFemale, 9500, 25000
+			pet.BirthDate = &models.Date{Time: time.Now().AddDate(-2, 0, 0)}
+		}, nil},
+		{"born today", func(pet *models.Pet) { today := models.NewDate(time.Now()); pet.BirthDate = &today }, nil},
+		{"unborn", func(pet *models.Pet) { pet.BirthDate = &models.Date{Time: time.Now().AddDate(0, 0, 2)} }, []string{"birthDate"}},
+		{"ancient", func(pet *models.Pet) { pet.BirthDate = &models.Date{Time: time.Date(1700, 1, 1, 0, 0, 0, 0, time.UTC)} }, []string{"birthDate"}},
+		{"bogus sex", func(pet *models.Pet) { pet.Sex = "m" }, []string{"sex"}},
+		{"long breed", func(pet *models.Pet) { pet.Breed = long }, []string{"breed"}},
+		{"negative weight", func(pet *models.Pet) { pet.WeightGrams = -1 }, []string{"weightGrams"}},
+		{"negative price", func(pet *models.Pet) { pet.Price = -100 }, []string{"price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pet := testpetctor()
+			tt.modify(pet)
+
+			v := validator.New()
+			ValidatePet(v, pet)
+			if len(v.Errors) != len(tt.fields) {
+				t.Fatalf("expected errors for %v got %v", tt.fields, v.Errors)
+			}
+			for _, field := range tt.fields {
+				if v.Errors[field] == "" {
+					t.Errorf("expected an error for %s got %v", field, v.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	mockStorage := MockStorage{
+		Create_mock: func(pet *models.Pet) error {
+			t.Fatal("invalid pet reached the storage")
+			return nil
+		},
+	}
+	service := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{})
+
+	pet := testpetctor()
+	pet.Category, pet.Status = nil, "lost"
+	err := service.Create(models.Actor{Username: "clerk"}, pet)
+
+	var validationErr *validator.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Errors["category"] == "" || validationErr.Errors["status"] == "" {
+		t.Fatalf("expected a validation error for category and status got %v", err)
+	}
+	if !errors.Is(err, ErrValidation) || !errors.Is(err, ErrInvalidStatus) {
+		t.Errorf("expected %v to match ErrValidation and ErrInvalidStatus", err)
+	}
+}
+
+func TestPetAge(t *testing.T) {
+	tests := []struct {
+		born, now string
+		want      *models.Age
+	}{
+		{"2020-05-17", "2020-05-17", &models.Age{}},
+		{"2020-05-17", "2021-05-16", &models.Age{Years: 0, Months: 11}},
+		{"2020-05-17", "2021-05-17", &models.Age{Years: 1}},
+		{"2020-01-31", "2020-03-01", &models.Age{Months: 1}},
+		{"2020-02-29", "2021-02-28", &models.Age{Months: 11}},
+		{"2020-02-29", "2021-03-01", &models.Age{Years: 1}},
+		{"2020-05-17", "2020-05-16", nil},
+	}
+	for _, tt := range tests {
+		born, _ := models.ParseDate(tt.born)
+		now, _ := time.Parse(models.DateLayout, tt.now)
+		got := born.AgeAt(now.Add(15 * time.Hour))
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("born %s, at %s expected age %v got %v", tt.born, tt.now, tt.want, got)
+		}
+	}
+
+	born := &models.Date{Time: models.NewDate(time.Now()).AddDate(-2, -3, 0)}
+	mockStorage := MockStorage{
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			pet := testpetctor()
+			pet.BirthDate = born
+			pet.Age = &models.Age{Years: 40}
+			return pet, nil
+		},
+	}
+	service := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{})
+	pet, err := service.GetByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pet.Age == nil || *pet.Age != (models.Age{Years: 2, Months: 3}) {
+		t.Errorf("expected age 2 years 3 months got %v", pet.Age)
+	}
+}
+
+func TestPetOwnership(t *testing.T) {
+	alice := models.Actor{Username: "alice", Role: models.RoleUser}
+	bob := models.Actor{Username: "bob", Role: models.RoleUser}
+	staff := models.Actor{Username: "clerk", Role: models.RoleStaff}
+	admin := models.Actor{Username: "root", Role: models.RoleAdmin}
+
+	var stored *models.Pet
+	mockStorage := MockStorage{
+		Create_mock: func(pet *models.Pet) error {
+			stored = pet
+			return nil
+		},
+		GetByID_mock: func(id int64) (*models.Pet, error) {
+			pet := *stored
+			return &pet, nil
+		},
+		Update_mock:     func(pet *models.Pet) error { return nil },
+		Update_put_mock: func(pet *models.Pet) error { return nil },
+		Delete_mock:     func(id int64) error { return nil },
+	}
+	service := NewPetService(&mockStorage, &MockEventStorage{}, ImageConfig{})
+
+	pet := testpetctor()
+	pet.CreatedBy = "mallory"
+	if err := service.Create(alice, pet); err != nil {
+		t.Fatal(err)
+	}
+	if stored.CreatedBy != "alice" {
+		t.Fatalf("expected the pet to be owned by alice got %q", stored.CreatedBy)
+	}
+
+	name, status := "test", models.PetStatusPending
+	tests := []struct {
+		name    string
+		actor   models.Actor
+		owner   string
+		allowed bool
+	}{
+		{"owner", alice, "alice", true},
+		{"someone else", bob, "alice", false},
+		{"staff", staff, "alice", true},
+		{"admin", admin, "alice", true},
+		{"no owner", alice, "", false},
+		{"no owner, staff", staff, "", true},
+		{"anonymous", models.Actor{}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored.CreatedBy = tt.owner
+			errs := map[string]error{
+				"update":     service.Update(tt.actor, &name, &status, 1, 0),
+				"update_put": service.Update_put(tt.actor, testpetctor(), 0),
+				"delete":     service.Delete(tt.actor, 1, 0),
+			}
+			_, errs["patch"] = service.Patch(tt.actor, 1, 0, MergePatch, []byte(`{"name": "rex"}`))
+			_, errs["upload"] = service.UploadImage(tt.actor, 1, strings.NewReader("not an image"))
+
+			for op, err := range errs {
+				if tt.allowed && errors.Is(err, ErrForbidden) {
+					t.Errorf("%s: expected %s to be allowed", op, tt.actor.Username)
+				}
+				if !tt.allowed && !errors.Is(err, ErrForbidden) {
+					t.Errorf("%s: expected ErrForbidden got %v", op, err)
+				}
+			}
+		})
+	}
+
+	t.Run("patching the owner", func(t *testing.T) {
+		stored.CreatedBy = "alice"
+		_, err := service.Patch(alice, 1, 0, MergePatch, []byte(`{"createdBy": "bob"}`))
+
+		var validationErr *validator.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Errors["createdBy"] == "" {
+			t.Errorf("expected a validation error for createdBy got %v", err)
+		}
+	})
+}
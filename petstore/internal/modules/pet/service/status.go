@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"test/internal/models"
+	"test/internal/modules/pet/repository"
+)
+
+var ErrInvalidStatus = errors.New("invalid pet status")
+
+// statusTransitions lists, for every status, the statuses a pet may move to
+// from it. A sold pet is final; only an admin can take it back.
+var statusTransitions = map[string][]string{
+	models.PetStatusAvailable: {models.PetStatusPending, models.PetStatusSold},
+	models.PetStatusPending:   {models.PetStatusAvailable, models.PetStatusSold},
+	models.PetStatusSold:      {},
+}
+
+// StatusTransitionError reports a status change the state machine doesn't
+// allow.
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("pet status can't change from %s to %s", e.From, e.To)
+}
+
+func validStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// changeStatus moves pet to status on behalf of actor and stamps who did it
+// and when. Setting the current status again is a no-op. Admins may make any
+// transition between known statuses.
+func changeStatus(pet *models.Pet, status string, actor models.Actor) error {
+	if !validStatus(status) {
+		return ErrInvalidStatus
+	}
+	if pet.Status == status {
+		return nil
+	}
+	if !canTransition(pet.Status, status) && !actor.IsAdmin() {
+		return &StatusTransitionError{From: pet.Status, To: status}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	pet.Status = status
+	pet.StatusChangedBy = actor.Username
+	pet.StatusChangedAt = &now
+	return nil
+}
+
+// ErrUnexpectedStatus is returned by Transition when the pet isn't in the
+// status the caller expected, usually because someone else changed it first.
+var ErrUnexpectedStatus = errors.New("pet is not in the expected status")
+
+// Transition moves a pet from one status to another for a workflow that has
+// already decided actor may do so, such as an adoption, so the owner check
+// of the other updates doesn't apply. The change only happens if the pet is
+// still in from, which makes it safe for two workflows racing for a pet.
+func (s *PetService) Transition(actor models.Actor, id int64, from, to string) error {
+	pet, err := s.storage.GetByID(id)
+	if err != nil {
+		return ErrRecordNotFound
+	}
+	if pet.Status != from {
+		return ErrUnexpectedStatus
+	}
+
+	before := petSnapshot(pet)
+	err = changeStatus(pet, to, actor)
+	if err != nil {
+		return err
+	}
+	err = s.storage.Update(pet, recordChange(actor, models.PetEventUpdated, before))
+	if err != nil {
+		if errors.Is(err, repository.ErrHistoryNotRecorded) {
+			return err
+		}
+		return ErrEditConflict
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+)
+
+var ErrValidation = validator.ErrValidation
+
+const (
+	maxNameLength = 255
+	maxTagLength  = 255
+	maxTags       = 20
+	maxPhotoURLs  = 20
+
+	maxBreedLength       = 255
+	maxDescriptionLength = 5000
+	// maxWeightGrams is well above the heaviest animal a pet store would sell.
+	maxWeightGrams = 10_000_000
+	// maxAgeYears bounds dates of birth from below, mostly to catch typos in
+	// the year.
+	maxAgeYears = 200
+)
+
+// invalid reports what v found wrong with a pet. An invalid status matches
+// ErrInvalidStatus too.
+func invalid(v *validator.Validator) error {
+	return &validator.ValidationError{Errors: v.Errors, Causes: map[string]error{"status": ErrInvalidStatus}}
+}
+
+// ValidatePet checks a pet as sent by a client, before the service fills in
+// anything. An empty status is allowed and means available.
+func ValidatePet(v *validator.Validator, pet *models.Pet) {
+	validatePetName(v, pet.Name)
+	v.Check(pet.Category != nil && (pet.Category.ID != 0 || pet.Category.Name != ""), "category", "must be provided")
+	v.Check(pet.Category == nil || utf8.RuneCountInString(pet.Category.Name) <= maxNameLength, "category", fmt.Sprintf("name must not be more than %d characters long", maxNameLength))
+	v.Check(pet.Status == "" || validStatus(pet.Status), "status", "must be one of available, pending, sold")
+
+	v.Check(len(pet.PhotoUrls) > 0, "photoUrls", "must contain at least one url")
+	v.Check(len(pet.PhotoUrls) <= maxPhotoURLs, "photoUrls", fmt.Sprintf("must not contain more than %d urls", maxPhotoURLs))
+	for _, u := range pet.PhotoUrls {
+		v.Check(validPhotoURL(u), "photoUrls", "must contain http(s) urls or absolute paths")
+	}
+
+	v.Check(len(pet.Tags) <= maxTags, "tags", fmt.Sprintf("must not contain more than %d tags", maxTags))
+	names := make([]string, 0, len(pet.Tags))
+	for _, tag := range pet.Tags {
+		if tag == nil || (tag.ID == 0 && tag.Name == "") {
+			v.AddError("tags", "must have an id or a name")
+			continue
+		}
+		v.Check(utf8.RuneCountInString(tag.Name) <= maxTagLength, "tags", fmt.Sprintf("names must not be more than %d characters long", maxTagLength))
+		if tag.ID == 0 {
+			names = append(names, tag.Name)
+		}
+	}
+	v.Check(validator.Unique(names), "tags", "must not contain duplicate names")
+
+	validateProfile(v, pet, time.Now())
+}
+
+// validateProfile checks the descriptive fields of a pet, which are all
+// optional. Dates of birth are checked against now.
+func validateProfile(v *validator.Validator, pet *models.Pet, now time.Time) {
+	v.Check(utf8.RuneCountInString(pet.Breed) <= maxBreedLength, "breed", fmt.Sprintf("must not be more than %d characters long", maxBreedLength))
+	v.Check(pet.Sex == "" || validator.PermittedValue(pet.Sex, models.PetSexes...), "sex", "must be one of male, female")
+	v.Check(pet.WeightGrams >= 0, "weightGrams", "must not be negative")
+	v.Check(pet.WeightGrams <= maxWeightGrams, "weightGrams", fmt.Sprintf("must not be more than %d", maxWeightGrams))
+	v.Check(utf8.RuneCountInString(pet.Description) <= maxDescriptionLength, "description", fmt.Sprintf("must not be more than %d characters long", maxDescriptionLength))
+	v.Check(pet.Price >= 0, "price", "must not be negative")
+
+	if pet.BirthDate != nil {
+		today := models.NewDate(now)
+		v.Check(!pet.BirthDate.After(today.Time), "birthDate", "must not be in the future")
+		v.Check(!pet.BirthDate.Before(today.AddDate(-maxAgeYears, 0, 0)), "birthDate", fmt.Sprintf("must not be more than %d years ago", maxAgeYears))
+	}
+}
+
+// validateUpdate checks the fields of the name and status form update.
+func validateUpdate(v *validator.Validator, name, status *string) {
+	validatePetName(v, name)
+	v.Check(status != nil && validStatus(*status), "status", "must be one of available, pending, sold")
+}
+
+func validatePetName(v *validator.Validator, name *string) {
+	v.Check(name != nil && strings.TrimSpace(*name) != "", "name", "must be provided")
+	v.Check(name == nil || utf8.RuneCountInString(*name) <= maxNameLength, "name", fmt.Sprintf("must not be more than %d characters long", maxNameLength))
+}
+
+// validPhotoURL accepts absolute http and https urls, and absolute paths such
+// as the /media/ urls of uploaded photos.
+func validPhotoURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || s == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "":
+		return u.Host == "" && strings.HasPrefix(u.Path, "/")
+	}
+	return false
+}
@@ -2,21 +2,43 @@ package modules
 
 import (
 	"test/internal/infrastructure/components"
+	adoption_service "test/internal/modules/adoption/service"
+	category_service "test/internal/modules/category/service"
+	medical_service "test/internal/modules/medical/service"
 	pet_service "test/internal/modules/pet/service"
 	store_service "test/internal/modules/store/service"
+	tag_service "test/internal/modules/tag/service"
 	user_service "test/internal/modules/user/service"
 )
 
 type Services struct {
-	UserService  user_service.IUserService
-	PetService   pet_service.IPetstoreService
-	StoreService store_service.IStoreService
+	UserService     user_service.IUserService
+	PetService      pet_service.IPetstoreService
+	FavoriteService pet_service.IFavoriteService
+	StoreService    store_service.IStoreService
+	CategoryService category_service.ICategoryService
+	TagService      tag_service.ITagService
+	AdoptionService adoption_service.IAdoptionService
+	MedicalService  medical_service.IMedicalService
 }
 
 func NewServices(cmp *components.Components, storages *Storages) *Services {
+	petService := pet_service.NewPetService(storages.PetStorage, storages.PetEventStorage, pet_service.ImageConfig{
+		Store:          cmp.Blobs,
+		MaxSize:        cmp.Config.Media.MaxUploadSize,
+		URLPrefix:      "/media/",
+		ThumbnailSizes: cmp.Config.Media.ThumbnailSizes,
+	})
+	storeService := store_service.NewStoreService(storages.StoreStorage, storages.HoldStorage, cmp.Config.Reservation.TTL)
+
 	return &Services{
-		UserService:  user_service.NewUserService(storages.UserStorage),
-		PetService:   pet_service.NewPetService(storages.PetStorage),
-		StoreService: store_service.NewStoreService(storages.StoreStorage),
+		UserService:     user_service.NewUserService(storages.UserStorage),
+		PetService:      petService,
+		FavoriteService: pet_service.NewFavoriteService(storages.FavoriteStorage),
+		StoreService:    storeService,
+		CategoryService: category_service.NewCategoryService(storages.CategoryStorage),
+		TagService:      tag_service.NewTagService(storages.TagStorage),
+		AdoptionService: adoption_service.NewAdoptionService(storages.AdoptionStorage, petService, storeService, cmp.Config.Adoption.TTL),
+		MedicalService:  medical_service.NewMedicalService(storages.MedicalStorage, petService),
 	}
 }
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"test/config"
 	"test/internal/infrastructure/components"
 	"test/internal/infrastructure/responder"
 	"testing"
@@ -18,7 +19,7 @@ func TestNewServices(t *testing.T) {
 	decoder := godecoder.NewDecoder(jsoniter.Config{})
 	responseManager := responder.NewResponder(decoder, logger)
 
-	components := components.NewComponents(responseManager, decoder, logger, nil)
+	components := components.NewComponents(responseManager, decoder, logger, nil, nil, config.NewConfig())
 	storages := NewStorages(nil, nil)
 	services := NewServices(components, storages)
 	if services == nil {
@@ -1,8 +1,12 @@
 package modules
 
 import (
+	adoption_storage "test/internal/modules/adoption/repository"
+	category_storage "test/internal/modules/category/repository"
+	medical_storage "test/internal/modules/medical/repository"
 	pet_storage "test/internal/modules/pet/repository"
 	store_storage "test/internal/modules/store/repository"
+	tag_storage "test/internal/modules/tag/repository"
 	user_storage "test/internal/modules/user/repository"
 
 	"github.com/jmoiron/sqlx"
@@ -10,15 +14,48 @@ import (
 )
 
 type Storages struct {
-	UserStorage  user_storage.IUserStorage
-	PetStorage   pet_storage.IPetStorage
-	StoreStorage store_storage.IStoreStorage
+	UserStorage     user_storage.IUserStorage
+	PetStorage      pet_storage.IPetStorage
+	PetEventStorage pet_storage.IPetEventStorage
+	FavoriteStorage pet_storage.IFavoriteStorage
+	StoreStorage    store_storage.IStoreStorage
+	HoldStorage     store_storage.IHoldStorage
+	CategoryStorage category_storage.ICategoryStorage
+	TagStorage      tag_storage.ITagStorage
+	AdoptionStorage adoption_storage.IAdoptionStorage
+	MedicalStorage  medical_storage.IMedicalRecordStorage
 }
 
 func NewStorages(sql *sqlx.DB, logger *zap.Logger) *Storages {
 	return &Storages{
-		UserStorage:  user_storage.NewUserModel(sql),
-		PetStorage:   pet_storage.NewPetStorage(sql, logger),
-		StoreStorage: store_storage.NewStoreStorage(sql, logger),
+		UserStorage:     user_storage.NewUserModel(sql),
+		PetStorage:      pet_storage.NewPetStorage(sql, logger),
+		PetEventStorage: pet_storage.NewPetEventStorage(sql, logger),
+		FavoriteStorage: pet_storage.NewFavoriteStorage(sql, logger),
+		StoreStorage:    store_storage.NewStoreStorage(sql, logger),
+		HoldStorage:     store_storage.NewHoldStorage(sql, logger),
+		CategoryStorage: category_storage.NewCategoryStorage(sql, logger),
+		TagStorage:      tag_storage.NewTagStorage(sql, logger),
+		AdoptionStorage: adoption_storage.NewAdoptionStorage(sql, logger),
+		MedicalStorage:  medical_storage.NewMedicalRecordStorage(sql, logger),
+	}
+}
+
+// NewMemoryStorages keeps everything in memory, with pets in the given
+// storage. Categories and tags are its catalog, so that pets and the
+// /category and /tag endpoints agree.
+func NewMemoryStorages(pets *pet_storage.PetStorage_map, logger *zap.Logger) *Storages {
+	holds := store_storage.NewHoldStorage_map(pets)
+	return &Storages{
+		UserStorage:     user_storage.NewUserStorage_map(),
+		PetStorage:      pets,
+		PetEventStorage: pet_storage.NewPetEventStorage_map(pets),
+		FavoriteStorage: pet_storage.NewFavoriteStorage_map(pets),
+		StoreStorage:    store_storage.NewStoreStorage_map(pets, holds, logger),
+		HoldStorage:     holds,
+		CategoryStorage: category_storage.NewCategoryStorage_map(pets),
+		TagStorage:      tag_storage.NewTagStorage_map(pets),
+		AdoptionStorage: adoption_storage.NewAdoptionStorage_map(),
+		MedicalStorage:  medical_storage.NewMedicalRecordStorage_map(),
 	}
 }
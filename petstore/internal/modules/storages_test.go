@@ -1,6 +1,7 @@
 package modules
 
 import (
+	pet_storage "test/internal/modules/pet/repository"
 	"testing"
 )
 
@@ -10,3 +11,11 @@ func TestNewStorages(t *testing.T) {
 		t.Fatal("storages is nil")
 	}
 }
+
+func TestNewMemoryStorages(t *testing.T) {
+	storages := NewMemoryStorages(pet_storage.NewPetStorage_map(nil), nil)
+	if storages == nil || storages.PetStorage == nil || storages.StoreStorage == nil || storages.HoldStorage == nil || storages.FavoriteStorage == nil ||
+		storages.UserStorage == nil || storages.CategoryStorage == nil || storages.TagStorage == nil {
+		t.Fatal("storages is nil")
+	}
+}
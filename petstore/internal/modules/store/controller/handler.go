@@ -2,9 +2,13 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
 	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
 	"test/internal/models"
 	"test/internal/modules/store/service"
 
@@ -21,8 +25,12 @@ import (
 type IStoreController interface {
 	GetInventory(w http.ResponseWriter, r *http.Request)
 	GetOrderByID(w http.ResponseWriter, r *http.Request)
+	ListOrders(w http.ResponseWriter, r *http.Request)
 	CreateOrder(w http.ResponseWriter, r *http.Request)
 	DeleteOrder(w http.ResponseWriter, r *http.Request)
+	CreateHold(w http.ResponseWriter, r *http.Request)
+	GetHold(w http.ResponseWriter, r *http.Request)
+	ReleaseHold(w http.ResponseWriter, r *http.Request)
 }
 
 type StoreController struct {
@@ -37,6 +45,8 @@ func NewStoreController(responder responder.Responder, service service.IStoreSer
 	}
 }
 
+// CreateOrder serves POST /store/order. The order is placed on behalf of the
+// user behind the token, if any, so that their own hold on the pet counts.
 func (s *StoreController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var order *models.Order
 	err := json.NewDecoder(r.Body).Decode(&order)
@@ -46,9 +56,9 @@ func (s *StoreController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.service.Create(order)
+	err = s.service.Create(helpers.ActorFromContext(r.Context()), order)
 	if err != nil {
-		s.responder.ErrorInternal(w, err)
+		s.error(w, err)
 		return
 	}
 
@@ -79,6 +89,33 @@ func (s *StoreController) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	s.responder.OutputJSON(w, pet)
 }
 
+// ListOrders serves GET /store/order to staff, by page with ?page= and
+// ?page_size= or by cursor with ?after= and ?limit=.
+func (s *StoreController) ListOrders(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	page := filters.Filters{
+		Page:         helpers.ReadInt(qs, "page", 1, v),
+		PageSize:     helpers.ReadInt(qs, "page_size", 20, v),
+		Sort:         helpers.ReadString(qs, "sort", "id"),
+		SortSafelist: []string{"id", "-id"},
+	}
+	filters.ReadKeyset(qs, &page, v)
+
+	if filters.ValidateFilters(v, page); !v.Valid() {
+		s.responder.ErrorBadRequest(w, errors.New("Invalid query parameters"))
+		return
+	}
+
+	orders, metadata, err := s.service.List(helpers.ActorFromContext(r.Context()), page)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	s.responder.OutputJSON(w, map[string]interface{}{"metadata": metadata, "data": orders})
+}
+
 func (s *StoreController) DeleteOrder(w http.ResponseWriter, r *http.Request) {
 
 	var (
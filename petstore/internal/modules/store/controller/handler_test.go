@@ -6,12 +6,17 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	filter "test/internal/infrastructure/filters"
+	"test/internal/infrastructure/helpers"
 	"test/internal/infrastructure/responder"
 	"test/internal/models"
+	"test/internal/modules/store/repository"
 	"test/internal/modules/store/service"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth/v5"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ptflp/godecoder"
 	"go.uber.org/zap"
@@ -24,14 +29,15 @@ import (
 // r.Delete("/store/order/{orderID}", ctrl.StoreHandler.DeleteOrder)
 
 type MockStorage struct {
-	Create_mock       func(order *models.Order) error
+	Create_mock       func(order *models.Order, holder string) error
 	Delelte_mock      func(id int64) error
 	GetByID_mock      func(id int64) (*models.Order, error)
 	GetInventory_mock func() (map[string]int, error)
+	GetAll_mock       func(filters filter.Filters) ([]models.Order, filter.Metadata, error)
 }
 
-func (m *MockStorage) Create(order *models.Order) error {
-	return m.Create_mock(order)
+func (m *MockStorage) Create(order *models.Order, holder, petStatus string, now time.Time) error {
+	return m.Create_mock(order, holder)
 }
 func (m *MockStorage) Delete(id int64) error {
 	return m.Delelte_mock(id)
@@ -43,6 +49,45 @@ func (m *MockStorage) GetInventory() (map[string]int, error) {
 	return m.GetInventory_mock()
 }
 
+func (m *MockStorage) GetAll(filters filter.Filters) ([]models.Order, filter.Metadata, error) {
+	return m.GetAll_mock(filters)
+}
+
+type MockHoldStorage struct {
+	Create_mock        func(hold *models.Hold) error
+	GetByID_mock       func(id int64) (*models.Hold, error)
+	GetActive_mock     func(petID int64, now time.Time) (*models.Hold, error)
+	Delete_mock        func(id int64) error
+	DeleteExpired_mock func(now time.Time) (int, error)
+}
+
+// NewMockHoldStorage returns holds storage without any holds.
+func NewMockHoldStorage() *MockHoldStorage {
+	return &MockHoldStorage{
+		Create_mock:        func(hold *models.Hold) error { return nil },
+		GetByID_mock:       func(id int64) (*models.Hold, error) { return nil, repository.ErrRecordNotFound },
+		GetActive_mock:     func(petID int64, now time.Time) (*models.Hold, error) { return nil, repository.ErrRecordNotFound },
+		Delete_mock:        func(id int64) error { return repository.ErrRecordNotFound },
+		DeleteExpired_mock: func(now time.Time) (int, error) { return 0, nil },
+	}
+}
+
+func (m *MockHoldStorage) Create(hold *models.Hold) error {
+	return m.Create_mock(hold)
+}
+func (m *MockHoldStorage) GetByID(id int64) (*models.Hold, error) {
+	return m.GetByID_mock(id)
+}
+func (m *MockHoldStorage) GetActive(petID int64, now time.Time) (*models.Hold, error) {
+	return m.GetActive_mock(petID, now)
+}
+func (m *MockHoldStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+func (m *MockHoldStorage) DeleteExpired(now time.Time) (int, error) {
+	return m.DeleteExpired_mock(now)
+}
+
 func TestCreateHandler(t *testing.T) {
 
 	t.Run("happy path", func(t *testing.T) {
@@ -60,7 +105,7 @@ func TestCreateHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		mock := &MockStorage{
-			Create_mock: func(order *models.Order) error { return nil },
+			Create_mock: func(order *models.Order, holder string) error { return nil },
 		}
 
 		logger, err := zap.NewProduction()
@@ -74,7 +119,7 @@ func TestCreateHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewStoreService(mock)
+		service := service.NewStoreService(mock, NewMockHoldStorage(), time.Minute)
 
 		controller := NewStoreController(responder.NewResponder(decoder, logger), service)
 		controller.CreateOrder(w, req)
@@ -96,7 +141,7 @@ func TestCreateHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		mock := &MockStorage{
-			Create_mock: func(order *models.Order) error { return errors.New("some error") },
+			Create_mock: func(order *models.Order, holder string) error { return errors.New("some error") },
 		}
 
 		logger, err := zap.NewProduction()
@@ -110,7 +155,7 @@ func TestCreateHandler(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewStoreService(mock)
+		service := service.NewStoreService(mock, NewMockHoldStorage(), time.Minute)
 
 		controller := NewStoreController(responder.NewResponder(decoder, logger), service)
 		controller.CreateOrder(w, req)
@@ -147,7 +192,7 @@ func TestGetOrderByID(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewStoreService(mock)
+		service := service.NewStoreService(mock, NewMockHoldStorage(), time.Minute)
 
 		controller := NewStoreController(responder.NewResponder(decoder, logger), service)
 		chiCtx := chi.NewRouteContext()
@@ -188,7 +233,7 @@ func TestDeleteOrder(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewStoreService(mock)
+		service := service.NewStoreService(mock, NewMockHoldStorage(), time.Minute)
 
 		controller := NewStoreController(responder.NewResponder(decoder, logger), service)
 		chiCtx := chi.NewRouteContext()
@@ -231,7 +276,7 @@ func TestGetAll(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewStoreService(mock)
+		service := service.NewStoreService(mock, NewMockHoldStorage(), time.Minute)
 
 		controller := NewStoreController(responder.NewResponder(decoder, logger), service)
 		chiCtx := chi.NewRouteContext()
@@ -271,7 +316,7 @@ func TestGetAll(t *testing.T) {
 			DisallowUnknownFields:  true,
 		})
 
-		service := service.NewStoreService(mock)
+		service := service.NewStoreService(mock, NewMockHoldStorage(), time.Minute)
 
 		controller := NewStoreController(responder.NewResponder(decoder, logger), service)
 		chiCtx := chi.NewRouteContext()
@@ -287,3 +332,195 @@ func TestGetAll(t *testing.T) {
 
 	})
 }
+
+func newHoldController(mock *MockStorage, holds *MockHoldStorage) *StoreController {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		DisallowUnknownFields:  true,
+	})
+	return NewStoreController(responder.NewResponder(decoder, logger), service.NewStoreService(mock, holds, time.Minute))
+}
+
+func asUser(req *http.Request, username string) *http.Request {
+	token, err := helpers.TokenAuth.Decode(helpers.GenerateToken(username, models.RoleUser))
+	return req.WithContext(jwtauth.NewContext(req.Context(), token, err))
+}
+
+func TestCreateOrderConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		err      error
+		code     int
+	}{
+		{"held by someone else", "bob", repository.ErrPetHeld, http.StatusConflict},
+		{"held anonymously", "", repository.ErrPetHeld, http.StatusConflict},
+		{"not available", "bob", repository.ErrPetUnavailable, http.StatusConflict},
+		{"own hold", "alice", nil, http.StatusOK},
+		{"already ordered", "bob", repository.ErrDuplicateOrder, http.StatusConflict},
+		{"no such pet", "bob", repository.ErrRecordNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holder := "-"
+			mock := &MockStorage{
+				Create_mock: func(order *models.Order, h string) error {
+					holder = h
+					return tt.err
+				},
+			}
+
+			req := httptest.NewRequest("POST", "/store/order", bytes.NewReader([]byte(`{"petId": 1, "quantity": 1, "status": "placed"}`)))
+			if tt.username != "" {
+				req = asUser(req, tt.username)
+			}
+			w := httptest.NewRecorder()
+			newHoldController(mock, NewMockHoldStorage()).CreateOrder(w, req)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+			if holder != tt.username {
+				t.Errorf("expected the order to be placed for %q, got %q", tt.username, holder)
+			}
+		})
+	}
+}
+
+func TestHoldHandlers(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		holds := NewMockHoldStorage()
+		holds.Create_mock = func(hold *models.Hold) error {
+			if hold.Holder != "alice" || hold.PetID != 1 {
+				t.Errorf("unexpected hold %+v", hold)
+			}
+			hold.ID = 1
+			return nil
+		}
+
+		req := httptest.NewRequest("POST", "/store/hold", bytes.NewReader([]byte(`{"petId": 1}`)))
+		w := httptest.NewRecorder()
+		newHoldController(&MockStorage{}, holds).CreateHold(w, asUser(req, "alice"))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"no pet", `{}`, nil, http.StatusUnprocessableEntity},
+		{"held", `{"petId": 1}`, repository.ErrPetHeld, http.StatusConflict},
+		{"sold", `{"petId": 1}`, repository.ErrPetUnavailable, http.StatusConflict},
+		{"missing", `{"petId": 1}`, repository.ErrRecordNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holds := NewMockHoldStorage()
+			holds.Create_mock = func(hold *models.Hold) error { return tt.err }
+
+			req := httptest.NewRequest("POST", "/store/hold", bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+			newHoldController(&MockStorage{}, holds).CreateHold(w, asUser(req, "bob"))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+
+	t.Run("release someone else's hold", func(t *testing.T) {
+		holds := NewMockHoldStorage()
+		holds.GetByID_mock = func(id int64) (*models.Hold, error) {
+			return &models.Hold{ID: id, PetID: 1, Holder: "alice"}, nil
+		}
+
+		req := httptest.NewRequest("DELETE", "/store/hold/1", nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("holdID", "1")
+		req = asUser(req, "bob")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		w := httptest.NewRecorder()
+		newHoldController(&MockStorage{}, holds).ReleaseHold(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, w.Code)
+		}
+	})
+}
+
+func TestListOrders(t *testing.T) {
+	asStaff := func(req *http.Request) *http.Request {
+		token, err := helpers.TokenAuth.Decode(helpers.GenerateToken("clerk", models.RoleStaff))
+		return req.WithContext(jwtauth.NewContext(req.Context(), token, err))
+	}
+
+	var got filter.Filters
+	mock := &MockStorage{
+		GetAll_mock: func(filters filter.Filters) ([]models.Order, filter.Metadata, error) {
+			got = filters
+			return []models.Order{{ID: 1}}, filter.Metadata{PageSize: filters.PageSize, NextCursor: "next"}, nil
+		},
+	}
+
+	t.Run("keyset", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/store/order?limit=1&sort=-id", nil)
+		w := httptest.NewRecorder()
+		newHoldController(mock, NewMockHoldStorage()).ListOrders(w, asStaff(req))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		if !got.Keyset || got.PageSize != 1 || got.Sort != "-id" || got.After != nil {
+			t.Errorf("unexpected filters %+v", got)
+		}
+		var body struct {
+			Metadata filter.Metadata `json:"metadata"`
+			Data     []models.Order  `json:"data"`
+		}
+		if err := jsoniter.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Data) != 1 || body.Metadata.NextCursor != "next" {
+			t.Errorf("unexpected orders %s", w.Body)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		staff bool
+		code  int
+	}{
+		{"customer", "", false, http.StatusForbidden},
+		{"bad cursor", "after=nope", true, http.StatusBadRequest},
+		{"cursor of another order", "sort=id&after=" + filter.Cursor{Sort: "-id", ID: 1}.Encode(), true, http.StatusBadRequest},
+		{"bad sort", "sort=status", true, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/store/order?"+tt.query, nil)
+			if tt.staff {
+				req = asStaff(req)
+			} else {
+				req = asUser(req, "alice")
+			}
+			w := httptest.NewRecorder()
+			newHoldController(mock, NewMockHoldStorage()).ListOrders(w, req)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status code %d but got %d", tt.code, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/validator"
+	"test/internal/modules/store/service"
+
+	"github.com/go-chi/chi"
+)
+
+// r.Post("/store/hold", ctrl.StoreHandler.CreateHold)
+// r.Get("/store/hold/{holdID}", ctrl.StoreHandler.GetHold)
+// r.Delete("/store/hold/{holdID}", ctrl.StoreHandler.ReleaseHold)
+
+// CreateHold serves POST /store/hold with a body of {"petId": 1}, reserving
+// the pet for the caller while they check out.
+func (s *StoreController) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PetID int64 `json:"petId"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		s.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.PetID > 0, "petId", "must be provided")
+	if !v.Valid() {
+		s.responder.ErrorValidation(w, v.Errors)
+		return
+	}
+
+	hold, err := s.service.Hold(helpers.ActorFromContext(r.Context()), input.PetID)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	s.responder.OutputJSON(w, hold)
+}
+
+func (s *StoreController) GetHold(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "holdID"))
+	if err != nil {
+		s.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	hold, err := s.service.GetHold(helpers.ActorFromContext(r.Context()), int64(id))
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	s.responder.OutputJSON(w, hold)
+}
+
+func (s *StoreController) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "holdID"))
+	if err != nil {
+		s.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = s.service.Release(helpers.ActorFromContext(r.Context()), int64(id))
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	s.responder.OutputJSON(w, "Hold released successfully")
+}
+
+func (s *StoreController) error(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrRecordNotFound), errors.Is(err, service.ErrPetNotFound):
+		s.responder.ErrorNotFound(w, err)
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrStaffOnly):
+		s.responder.ErrorForbidden(w, err)
+	case errors.Is(err, service.ErrPetHeld), errors.Is(err, service.ErrPetUnavailable), errors.Is(err, service.ErrDuplicateRecord):
+		s.responder.ErrorConflict(w, err)
+	default:
+		s.responder.ErrorInternal(w, err)
+	}
+}
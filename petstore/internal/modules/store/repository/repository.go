@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 )
 
@@ -23,35 +26,76 @@ func NewStoreStorage(db *sqlx.DB, logger *zap.Logger) IStoreStorage {
 		DB:     db}
 }
 
-func (ps *StoreStorage) Create(order *models.Order) error {
-	query := `
-	INSERT INTO orders (pet_id, quantity, ship_date, status, complete) 
-	VALUES ($1, $2, $3, $4, $5) 
-	RETURNING id`
+func (ps *StoreStorage) Create(order *models.Order, holder, petStatus string, now time.Time) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := ps.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		ps.logger.Error("error on starting order transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	query := ps.DB.Rebind(`
+	INSERT INTO orders (pet_id, quantity, ship_date, status, complete)
+	SELECT id, ?, ?, ?, ? FROM pets
+	WHERE id = ? AND status = ?
+	AND NOT EXISTS (SELECT 1 FROM holds WHERE pet_id = ? AND holder <> ? AND expires_at > ?)
+	RETURNING id`)
+
 	args := []any{
-		order.PetID,
 		order.Quantity,
 		order.ShipDate,
 		order.Status,
 		order.Complete,
+		order.PetID,
+		petStatus,
+		order.PetID,
+		holder,
+		now,
 	}
 
-	err := ps.DB.QueryRowContext(ctx, query, args...).Scan(&order.ID)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&order.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			ps.logger.Error("no rows in orders table", zap.Error(err))
-			return ErrEditConflict
+			return ps.refused(ctx, tx, order.PetID, petStatus)
+		case isUniqueViolation(err):
+			return ErrDuplicateOrder
+		case strings.Contains(err.Error(), "violates foreign key constraint"):
+			return ErrRecordNotFound
 		default:
 			ps.logger.Error(" error on inserting order", zap.Error(err))
 			return err
 		}
 	}
 
-	return nil
+	_, err = tx.ExecContext(ctx, ps.DB.Rebind(`DELETE FROM holds WHERE pet_id = ? AND holder = ?`), order.PetID, holder)
+	if err != nil {
+		ps.logger.Error("error on using up hold", zap.Error(err))
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// refused tells why no order was placed: the pet is missing, not in
+// petStatus, or held by somebody else.
+func (ps *StoreStorage) refused(ctx context.Context, tx *sqlx.Tx, petID int64, petStatus string) error {
+	var status string
+	err := tx.QueryRowContext(ctx, ps.DB.Rebind(`SELECT status FROM pets WHERE id = ?`), petID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		ps.logger.Error("error on looking up ordered pet", zap.Error(err))
+		return err
+	}
+	if status != petStatus {
+		return ErrPetUnavailable
+	}
+	return ErrPetHeld
 }
 
 func (ps *StoreStorage) GetByID(id int64) (*models.Order, error) {
@@ -77,6 +121,71 @@ func (ps *StoreStorage) GetByID(id int64) (*models.Order, error) {
 	return order, nil
 }
 
+func (ps *StoreStorage) GetAll(filters filter.Filters) ([]models.Order, filter.Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if filters.Keyset {
+		clause, args := filters.KeysetClause("orders")
+		query := ps.DB.Rebind(fmt.Sprintf(`
+	SELECT id, pet_id, quantity, ship_date, status, complete FROM orders
+	WHERE %s
+	ORDER BY %s %s, id ASC
+	LIMIT ?`, clause, filters.SortColumn(), filters.SortDirection()))
+
+		orders, err := ps.scanOrders(ctx, query, append(args, filters.KeysetLimit())...)
+		if err != nil {
+			ps.logger.Error("some error on listing orders", zap.Error(err))
+			return nil, filter.Metadata{}, err
+		}
+		orders, metadata := filter.KeysetPage(filters, orders, func(order models.Order) (string, int64) {
+			return "", order.ID
+		})
+		return orders, metadata, nil
+	}
+
+	totalRecords := 0
+	err := ps.DB.QueryRowContext(ctx, `SELECT count(*) FROM orders`).Scan(&totalRecords)
+	if err != nil {
+		ps.logger.Error("some error on counting orders", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	query := ps.DB.Rebind(fmt.Sprintf(`
+	SELECT id, pet_id, quantity, ship_date, status, complete FROM orders
+	ORDER BY %s %s, id ASC
+	LIMIT ? OFFSET ?`, filters.SortColumn(), filters.SortDirection()))
+
+	orders, err := ps.scanOrders(ctx, query, filters.Limit(), filters.Offset())
+	if err != nil {
+		ps.logger.Error("some error on listing orders", zap.Error(err))
+		return nil, filter.Metadata{}, err
+	}
+
+	metadata := filter.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return orders, metadata, nil
+}
+
+func (ps *StoreStorage) scanOrders(ctx context.Context, query string, args ...any) ([]models.Order, error) {
+	rows, err := ps.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		var order models.Order
+		err := rows.Scan(&order.ID, &order.PetID, &order.Quantity, &order.ShipDate, &order.Status, &order.Complete)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
 func (ps *StoreStorage) Delete(id int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
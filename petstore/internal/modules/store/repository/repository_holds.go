@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"test/internal/models"
+)
+
+type HoldStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+}
+
+func NewHoldStorage(db *sqlx.DB, logger *zap.Logger) IHoldStorage {
+	return &HoldStorage{
+		logger: logger,
+		DB:     db}
+}
+
+const selectHolds = `
+	SELECT id, pet_id, holder, created_at, expires_at
+FROM holds
+`
+
+func (hs *HoldStorage) Create(hold *models.Hold) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := hs.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		hs.logger.Error("error on starting hold transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, hs.DB.Rebind(`DELETE FROM holds WHERE pet_id = ? AND expires_at <= ?`), hold.PetID, hold.CreatedAt)
+	if err != nil {
+		hs.logger.Error("error on deleting expired hold", zap.Error(err))
+		return err
+	}
+
+	query := hs.DB.Rebind(`INSERT INTO holds (pet_id, holder, created_at, expires_at)
+		SELECT id, ?, ?, ? FROM pets WHERE id = ? AND status = ?
+		RETURNING id`)
+
+	args := []any{
+		hold.Holder,
+		hold.CreatedAt,
+		hold.ExpiresAt,
+		hold.PetID,
+		models.PetStatusAvailable,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&hold.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return hs.unavailable(ctx, tx, hold.PetID)
+		case isUniqueViolation(err):
+			return ErrPetHeld
+		default:
+			hs.logger.Error("error on inserting hold", zap.Error(err))
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// unavailable tells a missing pet from one that cannot be held.
+func (hs *HoldStorage) unavailable(ctx context.Context, tx *sqlx.Tx, petID int64) error {
+	exists := false
+	err := tx.QueryRowContext(ctx, hs.DB.Rebind(`SELECT EXISTS(SELECT 1 FROM pets WHERE id = ?)`), petID).Scan(&exists)
+	if err != nil {
+		hs.logger.Error("error on looking up held pet", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return ErrRecordNotFound
+	}
+	return ErrPetUnavailable
+}
+
+func (hs *HoldStorage) GetByID(id int64) (*models.Hold, error) {
+	return hs.get(hs.DB.Rebind(selectHolds+`WHERE id = ?`), id)
+}
+
+func (hs *HoldStorage) GetActive(petID int64, now time.Time) (*models.Hold, error) {
+	return hs.get(hs.DB.Rebind(selectHolds+`WHERE pet_id = ? AND expires_at > ?`), petID, now)
+}
+
+func (hs *HoldStorage) get(query string, args ...any) (*models.Hold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	hold := &models.Hold{}
+	err := hs.DB.QueryRowContext(ctx, query, args...).Scan(&hold.ID, &hold.PetID, &hold.Holder, &hold.CreatedAt, &hold.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			hs.logger.Error("error on getting hold", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return hold, nil
+}
+
+func (hs *HoldStorage) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := hs.DB.ExecContext(ctx, hs.DB.Rebind(`DELETE FROM holds WHERE id = ?`), id)
+	if err != nil {
+		hs.logger.Error("error on deleting hold", zap.Error(err))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (hs *HoldStorage) DeleteExpired(now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := hs.DB.ExecContext(ctx, hs.DB.Rebind(`DELETE FROM holds WHERE expires_at <= ?`), now)
+	if err != nil {
+		hs.logger.Error("error on deleting expired holds", zap.Error(err))
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// isUniqueViolation recognizes unique index violations of postgres and of
+// sqlite, which the tests run on.
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "duplicate key value violates unique constraint") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
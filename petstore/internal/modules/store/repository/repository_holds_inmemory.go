@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"test/internal/models"
+)
+
+// HoldStorage_map keeps holds in memory next to the in-memory pet storage.
+type HoldStorage_map struct {
+	pets   PetIndex
+	mu     sync.Mutex
+	holds  map[int64]*models.Hold
+	lastID int64
+}
+
+func NewHoldStorage_map(pets PetIndex) *HoldStorage_map {
+	return &HoldStorage_map{pets: pets, holds: make(map[int64]*models.Hold)}
+}
+
+func (hs *HoldStorage_map) Create(hold *models.Hold) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	pet, err := hs.pets.GetByID(hold.PetID)
+	if err != nil {
+		return ErrRecordNotFound
+	}
+	if pet.Status != models.PetStatusAvailable {
+		return ErrPetUnavailable
+	}
+
+	for id, held := range hs.holds {
+		if held.PetID != hold.PetID {
+			continue
+		}
+		if held.ExpiresAt.After(hold.CreatedAt) {
+			return ErrPetHeld
+		}
+		delete(hs.holds, id)
+	}
+
+	hs.lastID++
+	hold.ID = hs.lastID
+	stored := *hold
+	hs.holds[hold.ID] = &stored
+	return nil
+}
+
+func (hs *HoldStorage_map) GetByID(id int64) (*models.Hold, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	held, ok := hs.holds[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	hold := *held
+	return &hold, nil
+}
+
+func (hs *HoldStorage_map) GetActive(petID int64, now time.Time) (*models.Hold, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	for _, held := range hs.holds {
+		if held.PetID == petID && held.ExpiresAt.After(now) {
+			hold := *held
+			return &hold, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (hs *HoldStorage_map) Delete(id int64) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if _, ok := hs.holds[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(hs.holds, id)
+	return nil
+}
+
+func (hs *HoldStorage_map) DeleteExpired(now time.Time) (int, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	deleted := 0
+	for id, held := range hs.holds {
+		if !held.ExpiresAt.After(now) {
+			delete(hs.holds, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"errors"
+	"test/internal/infrastructure/dbtest"
+	"test/internal/models"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// holdsSchema mirrors migration 000012 with just enough of pets to hold.
+const holdsSchema = `
+CREATE TABLE pets (
+	id INTEGER PRIMARY KEY,
+	status TEXT NOT NULL
+);
+CREATE TABLE holds (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pet_id INTEGER NOT NULL UNIQUE REFERENCES pets(id) ON DELETE CASCADE,
+	holder TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+INSERT INTO pets (id, status) VALUES (1, 'available'), (2, 'available'), (3, 'sold');
+`
+
+func newSQLiteHoldStorage(tb testing.TB) *HoldStorage {
+	tb.Helper()
+
+	db := dbtest.OpenSQLite(tb, holdsSchema)
+	return NewHoldStorage(db, zap.NewNop()).(*HoldStorage)
+}
+
+// holdPets is the memory backend's view of the pets in holdsSchema.
+type holdPets struct{}
+
+func (holdPets) GetByID(id int64) (*models.Pet, error) {
+	switch id {
+	case 1, 2:
+		return &models.Pet{ID: id, Status: models.PetStatusAvailable}, nil
+	case 3:
+		return &models.Pet{ID: id, Status: models.PetStatusSold}, nil
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (holdPets) CountByStatus() map[string]int {
+	return map[string]int{}
+}
+
+var holdStart = time.Date(2024, 10, 12, 12, 0, 0, 0, time.UTC)
+
+func testHold(petID int64, holder string, at time.Time) *models.Hold {
+	return &models.Hold{
+		PetID:     petID,
+		Holder:    holder,
+		CreatedAt: at,
+		ExpiresAt: at.Add(10 * time.Minute),
+	}
+}
+
+func TestHoldStorage(t *testing.T) {
+	backends := map[string]func(t *testing.T) IHoldStorage{
+		"sql":    func(t *testing.T) IHoldStorage { return newSQLiteHoldStorage(t) },
+		"memory": func(t *testing.T) IHoldStorage { return NewHoldStorage_map(holdPets{}) },
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("create and get", func(t *testing.T) {
+				storage := newStorage(t)
+
+				hold := testHold(1, "alice", holdStart)
+				if err := storage.Create(hold); err != nil {
+					t.Fatal(err)
+				}
+				if hold.ID == 0 {
+					t.Fatal("expected an id")
+				}
+
+				got, err := storage.GetByID(hold.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.PetID != 1 || got.Holder != "alice" || !got.ExpiresAt.Equal(hold.ExpiresAt) {
+					t.Errorf("unexpected hold %+v", got)
+				}
+
+				got, err = storage.GetActive(1, holdStart.Add(time.Minute))
+				if err != nil || got.ID != hold.ID {
+					t.Errorf("expected the active hold, got %+v, %v", got, err)
+				}
+				if _, err := storage.GetActive(1, hold.ExpiresAt); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected no active hold at expiry, got %v", err)
+				}
+				if _, err := storage.GetActive(2, holdStart); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected no hold on pet 2, got %v", err)
+				}
+			})
+
+			t.Run("one hold per pet", func(t *testing.T) {
+				storage := newStorage(t)
+
+				first := testHold(1, "alice", holdStart)
+				if err := storage.Create(first); err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.Create(testHold(1, "bob", holdStart.Add(time.Minute))); !errors.Is(err, ErrPetHeld) {
+					t.Fatalf("expected ErrPetHeld, got %v", err)
+				}
+
+				replacement := testHold(1, "bob", first.ExpiresAt)
+				if err := storage.Create(replacement); err != nil {
+					t.Fatalf("after expiry: %v", err)
+				}
+				if _, err := storage.GetByID(first.ID); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected the expired hold to be replaced, got %v", err)
+				}
+			})
+
+			t.Run("unavailable pets", func(t *testing.T) {
+				storage := newStorage(t)
+
+				if err := storage.Create(testHold(3, "alice", holdStart)); !errors.Is(err, ErrPetUnavailable) {
+					t.Errorf("expected ErrPetUnavailable, got %v", err)
+				}
+				if err := storage.Create(testHold(4, "alice", holdStart)); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				storage := newStorage(t)
+
+				hold := testHold(1, "alice", holdStart)
+				if err := storage.Create(hold); err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.Delete(hold.ID); err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.Delete(hold.ID); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+			})
+
+			t.Run("delete expired", func(t *testing.T) {
+				storage := newStorage(t)
+
+				if err := storage.Create(testHold(1, "alice", holdStart)); err != nil {
+					t.Fatal(err)
+				}
+				later := testHold(2, "bob", holdStart.Add(5*time.Minute))
+				if err := storage.Create(later); err != nil {
+					t.Fatal(err)
+				}
+
+				deleted, err := storage.DeleteExpired(holdStart.Add(10 * time.Minute))
+				if err != nil || deleted != 1 {
+					t.Fatalf("expected one expired hold, got %d, %v", deleted, err)
+				}
+				if _, err := storage.GetByID(later.ID); err != nil {
+					t.Errorf("expected the later hold to stay, got %v", err)
+				}
+			})
+		})
+	}
+}
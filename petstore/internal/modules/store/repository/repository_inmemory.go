@@ -1,15 +1,26 @@
 package repository
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 )
 
+// PetIndex is what StoreStorage_map needs from the in-memory pet storage it
+// runs next to.
+type PetIndex interface {
+	GetByID(id int64) (*models.Pet, error)
+	CountByStatus() map[string]int
+}
+
 type StoreStorage_map struct {
-	pets               map[int64]*models.Pet
+	pets               PetIndex
+	holds              *HoldStorage_map
 	logger             *zap.Logger
 	orders             []*models.Order
 	primaryKeyIDx      map[int64]*models.Order
@@ -17,24 +28,47 @@ type StoreStorage_map struct {
 	sync.Mutex
 }
 
-func NewStoreStorage_map(logger *zap.Logger) IStoreStorage {
+// NewStoreStorage_map returns order storage that checks and uses up the
+// holds kept in holds.
+func NewStoreStorage_map(pets PetIndex, holds *HoldStorage_map, logger *zap.Logger) IStoreStorage {
 	return &StoreStorage_map{
-		pets:               models.PrimaryKeyIDx,
+		pets:               pets,
+		holds:              holds,
 		logger:             logger,
 		primaryKeyIDx:      make(map[int64]*models.Order),
-		autoIncrementCount: 0,
+		autoIncrementCount: 1,
 		orders:             make([]*models.Order, 0),
 	}
 }
 
-func (ps *StoreStorage_map) Create(order *models.Order) error {
+func (ps *StoreStorage_map) Create(order *models.Order, holder, petStatus string, now time.Time) error {
 	ps.Lock()
 	defer ps.Unlock()
-	v, ok := ps.pets[order.PetID]
-	if !ok {
+	ps.holds.mu.Lock()
+	defer ps.holds.mu.Unlock()
+
+	pet, err := ps.pets.GetByID(order.PetID)
+	if err != nil {
 		return ErrRecordNotFound
 	}
-	v.Status = "pending"
+	if pet.Status != petStatus {
+		return ErrPetUnavailable
+	}
+	for _, held := range ps.holds.holds {
+		if held.PetID == order.PetID && held.Holder != holder && held.ExpiresAt.After(now) {
+			return ErrPetHeld
+		}
+	}
+	for _, placed := range ps.orders {
+		if placed.PetID == order.PetID {
+			return ErrDuplicateOrder
+		}
+	}
+	for id, held := range ps.holds.holds {
+		if held.PetID == order.PetID && held.Holder == holder {
+			delete(ps.holds.holds, id)
+		}
+	}
 	order.ID = int64(ps.autoIncrementCount)
 	ps.autoIncrementCount++
 	ps.primaryKeyIDx[order.ID] = order
@@ -43,6 +77,8 @@ func (ps *StoreStorage_map) Create(order *models.Order) error {
 }
 
 func (ps *StoreStorage_map) Delete(id int64) error {
+	ps.Lock()
+	defer ps.Unlock()
 	if _, ok := ps.primaryKeyIDx[id]; ok {
 		delete(ps.primaryKeyIDx, id)
 	}
@@ -74,11 +110,43 @@ func (ps *StoreStorage_map) GetByID(id int64) (*models.Order, error) {
 	return nil, ErrRecordNotFound
 }
 
-func (ps *StoreStorage_map) GetInventory() (map[string]int, error) {
-	inventory := make(map[string]int)
-	for _, v := range ps.pets {
-		inventory[v.Status]++
+// GetAll pages through the orders by id, the only order listings sort by.
+func (ps *StoreStorage_map) GetAll(filters filter.Filters) ([]models.Order, filter.Metadata, error) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	desc := filters.SortDirection() == "DESC"
+	orders := make([]models.Order, 0, len(ps.orders))
+	for _, order := range ps.orders {
+		if after := filters.After; after != nil && (desc && order.ID >= after.ID || !desc && order.ID <= after.ID) {
+			continue
+		}
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return (orders[i].ID < orders[j].ID) != desc })
+
+	if filters.Keyset {
+		if len(orders) > filters.KeysetLimit() {
+			orders = orders[:filters.KeysetLimit()]
+		}
+		orders, metadata := filter.KeysetPage(filters, orders, func(order models.Order) (string, int64) {
+			return "", order.ID
+		})
+		return orders, metadata, nil
+	}
+
+	metadata := filter.CalculateMetadata(len(orders), filters.Page, filters.PageSize)
+	start := filters.Offset()
+	if start > len(orders) {
+		start = len(orders)
 	}
+	end := start + filters.Limit()
+	if end > len(orders) {
+		end = len(orders)
+	}
+	return orders[start:end], metadata, nil
+}
 
-	return inventory, nil
+func (ps *StoreStorage_map) GetInventory() (map[string]int, error) {
+	return ps.pets.CountByStatus(), nil
 }
@@ -7,7 +7,9 @@ import (
 	//"fmt"
 
 	"errors"
+	"time"
 
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 )
 
@@ -16,11 +18,33 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 	ErrNoOrderPlaced  = errors.New("order not placed")
 	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrDuplicateOrder = errors.New("pet already ordered")
+	ErrPetHeld        = errors.New("pet is held")
+	ErrPetUnavailable = errors.New("pet is not available")
 )
 
 type IStoreStorage interface {
-	Create(order *models.Order) error
+	// Create places order for a pet in petStatus that nobody but holder
+	// holds at now, and uses up holder's own hold on it. Both are checked
+	// by the insert itself, so no hold can slip in between.
+	Create(order *models.Order, holder, petStatus string, now time.Time) error
 	Delete(id int64) error
 	GetByID(id int64) (*models.Order, error)
+	// GetAll returns one page of orders, by page number or by keyset.
+	GetAll(filters filter.Filters) ([]models.Order, filter.Metadata, error)
 	GetInventory() (map[string]int, error)
 }
+
+// IHoldStorage keeps reservation holds, at most one per pet. Holds past
+// their expiry no longer count, whether or not they have been swept yet.
+type IHoldStorage interface {
+	// Create holds a pet that is available and not held by anybody else at
+	// hold.CreatedAt, replacing an expired hold on it.
+	Create(hold *models.Hold) error
+	GetByID(id int64) (*models.Hold, error)
+	// GetActive returns the hold on petID unexpired at now.
+	GetActive(petID int64, now time.Time) (*models.Hold, error)
+	Delete(id int64) error
+	// DeleteExpired removes the holds expired at now and returns how many.
+	DeleteExpired(now time.Time) (int, error)
+}
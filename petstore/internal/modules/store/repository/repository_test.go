@@ -1,8 +1,14 @@
 package repository
 
 import (
+	"errors"
+	"test/internal/infrastructure/dbtest"
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 	"testing"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 func NewMockStorage() *MockStorage {
@@ -29,9 +35,10 @@ type MockStorage struct {
 	Delelte_mock      func(id int64) error
 	GetByID_mock      func(id int64) (*models.Order, error)
 	GetInventory_mock func() (map[string]int, error)
+	GetAll_mock       func(filters filter.Filters) ([]models.Order, filter.Metadata, error)
 }
 
-func (m *MockStorage) Create(order *models.Order) error {
+func (m *MockStorage) Create(order *models.Order, holder, petStatus string, now time.Time) error {
 	return m.Create_mock(order)
 }
 func (m *MockStorage) Delete(id int64) error {
@@ -44,11 +51,15 @@ func (m *MockStorage) GetInventory() (map[string]int, error) {
 	return m.GetInventory_mock()
 }
 
+func (m *MockStorage) GetAll(filters filter.Filters) ([]models.Order, filter.Metadata, error) {
+	return m.GetAll_mock(filters)
+}
+
 func TestRepo(t *testing.T) {
 	storeRepository := NewMockStorage()
 
 	t.Run("Create", func(t *testing.T) {
-		resp := storeRepository.Create(&models.Order{})
+		resp := storeRepository.Create(&models.Order{}, "", models.PetStatusAvailable, time.Now())
 		if resp != nil {
 			t.Errorf("expected error got nil")
 		}
@@ -75,3 +86,136 @@ func TestRepo(t *testing.T) {
 		}
 	})
 }
+
+// ordersSchema has the orders table of init.sql next to holdsSchema's pets.
+const ordersSchema = holdsSchema + `
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pet_id INTEGER NOT NULL UNIQUE REFERENCES pets(id),
+	quantity INTEGER NOT NULL DEFAULT 0,
+	ship_date TIMESTAMP NOT NULL,
+	status TEXT NOT NULL DEFAULT '',
+	complete BOOLEAN NOT NULL DEFAULT false
+);
+`
+
+// newSQLiteStoreStorage returns order and hold storage sharing a database.
+func newSQLiteStoreStorage(tb testing.TB) (IStoreStorage, IHoldStorage) {
+	tb.Helper()
+
+	db := dbtest.OpenSQLite(tb, ordersSchema)
+	return NewStoreStorage(db, zap.NewNop()), NewHoldStorage(db, zap.NewNop())
+}
+
+func newMemoryStoreStorage(tb testing.TB) (IStoreStorage, IHoldStorage) {
+	holds := NewHoldStorage_map(holdPets{})
+	return NewStoreStorage_map(holdPets{}, holds, zap.NewNop()), holds
+}
+
+var storeBackends = map[string]func(tb testing.TB) (IStoreStorage, IHoldStorage){
+	"sql":    newSQLiteStoreStorage,
+	"memory": newMemoryStoreStorage,
+}
+
+func TestStoreStorageCreate(t *testing.T) {
+	for name, newStorage := range storeBackends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("held pets", func(t *testing.T) {
+				storage, holds := newStorage(t)
+
+				hold := testHold(1, "alice", holdStart)
+				if err := holds.Create(hold); err != nil {
+					t.Fatal(err)
+				}
+				at := holdStart.Add(time.Minute)
+				for _, holder := range []string{"bob", ""} {
+					if err := storage.Create(testOrder(1), holder, models.PetStatusAvailable, at); !errors.Is(err, ErrPetHeld) {
+						t.Errorf("expected ErrPetHeld for %q, got %v", holder, err)
+					}
+				}
+
+				order := testOrder(1)
+				if err := storage.Create(order, "alice", models.PetStatusAvailable, at); err != nil {
+					t.Fatalf("alice's order: %v", err)
+				}
+				if order.ID == 0 {
+					t.Error("expected an id")
+				}
+				if _, err := holds.GetByID(hold.ID); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected the order to use up the hold, got %v", err)
+				}
+				if err := storage.Create(testOrder(1), "bob", models.PetStatusAvailable, at); !errors.Is(err, ErrDuplicateOrder) {
+					t.Errorf("expected ErrDuplicateOrder, got %v", err)
+				}
+			})
+
+			t.Run("expired holds", func(t *testing.T) {
+				storage, holds := newStorage(t)
+
+				hold := testHold(1, "alice", holdStart)
+				if err := holds.Create(hold); err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.Create(testOrder(1), "bob", models.PetStatusAvailable, hold.ExpiresAt); err != nil {
+					t.Errorf("expected bob to order once the hold expired, got %v", err)
+				}
+			})
+
+			t.Run("pet status", func(t *testing.T) {
+				storage, _ := newStorage(t)
+
+				if err := storage.Create(testOrder(3), "bob", models.PetStatusAvailable, holdStart); !errors.Is(err, ErrPetUnavailable) {
+					t.Errorf("expected ErrPetUnavailable for a sold pet, got %v", err)
+				}
+				if err := storage.Create(testOrder(2), "bob", models.PetStatusPending, holdStart); !errors.Is(err, ErrPetUnavailable) {
+					t.Errorf("expected ErrPetUnavailable for an available pet, got %v", err)
+				}
+				if err := storage.Create(testOrder(3), "bob", models.PetStatusSold, holdStart); err != nil {
+					t.Errorf("expected an order in the asked status, got %v", err)
+				}
+				if err := storage.Create(testOrder(4), "bob", models.PetStatusAvailable, holdStart); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("expected ErrRecordNotFound, got %v", err)
+				}
+			})
+		})
+	}
+}
+
+func testOrder(petID int64) *models.Order {
+	return &models.Order{PetID: petID, Quantity: 1, ShipDate: holdStart, Status: "placed"}
+}
+
+func TestStoreStorageGetAll(t *testing.T) {
+	safelist := []string{"id", "-id"}
+	// Pets 1 and 2 are available, 3 sold.
+	statuses := []string{models.PetStatusAvailable, models.PetStatusAvailable, models.PetStatusSold}
+
+	for name, newStorage := range storeBackends {
+		t.Run(name, func(t *testing.T) {
+			storage, _ := newStorage(t)
+			for petID := int64(1); petID <= 3; petID++ {
+				order := &models.Order{PetID: petID, Quantity: 1, ShipDate: time.Date(2024, 10, 12, 0, 0, 0, 0, time.UTC), Status: "placed"}
+				if err := storage.Create(order, "", statuses[petID-1], holdStart); err != nil {
+					t.Fatal(err)
+				}
+			}
+			orders, meta, err := storage.GetAll(filter.Filters{Page: 2, PageSize: 2, Sort: "id", SortSafelist: safelist})
+			if err != nil || len(orders) != 1 || orders[0].PetID != 3 || meta.TotalRecords != 3 || meta.LastPage != 2 {
+				t.Errorf("unexpected second page %+v %+v: %v", orders, meta, err)
+			}
+
+			page := filter.Filters{Page: 1, PageSize: 2, Sort: "-id", SortSafelist: safelist, Keyset: true}
+			orders, meta, err = storage.GetAll(page)
+			if err != nil || len(orders) != 2 || orders[0].PetID != 3 || orders[1].PetID != 2 || meta.NextCursor == "" {
+				t.Fatalf("unexpected first keyset page %+v %+v: %v", orders, meta, err)
+			}
+			if page.After, err = filter.DecodeCursor(meta.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+			orders, meta, err = storage.GetAll(page)
+			if err != nil || len(orders) != 1 || orders[0].PetID != 1 || meta.NextCursor != "" {
+				t.Errorf("unexpected last keyset page %+v %+v: %v", orders, meta, err)
+			}
+		})
+	}
+}
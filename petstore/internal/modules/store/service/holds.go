@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"test/internal/models"
+	"test/internal/modules/store/repository"
+)
+
+// Hold reserves an available pet for actor for the hold TTL. Holding a pet
+// the actor already holds returns the existing hold.
+func (s *StoreService) Hold(actor models.Actor, petID int64) (*models.Hold, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	hold := &models.Hold{
+		PetID:     petID,
+		Holder:    actor.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.holdTTL),
+	}
+
+	err := s.holds.Create(hold)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPetHeld):
+			held, getErr := s.holds.GetActive(petID, now)
+			if getErr == nil && held.Holder == actor.Username {
+				return held, nil
+			}
+			return nil, ErrPetHeld
+		case errors.Is(err, repository.ErrRecordNotFound):
+			return nil, ErrPetNotFound
+		case errors.Is(err, repository.ErrPetUnavailable):
+			return nil, ErrPetUnavailable
+		default:
+			return nil, err
+		}
+	}
+	return hold, nil
+}
+
+// GetHold returns a hold to its holder or to staff.
+func (s *StoreService) GetHold(actor models.Actor, id int64) (*models.Hold, error) {
+	hold, err := s.holds.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if !actor.IsStaff() && hold.Holder != actor.Username {
+		return nil, ErrForbidden
+	}
+	return hold, nil
+}
+
+// Release lets go of a hold before it expires.
+func (s *StoreService) Release(actor models.Actor, id int64) error {
+	_, err := s.GetHold(actor, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.holds.Delete(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// ExpireHolds sweeps the holds expired at now and returns how many there
+// were. Expired holds stop counting before they are swept.
+func (s *StoreService) ExpireHolds(now time.Time) (int, error) {
+	return s.holds.DeleteExpired(now)
+}
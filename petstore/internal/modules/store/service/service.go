@@ -2,7 +2,9 @@ package service
 
 import (
 	"errors"
+	"test/internal/infrastructure/filters"
 	"test/internal/modules/store/repository"
+	"time"
 
 	"test/internal/models"
 )
@@ -10,29 +12,67 @@ import (
 var (
 	ErrRecordNotFound  = errors.New("record not found")
 	ErrEditConflict    = errors.New("edit conflict")
-	ErrDuplicateRecord = errors.New("duplicate record")
+	ErrDuplicateRecord = errors.New("pet has already been ordered")
 	ErrNoInventory     = errors.New("inventory error")
+	ErrPetNotFound     = errors.New("pet not found")
+	ErrPetHeld         = errors.New("pet is reserved by another customer")
+	ErrPetUnavailable  = errors.New("pet is not available")
+	ErrForbidden       = errors.New("only the holder or staff can see or release this hold")
+	ErrStaffOnly       = errors.New("only staff can list orders")
 )
 
 type IStoreService interface {
-	Create(pet *models.Order) error
+	Create(actor models.Actor, order *models.Order) error
+	CreateAdopted(actor models.Actor, order *models.Order) error
 	Delete(id int64) error
 	GetByID(id int64) (*models.Order, error)
+	List(actor models.Actor, filters filters.Filters) ([]models.Order, filters.Metadata, error)
 	GetInventory() (map[string]int, error)
+	Hold(actor models.Actor, petID int64) (*models.Hold, error)
+	GetHold(actor models.Actor, id int64) (*models.Hold, error)
+	Release(actor models.Actor, id int64) error
+	ExpireHolds(now time.Time) (int, error)
 }
 
 type StoreService struct {
 	storage repository.IStoreStorage
+	holds   repository.IHoldStorage
+	holdTTL time.Duration
 }
 
-func NewStoreService(repo repository.IStoreStorage) *StoreService {
-	return &StoreService{storage: repo}
+// NewStoreService returns a service whose holds on pets last holdTTL.
+func NewStoreService(repo repository.IStoreStorage, holds repository.IHoldStorage, holdTTL time.Duration) *StoreService {
+	return &StoreService{storage: repo, holds: holds, holdTTL: holdTTL}
 }
 
-func (s *StoreService) Create(pet *models.Order) error {
-	err := s.storage.Create(pet)
+// Create places an order on behalf of actor, who may be anonymous, for an
+// available pet. A pet held by another customer cannot be ordered; the
+// actor's own hold is used up by the order.
+func (s *StoreService) Create(actor models.Actor, order *models.Order) error {
+	return s.place(actor, order, models.PetStatusAvailable)
+}
+
+// CreateAdopted places the order that sells a pet pending adoption, on
+// behalf of the staff member approving the application.
+func (s *StoreService) CreateAdopted(actor models.Actor, order *models.Order) error {
+	return s.place(actor, order, models.PetStatusPending)
+}
+
+func (s *StoreService) place(actor models.Actor, order *models.Order, petStatus string) error {
+	err := s.storage.Create(order, actor.Username, petStatus, time.Now())
 	if err != nil {
-		return ErrDuplicateRecord
+		switch {
+		case errors.Is(err, repository.ErrPetHeld):
+			return ErrPetHeld
+		case errors.Is(err, repository.ErrPetUnavailable):
+			return ErrPetUnavailable
+		case errors.Is(err, repository.ErrDuplicateOrder):
+			return ErrDuplicateRecord
+		case errors.Is(err, repository.ErrRecordNotFound):
+			return ErrPetNotFound
+		default:
+			return err
+		}
 	}
 	return nil
 }
@@ -53,6 +93,14 @@ func (s *StoreService) GetByID(id int64) (*models.Order, error) {
 	return order, nil
 }
 
+// List pages through every order placed, which only staff may see.
+func (s *StoreService) List(actor models.Actor, page filters.Filters) ([]models.Order, filters.Metadata, error) {
+	if !actor.IsStaff() {
+		return nil, filters.Metadata{}, ErrStaffOnly
+	}
+	return s.storage.GetAll(page)
+}
+
 func (s *StoreService) GetInventory() (map[string]int, error) {
 	inventory, err := s.storage.GetInventory()
 	if err != nil {
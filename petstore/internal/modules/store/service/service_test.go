@@ -1,9 +1,15 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
+	"test/internal/modules/store/repository"
 	"testing"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 type MockStorage struct {
@@ -11,9 +17,10 @@ type MockStorage struct {
 	Delelte_mock      func(id int64) error
 	GetByID_mock      func(id int64) (*models.Order, error)
 	GetInventory_mock func() (map[string]int, error)
+	GetAll_mock       func(filters filter.Filters) ([]models.Order, filter.Metadata, error)
 }
 
-func (m *MockStorage) Create(order *models.Order) error {
+func (m *MockStorage) Create(order *models.Order, holder, petStatus string, now time.Time) error {
 	return m.Create_mock(order)
 }
 func (m *MockStorage) Delete(id int64) error {
@@ -26,6 +33,10 @@ func (m *MockStorage) GetInventory() (map[string]int, error) {
 	return m.GetInventory_mock()
 }
 
+func (m *MockStorage) GetAll(filters filter.Filters) ([]models.Order, filter.Metadata, error) {
+	return m.GetAll_mock(filters)
+}
+
 func TestUserService(t *testing.T) {
 	mockStorage := MockStorage{}
 	mockStorage.Create_mock = func(order *models.Order) error {
@@ -41,9 +52,9 @@ func TestUserService(t *testing.T) {
 	mockStorage.Delelte_mock = func(id int64) error {
 		return nil
 	}
-	storeService := NewStoreService(&mockStorage)
+	storeService := NewStoreService(&mockStorage, repository.NewHoldStorage_map(testPets{}), time.Minute)
 	t.Run("Create", func(t *testing.T) {
-		resp := storeService.Create(&models.Order{})
+		resp := storeService.Create(models.Actor{}, &models.Order{})
 		fmt.Println(resp)
 	})
 	t.Run("Get by ID", func(t *testing.T) {
@@ -70,3 +81,130 @@ func TestUserService(t *testing.T) {
 	})
 
 }
+
+// testPets is the pet index of the in-memory hold storage: pet 1 is
+// available, pet 2 sold.
+type testPets struct{}
+
+func (testPets) GetByID(id int64) (*models.Pet, error) {
+	switch id {
+	case 1:
+		return &models.Pet{ID: 1, Status: models.PetStatusAvailable}, nil
+	case 2:
+		return &models.Pet{ID: 2, Status: models.PetStatusSold}, nil
+	}
+	return nil, repository.ErrRecordNotFound
+}
+
+func (testPets) CountByStatus() map[string]int {
+	return map[string]int{}
+}
+
+func TestHolds(t *testing.T) {
+	alice := models.Actor{Username: "alice", Role: models.RoleUser}
+	bob := models.Actor{Username: "bob", Role: models.RoleUser}
+	clerk := models.Actor{Username: "clerk", Role: models.RoleStaff}
+
+	newService := func() *StoreService {
+		holds := repository.NewHoldStorage_map(testPets{})
+		return NewStoreService(repository.NewStoreStorage_map(testPets{}, holds, zap.NewNop()), holds, time.Minute)
+	}
+
+	t.Run("blocks other customers", func(t *testing.T) {
+		s := newService()
+
+		hold, err := s.Hold(alice, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hold.Holder != "alice" || !hold.ExpiresAt.Equal(hold.CreatedAt.Add(time.Minute)) {
+			t.Errorf("unexpected hold %+v", hold)
+		}
+
+		again, err := s.Hold(alice, 1)
+		if err != nil || again.ID != hold.ID {
+			t.Errorf("expected alice to get her hold back, got %+v, %v", again, err)
+		}
+		if _, err := s.Hold(bob, 1); !errors.Is(err, ErrPetHeld) {
+			t.Errorf("expected ErrPetHeld, got %v", err)
+		}
+		if err := s.Create(bob, &models.Order{PetID: 1}); !errors.Is(err, ErrPetHeld) {
+			t.Errorf("expected ErrPetHeld for bob's order, got %v", err)
+		}
+		if err := s.Create(models.Actor{}, &models.Order{PetID: 1}); !errors.Is(err, ErrPetHeld) {
+			t.Errorf("expected ErrPetHeld for an anonymous order, got %v", err)
+		}
+
+		order := &models.Order{PetID: 1}
+		if err := s.Create(alice, order); err != nil {
+			t.Fatalf("alice's order: %v", err)
+		}
+		if placed, err := s.GetByID(order.ID); err != nil || placed.PetID != 1 {
+			t.Errorf("expected alice's order to be placed, got %+v, %v", placed, err)
+		}
+		if _, err := s.GetHold(alice, hold.ID); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("expected the order to use up the hold, got %v", err)
+		}
+		if err := s.Create(bob, &models.Order{PetID: 1}); !errors.Is(err, ErrDuplicateRecord) {
+			t.Errorf("expected ErrDuplicateRecord, got %v", err)
+		}
+	})
+
+	t.Run("unavailable pets", func(t *testing.T) {
+		s := newService()
+
+		if _, err := s.Hold(alice, 2); !errors.Is(err, ErrPetUnavailable) {
+			t.Errorf("expected ErrPetUnavailable, got %v", err)
+		}
+		if _, err := s.Hold(alice, 3); !errors.Is(err, ErrPetNotFound) {
+			t.Errorf("expected ErrPetNotFound, got %v", err)
+		}
+		if err := s.Create(alice, &models.Order{PetID: 2}); !errors.Is(err, ErrPetUnavailable) {
+			t.Errorf("expected ErrPetUnavailable for a sold pet, got %v", err)
+		}
+		if err := s.CreateAdopted(clerk, &models.Order{PetID: 1}); !errors.Is(err, ErrPetUnavailable) {
+			t.Errorf("expected ErrPetUnavailable for an adoption order of an available pet, got %v", err)
+		}
+		if err := s.Create(alice, &models.Order{PetID: 3}); !errors.Is(err, ErrPetNotFound) {
+			t.Errorf("expected ErrPetNotFound for the order, got %v", err)
+		}
+	})
+
+	t.Run("release", func(t *testing.T) {
+		s := newService()
+
+		hold, err := s.Hold(alice, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetHold(bob, hold.ID); !errors.Is(err, ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+		if err := s.Release(bob, hold.ID); !errors.Is(err, ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+		if err := s.Release(clerk, hold.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Hold(bob, 1); err != nil {
+			t.Errorf("expected the pet to be free again, got %v", err)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		s := newService()
+
+		if _, err := s.Hold(alice, 1); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := s.ExpireHolds(time.Now()); err != nil || n != 0 {
+			t.Errorf("expected nothing to expire yet, got %d, %v", n, err)
+		}
+		if n, err := s.ExpireHolds(time.Now().Add(2 * time.Minute)); err != nil || n != 1 {
+			t.Errorf("expected one expired hold, got %d, %v", n, err)
+		}
+		if err := s.Create(bob, &models.Order{PetID: 1}); err != nil {
+			t.Errorf("expected bob to order the released pet, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/validator"
+	"test/internal/models"
+	"test/internal/modules/tag/service"
+
+	"github.com/go-chi/chi"
+)
+
+// r.Get("/tag", ctrl.TagHandler.ListTags)
+// r.Post("/tag", ctrl.TagHandler.CreateTag)
+// r.Delete("/tag/{tagID}", ctrl.TagHandler.DeleteTag)
+
+type ITagController interface {
+	ListTags(w http.ResponseWriter, r *http.Request)
+	CreateTag(w http.ResponseWriter, r *http.Request)
+	DeleteTag(w http.ResponseWriter, r *http.Request)
+}
+
+type TagController struct {
+	responder responder.Responder
+	service   service.ITagService
+}
+
+func NewTagController(responder responder.Responder, service service.ITagService) *TagController {
+	return &TagController{
+		responder: responder,
+		service:   service,
+	}
+}
+
+// ListTags serves both the catalog listing and autocomplete: ?q= narrows the
+// result to tags starting with the given prefix.
+func (t *TagController) ListTags(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	prefix := helpers.ReadString(qs, "q", "")
+	limit := helpers.ReadInt(qs, "limit", 20, v)
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 100, "limit", "must be a maximum of 100")
+
+	if !v.Valid() {
+		t.responder.ErrorBadRequest(w, errors.New("Invalid limit"))
+		return
+	}
+
+	tags, err := t.service.Search(prefix, limit)
+	if err != nil {
+		t.responder.ErrorInternal(w, err)
+		return
+	}
+
+	t.responder.OutputJSON(w, tags)
+}
+
+func (t *TagController) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag models.Tag
+	err := json.NewDecoder(r.Body).Decode(&tag)
+	if err != nil {
+		t.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = t.service.Create(&tag)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			t.responder.ErrorBadRequest(w, errors.New("Tag name must be provided"))
+		case errors.Is(err, service.ErrDuplicateRecord):
+			t.responder.ErrorConflict(w, errors.New("Tag already exists"))
+		default:
+			t.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+
+	t.responder.OutputJSON(w, tag)
+}
+
+func (t *TagController) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := strconv.Atoi(chi.URLParam(r, "tagID"))
+	if err != nil {
+		t.responder.ErrorBadRequest(w, err)
+		return
+	}
+
+	err = t.service.Delete(int64(tagID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRecordNotFound):
+			t.responder.ErrorNotFound(w, errors.New("Tag not found"))
+		default:
+			t.responder.ErrorInternal(w, err)
+		}
+		return
+	}
+
+	t.responder.OutputJSON(w, "Tag deleted successfully")
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/infrastructure/responder"
+	"test/internal/models"
+	"test/internal/modules/tag/repository"
+	"test/internal/modules/tag/service"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ptflp/godecoder"
+	"go.uber.org/zap"
+)
+
+type MockStorage struct {
+	Create_mock func(tag *models.Tag) error
+	Delete_mock func(id int64) error
+	Search_mock func(prefix string, limit int) ([]*models.Tag, error)
+}
+
+func (m *MockStorage) Create(tag *models.Tag) error {
+	return m.Create_mock(tag)
+}
+
+func (m *MockStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+
+func (m *MockStorage) Search(prefix string, limit int) ([]*models.Tag, error) {
+	return m.Search_mock(prefix, limit)
+}
+
+func newTestController(mock *MockStorage) *TagController {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		DisallowUnknownFields:  true,
+	})
+
+	return NewTagController(responder.NewResponder(decoder, logger), service.NewTagService(mock))
+}
+
+func TestListTagsHandler(t *testing.T) {
+
+	t.Run("happy path", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tag?q=pu&limit=5", nil)
+		w := httptest.NewRecorder()
+
+		var gotPrefix string
+		var gotLimit int
+		controller := newTestController(&MockStorage{
+			Search_mock: func(prefix string, limit int) ([]*models.Tag, error) {
+				gotPrefix, gotLimit = prefix, limit
+				return []*models.Tag{}, nil
+			},
+		})
+		controller.ListTags(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if gotPrefix != "pu" || gotLimit != 5 {
+			t.Errorf("expected search for (pu, 5) got (%s, %d)", gotPrefix, gotLimit)
+		}
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tag?limit=1000", nil)
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{})
+		controller.ListTags(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestCreateTagHandler(t *testing.T) {
+
+	t.Run("duplicate", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/tag", bytes.NewReader([]byte(`{"name": "friendly"}`)))
+		w := httptest.NewRecorder()
+
+		controller := newTestController(&MockStorage{
+			Create_mock: func(tag *models.Tag) error { return repository.ErrDuplicateTag },
+		})
+		controller.CreateTag(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, w.Code)
+		}
+	})
+}
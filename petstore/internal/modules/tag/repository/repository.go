@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"test/internal/models"
+)
+
+type TagStorage struct {
+	logger *zap.Logger
+	DB     *sqlx.DB
+}
+
+func NewTagStorage(db *sqlx.DB, logger *zap.Logger) ITagStorage {
+	return &TagStorage{
+		logger: logger,
+		DB:     db}
+}
+
+func (ts *TagStorage) Create(tag *models.Tag) error {
+	query := `INSERT INTO tags (name) VALUES ($1) RETURNING id`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ts.DB.QueryRowContext(ctx, query, tag.Name).Scan(&tag.ID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "duplicate key value violates unique constraint"):
+			return ErrDuplicateTag
+		default:
+			ts.logger.Error("error on inserting tag", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ts *TagStorage) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := ts.DB.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		ts.logger.Error("error on deleting tag", zap.Error(err))
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ts.logger.Error("error on deleting tag", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// Search returns up to limit tags whose names start with prefix, ignoring
+// case. An empty prefix lists the whole catalog.
+func (ts *TagStorage) Search(prefix string, limit int) ([]*models.Tag, error) {
+	query := `
+	SELECT id, name FROM tags
+	WHERE name ILIKE $1 ESCAPE '\'
+	ORDER BY name ASC
+	LIMIT $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+
+	rows, err := ts.DB.QueryContext(ctx, query, pattern, limit)
+	if err != nil {
+		ts.logger.Error("error on searching tags", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		err := rows.Scan(&tag.ID, &tag.Name)
+		if err != nil {
+			ts.logger.Error("error on scanning tag", zap.Error(err))
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		ts.logger.Error("error on iterating tags", zap.Error(err))
+		return nil, err
+	}
+
+	return tags, nil
+}
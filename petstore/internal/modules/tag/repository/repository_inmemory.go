@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+
+	"test/internal/models"
+	pet_storage "test/internal/modules/pet/repository"
+)
+
+// PetCatalog is what TagStorage_map needs from the in-memory pet storage
+// whose tags it serves.
+type PetCatalog interface {
+	SearchTags(prefix string, limit int) []*models.Tag
+	CreateTag(tag *models.Tag) error
+	DeleteTag(id int64) error
+}
+
+type TagStorage_map struct {
+	pets PetCatalog
+}
+
+func NewTagStorage_map(pets PetCatalog) ITagStorage {
+	return &TagStorage_map{pets: pets}
+}
+
+func (ts *TagStorage_map) Create(tag *models.Tag) error {
+	return tagError(ts.pets.CreateTag(tag))
+}
+
+func (ts *TagStorage_map) Delete(id int64) error {
+	return tagError(ts.pets.DeleteTag(id))
+}
+
+func (ts *TagStorage_map) Search(prefix string, limit int) ([]*models.Tag, error) {
+	return ts.pets.SearchTags(prefix, limit), nil
+}
+
+// tagError turns the errors of the pet storage into those of this one.
+func tagError(err error) error {
+	switch {
+	case errors.Is(err, pet_storage.ErrTagNotFound):
+		return ErrTagNotFound
+	case errors.Is(err, pet_storage.ErrDuplicateTag):
+		return ErrDuplicateTag
+	}
+	return err
+}
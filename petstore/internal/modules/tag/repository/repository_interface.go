@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"errors"
+
+	"test/internal/models"
+)
+
+var (
+	ErrTagNotFound  = errors.New("tag not found")
+	ErrDuplicateTag = errors.New("duplicate pet tag")
+)
+
+type ITagStorage interface {
+	Create(tag *models.Tag) error
+	Delete(id int64) error
+	Search(prefix string, limit int) ([]*models.Tag, error)
+}
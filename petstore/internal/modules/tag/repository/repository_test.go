@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"test/internal/models"
+	"testing"
+)
+
+func NewMockStorage() *MockStorage {
+	return &MockStorage{
+		Create_mock: func(tag *models.Tag) error {
+			return nil
+		},
+		Delete_mock: func(id int64) error {
+			return nil
+		},
+		Search_mock: func(prefix string, limit int) ([]*models.Tag, error) {
+			return []*models.Tag{}, nil
+		},
+	}
+}
+
+type MockStorage struct {
+	Create_mock func(tag *models.Tag) error
+	Delete_mock func(id int64) error
+	Search_mock func(prefix string, limit int) ([]*models.Tag, error)
+}
+
+func (m *MockStorage) Create(tag *models.Tag) error {
+	return m.Create_mock(tag)
+}
+
+func (m *MockStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+
+func (m *MockStorage) Search(prefix string, limit int) ([]*models.Tag, error) {
+	return m.Search_mock(prefix, limit)
+}
+
+func TestRepo(t *testing.T) {
+	var tagRepository ITagStorage = NewMockStorage()
+
+	t.Run("Create", func(t *testing.T) {
+		resp := tagRepository.Create(&models.Tag{})
+		if resp != nil {
+			t.Errorf("expected nil got %v", resp)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		resp, _ := tagRepository.Search("", 10)
+		if resp == nil {
+			t.Errorf("expected tags got nil")
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"test/internal/infrastructure/validator"
+	"test/internal/modules/tag/repository"
+
+	"test/internal/models"
+)
+
+var (
+	ErrValidation      = errors.New("validation error")
+	ErrRecordNotFound  = errors.New("record not found")
+	ErrDuplicateRecord = errors.New("duplicate record")
+)
+
+// r.Get("/tag", ctrl.TagHandler.ListTags)
+// r.Post("/tag", ctrl.TagHandler.CreateTag)
+// r.Delete("/tag/{tagID}", ctrl.TagHandler.DeleteTag)
+
+type ITagService interface {
+	Create(tag *models.Tag) error
+	Delete(id int64) error
+	Search(prefix string, limit int) ([]*models.Tag, error)
+}
+
+type TagService struct {
+	storage repository.ITagStorage
+}
+
+func NewTagService(repo repository.ITagStorage) *TagService {
+	return &TagService{storage: repo}
+}
+
+func (s *TagService) Create(tag *models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+
+	v := validator.New()
+	if ValidateTag(v, tag); !v.Valid() {
+		return ErrValidation
+	}
+
+	err := s.storage.Create(tag)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicateTag):
+			return ErrDuplicateRecord
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TagService) Delete(id int64) error {
+	err := s.storage.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTagNotFound):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TagService) Search(prefix string, limit int) ([]*models.Tag, error) {
+	return s.storage.Search(strings.TrimSpace(prefix), limit)
+}
+
+func ValidateTag(v *validator.Validator, tag *models.Tag) {
+	v.Check(tag.Name != "", "name", "must be provided")
+	v.Check(len(tag.Name) <= 255, "name", "must not be more than 255 bytes long")
+}
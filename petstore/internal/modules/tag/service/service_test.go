@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"test/internal/models"
+	"test/internal/modules/tag/repository"
+	"testing"
+)
+
+type MockStorage struct {
+	Create_mock func(tag *models.Tag) error
+	Delete_mock func(id int64) error
+	Search_mock func(prefix string, limit int) ([]*models.Tag, error)
+}
+
+func (m *MockStorage) Create(tag *models.Tag) error {
+	return m.Create_mock(tag)
+}
+
+func (m *MockStorage) Delete(id int64) error {
+	return m.Delete_mock(id)
+}
+
+func (m *MockStorage) Search(prefix string, limit int) ([]*models.Tag, error) {
+	return m.Search_mock(prefix, limit)
+}
+
+func TestMockService(t *testing.T) {
+	mockStorage := MockStorage{}
+
+	mockStorage.Create_mock = func(tag *models.Tag) error {
+		if tag.Name == "friendly" {
+			return repository.ErrDuplicateTag
+		}
+		return nil
+	}
+	mockStorage.Delete_mock = func(id int64) error {
+		return repository.ErrTagNotFound
+	}
+	mockStorage.Search_mock = func(prefix string, limit int) ([]*models.Tag, error) {
+		return []*models.Tag{{ID: 1, Name: prefix}}, nil
+	}
+
+	tagService := NewTagService(&mockStorage)
+
+	t.Run("Create duplicate", func(t *testing.T) {
+		err := tagService.Create(&models.Tag{Name: " friendly "})
+		if !errors.Is(err, ErrDuplicateRecord) {
+			t.Errorf("expected %v got %v", ErrDuplicateRecord, err)
+		}
+	})
+
+	t.Run("Create blank", func(t *testing.T) {
+		err := tagService.Create(&models.Tag{Name: "  "})
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("expected %v got %v", ErrValidation, err)
+		}
+	})
+
+	t.Run("Delete missing", func(t *testing.T) {
+		err := tagService.Delete(1)
+		if !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("expected %v got %v", ErrRecordNotFound, err)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		resp, _ := tagService.Search(" pu ", 10)
+		if len(resp) != 1 || resp[0].Name != "pu" {
+			t.Errorf("expected trimmed prefix got %v", resp)
+		}
+	})
+}
@@ -333,6 +333,7 @@ func (uc *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 
 	input.Filters.Sort = helpers.ReadString(qs, "sort", "id")
 	input.Filters.SortSafelist = []string{"id", "name", "email", "-id", "-name", "-email"}
+	filters.ReadKeyset(qs, &input.Filters, v)
 
 	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
 		uc.responder.ErrorInternal(w, errors.New("Internal server error1"))
@@ -832,6 +832,39 @@ func TestListAllHandler(t *testing.T) {
 			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
 		}
 
+	})
+	t.Run("keyset", func(t *testing.T) {
+
+		after := filter.Cursor{Sort: "name", Value: "alice", ID: 7}
+		req := httptest.NewRequest("GET", "/user/list", nil)
+		req.URL.RawQuery = "sort=name&limit=5&after=" + after.Encode()
+
+		w := httptest.NewRecorder()
+
+		var got filter.Filters
+		mock := &MockStorage{
+			GetAll_mock: func(filters filter.Filters) ([]*models.User, filter.Metadata, error) {
+				got = filters
+				return []*models.User{}, filter.Metadata{}, nil
+			},
+		}
+
+		logger, err := zap.NewProduction()
+		if err != nil {
+			panic(err)
+		}
+		decoder := godecoder.NewDecoder(jsoniter.Config{})
+
+		controller := NewUserHandler(responder.NewResponder(decoder, logger), service.NewUserService(mock))
+		controller.ListUsers(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, w.Code)
+		}
+		if !got.Keyset || got.PageSize != 5 || got.After == nil || *got.After != after {
+			t.Errorf("unexpected filters %+v", got)
+		}
+
 	})
 	t.Run("internal server error", func(t *testing.T) {
 
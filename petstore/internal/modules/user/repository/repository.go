@@ -37,7 +37,7 @@ func (m UserModel) Get(id int64) (*models.User, error) {
 	}
 
 	query := `
-        SELECT  id, created_at, name, email, password_hash, activated, deleted, version
+        SELECT  id, created_at, name, email, password_hash, activated, deleted, version, role
         FROM users
         WHERE id = $1 AND deleted = false`
 
@@ -57,6 +57,7 @@ func (m UserModel) Get(id int64) (*models.User, error) {
 		&user.Activated,
 		&user.Deleted,
 		&user.Version,
+		&user.Role,
 	)
 
 	if err != nil {
@@ -77,14 +78,14 @@ func (m UserModel) Insert(user *models.User) error {
 	query := `
         INSERT INTO users (name, email, password_hash, activated, deleted) 
         VALUES ($1, $2, $3, $4, $5)
-        RETURNING id, created_at, version`
+        RETURNING id, created_at, version, role`
 
 	args := []any{user.Name, user.Email, user.Password.Hash, user.Activated, user.Deleted}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version, &user.Role)
 	if err != nil {
 		switch {
 		case strings.Contains(err.Error(), "duplicate key value violates unique constraint"):
@@ -100,7 +101,7 @@ func (m UserModel) Insert(user *models.User) error {
 
 func (m UserModel) GetByName(username string) (*models.User, error) {
 	query := `
-        SELECT id, created_at, name, email, password_hash, activated, deleted, version
+        SELECT id, created_at, name, email, password_hash, activated, deleted, version, role
         FROM users
         WHERE name = $1 AND deleted = false`
 
@@ -118,6 +119,7 @@ func (m UserModel) GetByName(username string) (*models.User, error) {
 		&user.Activated,
 		&user.Deleted,
 		&user.Version,
+		&user.Role,
 	)
 
 	if err != nil {
@@ -209,9 +211,12 @@ func (m UserModel) Delete(id int64) error {
 }
 
 func (u UserModel) GetAll(filters filter.Filters) ([]*models.User, filter.Metadata, error) {
+	if filters.Keyset {
+		return u.getAfter(filters)
+	}
 
 	query := fmt.Sprintf(`
-        SELECT count(*) OVER(), id, created_at, name, email, password_hash, activated, deleted, version
+        SELECT count(*) OVER(), id, created_at, name, email, password_hash, activated, deleted, version, role
         FROM users  
 		WHERE deleted = false
         ORDER BY %s %s, id ASC
@@ -247,6 +252,7 @@ func (u UserModel) GetAll(filters filter.Filters) ([]*models.User, filter.Metada
 			&user.Activated,
 			&user.Deleted,
 			&user.Version,
+			&user.Role,
 		)
 		if err != nil {
 			fmt.Println("some row error", err)
@@ -265,3 +271,69 @@ func (u UserModel) GetAll(filters filter.Filters) ([]*models.User, filter.Metada
 	return users, metadata, nil
 
 }
+
+// getAfter serves a keyset page of GetAll. It seeks to the cursor instead of
+// counting and skipping rows, so its cost doesn't grow with the page number.
+func (u UserModel) getAfter(filters filter.Filters) ([]*models.User, filter.Metadata, error) {
+	clause, args := filters.KeysetClause("users")
+
+	query := u.DB.Rebind(fmt.Sprintf(`
+        SELECT id, created_at, name, email, password_hash, activated, deleted, version, role
+        FROM users
+		WHERE deleted = false AND %s
+        ORDER BY %s %s, id ASC
+        LIMIT ?`, clause, filters.SortColumn(), filters.SortDirection()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := u.DB.QueryContext(ctx, query, append(args, filters.KeysetLimit())...)
+	if err != nil {
+		return nil, filter.Metadata{}, err
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		var user models.User
+
+		err := rows.Scan(
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Password.Hash,
+			&user.Activated,
+			&user.Deleted,
+			&user.Version,
+			&user.Role,
+		)
+		if err != nil {
+			return nil, filter.Metadata{}, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, filter.Metadata{}, err
+	}
+
+	users, metadata := filter.KeysetPage(filters, users, userKey(filters.SortColumn()))
+	return users, metadata, nil
+}
+
+// userKey returns the value of a user in the sort column and its id, for
+// cursors.
+func userKey(column string) func(*models.User) (string, int64) {
+	return func(user *models.User) (string, int64) {
+		switch column {
+		case "name":
+			return user.Name, user.ID
+		case "email":
+			return user.Email, user.ID
+		default:
+			return "", user.ID
+		}
+	}
+}
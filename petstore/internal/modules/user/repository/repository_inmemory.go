@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	filter "test/internal/infrastructure/filters"
+	"test/internal/models"
+)
+
+// UserStorage_map keeps the users of the in-memory backend, following the
+// users table: ids from 1, a version bumped on every write, soft deletes and
+// emails unique regardless of case.
+type UserStorage_map struct {
+	mu     sync.RWMutex
+	users  map[int64]*models.User
+	lastID int64
+}
+
+func NewUserStorage_map() IUserStorage {
+	return &UserStorage_map{users: make(map[int64]*models.User)}
+}
+
+func (us *UserStorage_map) Get(id int64) (*models.User, error) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	user, ok := us.users[id]
+	if !ok || user.Deleted {
+		return nil, ErrRecordNotFound
+	}
+	return cloneUser(user), nil
+}
+
+func (us *UserStorage_map) GetByName(username string) (*models.User, error) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	for _, user := range us.users {
+		if user.Name == username && !user.Deleted {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (us *UserStorage_map) Insert(user *models.User) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if us.emailTaken(user.Email, 0) {
+		return ErrDuplicateEmail
+	}
+	us.lastID++
+	user.ID = us.lastID
+	user.CreatedAt = time.Now()
+	user.Version = 1
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	us.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+func (us *UserStorage_map) Update(user *models.User) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	stored, ok := us.users[user.ID]
+	if !ok || stored.Deleted || stored.Version != user.Version {
+		return ErrEditConflict
+	}
+	if us.emailTaken(user.Email, user.ID) {
+		return ErrDuplicateEmail
+	}
+	updated := cloneUser(stored)
+	updated.Name = user.Name
+	updated.Email = user.Email
+	updated.Password.Hash = append([]byte(nil), user.Password.Hash...)
+	updated.Activated = user.Activated
+	updated.Version++
+	us.users[user.ID] = updated
+	user.Version = updated.Version
+	return nil
+}
+
+func (us *UserStorage_map) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	stored, ok := us.users[id]
+	if !ok || stored.Deleted {
+		return ErrEditConflict
+	}
+	deleted := cloneUser(stored)
+	deleted.Deleted = true
+	deleted.Version++
+	us.users[id] = deleted
+	return nil
+}
+
+// GetAll lists the users that are not deleted in the order of filters, ties
+// broken by id ascending, a page or a keyset page at a time.
+func (us *UserStorage_map) GetAll(filters filter.Filters) ([]*models.User, filter.Metadata, error) {
+	column := filters.SortColumn()
+	desc := filters.SortDirection() == "DESC"
+	key := userKey(column)
+	value := func(user *models.User) string {
+		v, _ := key(user)
+		if column == "email" {
+			return strings.ToLower(v)
+		}
+		return v
+	}
+	// follows reports whether a user with value v and id comes after one
+	// with value w and id after.
+	follows := func(v string, id int64, w string, after int64) bool {
+		if column == "id" {
+			return (id > after) != desc
+		}
+		if v != w {
+			return (v > w) != desc
+		}
+		return id > after
+	}
+
+	us.mu.RLock()
+	users := make([]*models.User, 0, len(us.users))
+	for _, user := range us.users {
+		if user.Deleted {
+			continue
+		}
+		if after := filters.After; after != nil {
+			w := after.Value
+			if column == "email" {
+				w = strings.ToLower(w)
+			}
+			if !follows(value(user), user.ID, w, after.ID) {
+				continue
+			}
+		}
+		users = append(users, cloneUser(user))
+	}
+	us.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return follows(value(users[j]), users[j].ID, value(users[i]), users[i].ID)
+	})
+
+	if filters.Keyset {
+		if len(users) > filters.KeysetLimit() {
+			users = users[:filters.KeysetLimit()]
+		}
+		users, metadata := filter.KeysetPage(filters, users, key)
+		return users, metadata, nil
+	}
+
+	metadata := filter.CalculateMetadata(len(users), filters.Page, filters.PageSize)
+	start := filters.Offset()
+	if start > len(users) {
+		start = len(users)
+	}
+	end := start + filters.Limit()
+	if end > len(users) {
+		end = len(users)
+	}
+	return users[start:end], metadata, nil
+}
+
+// emailTaken reports whether a user other than id has email, which like the
+// citext column ignores case. Callers must hold the lock.
+func (us *UserStorage_map) emailTaken(email string, id int64) bool {
+	for _, user := range us.users {
+		if user.ID != id && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneUser copies user without the plaintext password, which is never
+// stored.
+func cloneUser(user *models.User) *models.User {
+	c := *user
+	c.Password.Plaintext = nil
+	c.Password.Hash = append([]byte(nil), user.Password.Hash...)
+	return &c
+}
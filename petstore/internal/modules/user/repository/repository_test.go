@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	filter "test/internal/infrastructure/filters"
 	"test/internal/models"
 	"testing"
@@ -93,3 +94,80 @@ func TestRepo(t *testing.T) {
 		}
 	})
 }
+
+func TestUserStorageMap(t *testing.T) {
+	users := NewUserStorage_map()
+
+	names := []string{"carol", "alice", "bob", "dave"}
+	for _, name := range names {
+		user := &models.User{Name: name, Email: name + "@example.com"}
+		if err := users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		if user.ID == 0 || user.Version != 1 || user.Role != "user" || user.CreatedAt.IsZero() {
+			t.Errorf("unexpected inserted user %+v", user)
+		}
+	}
+	if err := users.Insert(&models.User{Name: "eve", Email: "ALICE@example.com"}); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+
+	bob, err := users.GetByName("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob.Activated = true
+	if err := users.Update(bob); err != nil {
+		t.Fatal(err)
+	}
+	if bob.Version != 2 {
+		t.Errorf("expected version 2, got %d", bob.Version)
+	}
+	stale := *bob
+	stale.Version = 1
+	if err := users.Update(&stale); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("expected ErrEditConflict, got %v", err)
+	}
+	bob.Email = "carol@example.com"
+	if err := users.Update(bob); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+
+	dave, _ := users.GetByName("dave")
+	if err := users.Delete(dave.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Get(dave.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	sorted := filter.Filters{Page: 1, PageSize: 2, Sort: "-name", SortSafelist: []string{"-name"}}
+	page, metadata, err := users.GetAll(sorted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Name != "carol" || page[1].Name != "bob" || metadata.TotalRecords != 3 {
+		t.Errorf("unexpected page %+v %+v", page, metadata)
+	}
+
+	sorted.Keyset = true
+	var got []string
+	for {
+		page, metadata, err := users.GetAll(sorted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range page {
+			got = append(got, user.Name)
+		}
+		if metadata.NextCursor == "" {
+			break
+		}
+		if sorted.After, err = filter.DecodeCursor(metadata.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 3 || got[0] != "carol" || got[1] != "bob" || got[2] != "alice" {
+		t.Errorf("unexpected keyset pages %v", got)
+	}
+}
@@ -64,7 +64,7 @@ func (s *UserService) Login(username, password string) (*models.User, *string, e
 	} else if !ok && err == nil {
 		return nil, nil, ErrWrongPassword
 	}
-	token := helpers.GenerateToken(username)
+	token := helpers.GenerateToken(username, user.Role)
 	return user, &token, nil
 }
 
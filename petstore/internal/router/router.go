@@ -8,10 +8,12 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"test/internal/infrastructure/components"
+	"test/internal/models"
 	"test/internal/modules"
 	swagger "test/static"
 
 	"test/internal/infrastructure/helpers"
+	"test/internal/infrastructure/responder"
 
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/lestrrat-go/jwx/v2/jwt"
@@ -20,7 +22,16 @@ import (
 func Routes(ctrl *modules.Controllers, comp *components.Components) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Group(func(r chi.Router) {
+	// The API speaks JSON and XML. Media and the swagger files are served
+	// as they are, the pet import and export stream their own formats, and
+	// forms and uploads are read by their handlers.
+	api := r.With(responder.Negotiate(comp.Responder,
+		[]interface{}{models.Pet{}, models.Category{}, models.Tag{}, models.Order{}, models.Hold{},
+			models.User{}, models.Adoption{}, models.MedicalRecord{}},
+		"text/csv", "application/x-ndjson", "application/ndjson", "application/octet-stream",
+		"multipart/form-data", "application/x-www-form-urlencoded"))
+
+	api.Group(func(r chi.Router) {
 
 		r.Use(jwtauth.Verifier(helpers.TokenAuth))
 		r.Use(func(next http.Handler) http.Handler {
@@ -43,29 +54,69 @@ func Routes(ctrl *modules.Controllers, comp *components.Components) *chi.Mux {
 			})
 		})
 		r.Get("/user/list", ctrl.UserHandler.ListUsers)
+		r.Get("/user/{username}/pets", ctrl.PetHandler.UserPets)
+		r.Get("/user/me/favorites", ctrl.FavoriteHandler.FavoriteList)
+		r.Post("/user/me/favorites/{petID}", ctrl.FavoriteHandler.FavoriteAdd)
+		r.Delete("/user/me/favorites/{petID}", ctrl.FavoriteHandler.FavoriteRemove)
+		r.Get("/pet", ctrl.PetHandler.PetList)
 		r.Post("/pet", ctrl.PetHandler.PetCreate)
 		r.Post("/pet/{petID}", ctrl.PetHandler.PetUpdate_post)
 		r.Get("/pet/{petID}", ctrl.PetHandler.PetGetByID)
 		r.Get("/pet/findByStatus", ctrl.PetHandler.PetGetByStatus)
+		r.Get("/pet/findByTags", ctrl.PetHandler.PetGetByTags)
+		r.Post("/pet/import", ctrl.PetHandler.PetImport)
+		r.Get("/pet/export", ctrl.PetHandler.PetExport)
 		r.Put("/pet", ctrl.PetHandler.PetUpdate)
+		r.Patch("/pet/{petID}", ctrl.PetHandler.PetPatch)
 		r.Delete("/pet/{petID}", ctrl.PetHandler.PetDelete)
+		r.Post("/pet/{petID}/uploadImage", ctrl.PetHandler.PetUploadImage)
+		r.Get("/pet/{petID}/history", ctrl.PetHandler.PetHistory)
+		r.Get("/pet/{petID}/similar", ctrl.PetHandler.PetSimilar)
+		r.Get("/pet/{petID}/records", ctrl.MedicalHandler.List)
+		r.Post("/pet/{petID}/records", ctrl.MedicalHandler.Create)
+		r.Get("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.GetByID)
+		r.Put("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.Update)
+		r.Delete("/pet/{petID}/records/{recordID}", ctrl.MedicalHandler.Delete)
+		r.Get("/pet/vaccinations/due", ctrl.MedicalHandler.DueVaccinations)
+
+		r.Get("/category", ctrl.CategoryHandler.ListCategories)
+		r.Get("/category/{categoryID}", ctrl.CategoryHandler.GetCategoryByID)
+		r.Post("/category", ctrl.CategoryHandler.CreateCategory)
+		r.Put("/category/{categoryID}", ctrl.CategoryHandler.UpdateCategory)
+		r.Delete("/category/{categoryID}", ctrl.CategoryHandler.DeleteCategory)
+
+		r.Get("/tag", ctrl.TagHandler.ListTags)
+		r.Post("/tag", ctrl.TagHandler.CreateTag)
+		r.Delete("/tag/{tagID}", ctrl.TagHandler.DeleteTag)
 
 		r.Get("/store/inventory", ctrl.StoreHandler.GetInventory)
+		r.Get("/store/order", ctrl.StoreHandler.ListOrders)
+		r.Post("/store/hold", ctrl.StoreHandler.CreateHold)
+		r.Get("/store/hold/{holdID}", ctrl.StoreHandler.GetHold)
+		r.Delete("/store/hold/{holdID}", ctrl.StoreHandler.ReleaseHold)
+
+		r.Post("/adoption", ctrl.AdoptionHandler.Submit)
+		r.Get("/adoption", ctrl.AdoptionHandler.List)
+		r.Get("/adoption/{adoptionID}", ctrl.AdoptionHandler.GetByID)
+		r.Post("/adoption/{adoptionID}/approve", ctrl.AdoptionHandler.Approve)
+		r.Post("/adoption/{adoptionID}/reject", ctrl.AdoptionHandler.Reject)
 
 	})
 
-	r.Get("/store/order/{orderID}", ctrl.StoreHandler.GetOrderByID)
-	r.Post("/store/order", ctrl.StoreHandler.CreateOrder)
-	r.Delete("/store/order/{orderID}", ctrl.StoreHandler.DeleteOrder)
-
-	r.Post("/user", ctrl.UserHandler.CreateUser)
-	r.Get("/user/login", ctrl.UserHandler.Login)
-	r.Get("/user/logout", ctrl.UserHandler.Logout)
-	r.Get("/user/{username}", ctrl.UserHandler.GetUserByName)
-	r.Put("/user/{username}", ctrl.UserHandler.UpdateUser)
-	r.Delete("/user/{username}", ctrl.UserHandler.DeleteUser)
-	r.Post("/user/CreateWithList", ctrl.UserHandler.CreateWithList)
-	r.Post("/user/CreateWithArray", ctrl.UserHandler.CreateWithArray)
+	r.Get("/media/*", ctrl.PetHandler.MediaGet)
+
+	api.Get("/store/order/{orderID}", ctrl.StoreHandler.GetOrderByID)
+	api.With(jwtauth.Verifier(helpers.TokenAuth)).Post("/store/order", ctrl.StoreHandler.CreateOrder)
+	api.Delete("/store/order/{orderID}", ctrl.StoreHandler.DeleteOrder)
+
+	api.Post("/user", ctrl.UserHandler.CreateUser)
+	api.Get("/user/login", ctrl.UserHandler.Login)
+	api.Get("/user/logout", ctrl.UserHandler.Logout)
+	api.Get("/user/{username}", ctrl.UserHandler.GetUserByName)
+	api.Put("/user/{username}", ctrl.UserHandler.UpdateUser)
+	api.Delete("/user/{username}", ctrl.UserHandler.DeleteUser)
+	api.Post("/user/CreateWithList", ctrl.UserHandler.CreateWithList)
+	api.Post("/user/CreateWithArray", ctrl.UserHandler.CreateWithArray)
 
 	fileServer := http.FileServerFS(swagger.Swaggerfile)
 	r.Get("/swagger/*", func(w http.ResponseWriter, r *http.Request) {
@@ -1,11 +1,18 @@
 package router
 
 import (
+	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"test/config"
 	"test/internal/infrastructure/components"
+	"test/internal/infrastructure/helpers"
 	"test/internal/infrastructure/responder"
+	"test/internal/models"
 	"test/internal/modules"
+	pet_storage "test/internal/modules/pet/repository"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
@@ -21,7 +28,7 @@ func TestRouter(t *testing.T) {
 	decoder := godecoder.NewDecoder(jsoniter.Config{})
 	responseManager := responder.NewResponder(decoder, logger)
 
-	components := components.NewComponents(responseManager, decoder, logger, nil)
+	components := components.NewComponents(responseManager, decoder, logger, nil, nil, config.NewConfig())
 	storages := modules.NewStorages(nil, nil)
 	services := modules.NewServices(components, storages)
 	ctrl := modules.NewControllers(services, components)
@@ -34,3 +41,71 @@ func TestRouter(t *testing.T) {
 		t.Errorf("expected status code %d, got %d", http.StatusForbidden, w.Code)
 	}
 }
+
+func TestRouterNegotiates(t *testing.T) {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	decoder := godecoder.NewDecoder(jsoniter.Config{})
+	responseManager := responder.NewResponder(decoder, logger)
+
+	components := components.NewComponents(responseManager, decoder, logger, nil, nil, config.NewConfig())
+	pets := pet_storage.NewPetStorage_map(zap.NewNop())
+	storages := modules.NewMemoryStorages(pets, logger)
+	services := modules.NewServices(components, storages)
+	ctrl := modules.NewControllers(services, components)
+	r := Routes(ctrl, components)
+
+	name := "rex"
+	rex := &models.Pet{Name: &name, Category: &models.Category{Name: "dogs"}, PhotoUrls: []string{"/media/rex.png"}, Status: models.PetStatusAvailable, CreatedBy: "alice"}
+	if err := pets.Create(rex, nil); err != nil {
+		t.Fatal(err)
+	}
+	token := "Bearer " + helpers.GenerateToken("alice", models.RoleUser)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/user/list", nil)
+	req.Header.Set("Accept", "text/html")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("expected status code %d, got %d", http.StatusNotAcceptable, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/pet/"+strconv.FormatInt(rex.ID, 10), strings.NewReader("name=rex&status=pending"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/pet", strings.NewReader(
+		"<Pet><name>007</name><category><name>dogs</name></category><photoUrls>/media/bond.png</photoUrls>"+
+			"<tags><name>calm</name></tags><weightGrams>1200</weightGrams></Pet>"))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Accept", "application/xml")
+	req.Header.Set("Authorization", token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var created models.Pet
+	if err := xml.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	if created.ID == 0 || created.Name == nil || *created.Name != "007" || created.Category == nil || created.Category.ID != rex.Category.ID ||
+		len(created.Tags) != 1 || created.Tags[0].Name != "calm" || created.WeightGrams != 1200 {
+		t.Errorf("unexpected pet %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/swagger/", nil)
+	req.Header.Set("Accept", "text/html")
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusNotAcceptable {
+		t.Error("expected the swagger files to be served whatever the Accept header")
+	}
+}
@@ -0,0 +1,53 @@
+package run
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// job is background work the app does every interval while it serves.
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(now time.Time) error
+}
+
+// startJobs starts every registered job. They are stopped with the other
+// closers once the server is down.
+func (a *App) startJobs() {
+	for _, j := range a.jobs {
+		a.closers = append(a.closers, a.startJob(j))
+	}
+}
+
+// startJob runs j in its own goroutine and returns the function stopping it,
+// which waits for a run in progress to finish. Failed runs are logged and
+// retried on the next tick.
+func (a *App) startJob(j job) func() error {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if err := j.run(now); err != nil {
+					a.logger.Error("background job failed", zap.String("job", j.name), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+		return nil
+	}
+}
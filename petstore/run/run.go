@@ -10,8 +10,11 @@ import (
 	"syscall"
 	"test/config"
 	"test/internal/db"
+	"test/internal/infrastructure/blobstore"
 	"test/internal/infrastructure/responder"
+	"test/internal/infrastructure/wal"
 	"test/internal/modules"
+	pet_storage "test/internal/modules/pet/repository"
 	"test/internal/router"
 	"time"
 
@@ -40,6 +43,12 @@ type App struct {
 	logger   *zap.Logger
 	server   *http.Server
 	services *modules.Services
+
+	// background work started by Serve
+	jobs []job
+
+	// run once the server has stopped, last added first
+	closers []func() error
 }
 
 func NewApp(conf *config.Config, logger *zap.Logger) *App {
@@ -49,6 +58,8 @@ func NewApp(conf *config.Config, logger *zap.Logger) *App {
 }
 
 func (app *App) Serve() error {
+	defer app.close()
+	app.startJobs()
 
 	shutdownErr := make(chan error)
 
@@ -89,10 +100,43 @@ func (a *App) Run() {
 	if err != nil {
 		a.logger.Fatal("error init db", zap.Error(err))
 	}
-	components := components.NewComponents(responseManager, decoder, a.logger, dbx)
-	storages := modules.NewStorages(dbx, a.logger)
+	blobs := blobstore.NewLocalStore(a.cfg.Media.Dir)
+	components := components.NewComponents(responseManager, decoder, a.logger, dbx, blobs, a.cfg)
+	var storages *modules.Storages
+	switch a.cfg.Db.DBname {
+	case config.BackendMemory:
+		pets, err := a.openMemoryPets()
+		if err != nil {
+			a.logger.Fatal("error restoring pets", zap.Error(err))
+		}
+		a.closers = append(a.closers, pets.Close)
+		storages = modules.NewMemoryStorages(pets, a.logger)
+	default:
+		storages = modules.NewStorages(dbx, a.logger)
+	}
 	services := modules.NewServices(components, storages)
 	a.services = services
+	a.jobs = append(a.jobs, job{
+		name:     "adoption expiry",
+		interval: a.cfg.Adoption.ExpiryInterval,
+		run: func(now time.Time) error {
+			expired, err := services.AdoptionService.ExpireStale(now)
+			if expired > 0 {
+				a.logger.Info("expired adoption applications", zap.Int("count", expired))
+			}
+			return err
+		},
+	}, job{
+		name:     "reservation expiry",
+		interval: a.cfg.Reservation.ExpiryInterval,
+		run: func(now time.Time) error {
+			expired, err := services.StoreService.ExpireHolds(now)
+			if expired > 0 {
+				a.logger.Info("expired reservation holds", zap.Int("count", expired))
+			}
+			return err
+		},
+	})
 	controllers := modules.NewControllers(services, components)
 
 	r := router.Routes(controllers, components)
@@ -105,3 +149,32 @@ func (a *App) Run() {
 	a.server = server
 
 }
+
+// openMemoryPets returns the pet storage of the memory backend, replaying
+// what an earlier run left in cfg.Memory.Dir when that is set.
+func (a *App) openMemoryPets() (*pet_storage.PetStorage_map, error) {
+	if a.cfg.Memory.Dir == "" {
+		return pet_storage.NewPetStorage_map(a.logger), nil
+	}
+
+	policy, err := wal.ParseSyncPolicy(a.cfg.Memory.Fsync)
+	if err != nil {
+		return nil, err
+	}
+
+	return pet_storage.OpenPetStorage_map(pet_storage.MapPersistence{
+		Dir:              a.cfg.Memory.Dir,
+		Fsync:            policy,
+		FsyncInterval:    a.cfg.Memory.FsyncInterval,
+		SnapshotInterval: a.cfg.Memory.SnapshotInterval,
+	}, a.logger)
+}
+
+func (a *App) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i](); err != nil {
+			a.logger.Error("error on closing", zap.Error(err))
+		}
+	}
+	a.closers = nil
+}
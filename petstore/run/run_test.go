@@ -1,8 +1,12 @@
 package run
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"test/config"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -19,3 +23,60 @@ func TestRoutes(t *testing.T) {
 	app.Run()
 
 }
+
+func TestRunMemoryBackend(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewConfig(config.WithDBname(config.BackendMemory), config.WithMemoryDir(dir), config.WithMediaDir(t.TempDir()))
+
+	app := NewApp(cfg, zap.NewNop())
+	app.Run()
+	if len(app.closers) != 1 {
+		t.Fatalf("expected the pet storage to be closed with the app, got %d closers", len(app.closers))
+	}
+	if len(app.jobs) != 2 || app.jobs[0].name != "adoption expiry" || app.jobs[1].name != "reservation expiry" {
+		t.Errorf("expected the adoption and reservation sweepers, got %+v", app.jobs)
+	}
+	app.close()
+
+	if _, err := os.Stat(filepath.Join(dir, "pets.snapshot")); err != nil {
+		t.Errorf("expected a snapshot on close: %v", err)
+	}
+}
+
+func TestRunJobs(t *testing.T) {
+	app := NewApp(config.NewConfig(), zap.NewNop())
+
+	var order []string
+	app.closers = append(app.closers, func() error { order = append(order, "storage"); return nil })
+
+	runs := make(chan time.Time, 10)
+	app.jobs = []job{{name: "tick", interval: time.Millisecond, run: func(now time.Time) error {
+		select {
+		case runs <- now:
+		default:
+		}
+		return errors.New("logged and retried")
+	}}}
+	app.startJobs()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("job didn't run")
+		}
+	}
+
+	app.closers = append(app.closers, func() error { order = append(order, "last"); return nil })
+	app.close()
+	for len(runs) > 0 {
+		<-runs
+	}
+	time.Sleep(5 * time.Millisecond)
+	if len(runs) != 0 {
+		t.Error("job kept running after close")
+	}
+	if len(order) != 2 || order[0] != "last" || order[1] != "storage" {
+		t.Errorf("expected closers to run last added first, got %v", order)
+	}
+}